@@ -3,7 +3,10 @@ package cmd
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Pincho-App/pincho-cli/pkg/client"
 	"github.com/Pincho-App/pincho-cli/pkg/config"
 	"github.com/spf13/cobra"
 )
@@ -20,7 +23,12 @@ or listed using the subcommands.
 Priority order for configuration values:
   1. Command-line flags (--token)
   2. Environment variables (PINCHO_TOKEN)
-  3. Config file (~/.pincho/config.yaml)
+  3. Active profile (--profile or PINCHO_PROFILE)
+  4. Config file (~/.pincho/config.yaml)
+
+With --profile, the subcommands operate on the named profile: set writes into
+it (creating it if needed), get and list show its effective values, with
+unset keys inherited from the top level.
 
 Examples:
   # Set configuration values
@@ -31,6 +39,10 @@ Examples:
 
   # List all configuration
   pincho config list
+
+  # Configure and inspect a profile
+  pincho config set token wpt_team123 --profile oncall
+  pincho config list --profile oncall
 `,
 }
 
@@ -42,15 +54,34 @@ var configSetCmd = &cobra.Command{
 
 Supported keys:
   - token: Your Pincho API token
+  - token_file: File to read the token from (e.g. a Docker secret)
+  - token_command: Shell command printing the token (e.g. a password manager)
+  - default_type: Notification type used when --type is not given
   - timeout: Request timeout in seconds (default: 30)
   - max_retries: Maximum retry attempts (default: 3)
-  - api_url: Custom API endpoint URL
+  - api_url: Custom send endpoint URL (legacy, prefer base_url)
+  - base_url: Base URL that endpoint paths are resolved against
+  - endpoints.<name>: Path or absolute URL of one endpoint (send, notifai)
+  - idempotency_window: How long an --idempotency-key is refused after a send (default: 24h)
+  - breaker_threshold: Consecutive failures before failing fast (default: 5, negative disables)
+  - breaker_cooldown: How long to fail fast once the breaker opens (default: 60s)
+  - dedup_window: Default window for suppressing identical notifications (default: disabled)
+  - outbox: Queue sends that fail with a transient error for "pincho outbox flush" (default: false)
 
 Examples:
   pincho config set token wpt_abc123xyz
+  pincho config set token_file /run/secrets/pincho_token
+  pincho config set token_command "pass show pincho"
   pincho config set timeout 60
   pincho config set max_retries 5
   pincho config set api_url https://api.pincho.app/send
+  pincho config set base_url https://gateway.internal/pincho/v1/
+  pincho config set endpoints.notifai ai/notifai
+  pincho config set idempotency_window 72h
+  pincho config set breaker_threshold 3
+  pincho config set dedup_window 10m
+  pincho config set outbox true
+  pincho config set default_type alert --profile oncall
 `,
 	Args: cobra.ExactArgs(2),
 	RunE: runConfigSet,
@@ -94,8 +125,10 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 
 	// Validate key and value types
 	switch key {
-	case "token", "api_url":
+	case "token", "token_file", "token_command", "api_url", "base_url", "default_type":
 		// String values, use as-is
+	case "endpoints." + client.EndpointSend, "endpoints." + client.EndpointNotifAI:
+		// Endpoint path or absolute URL, use as-is
 	case "timeout", "max_retries":
 		// Integer values, validate
 		intValue, err := strconv.Atoi(value)
@@ -105,16 +138,38 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 		if intValue < 0 {
 			return fmt.Errorf("invalid value for %s: must be non-negative", key)
 		}
+	case "breaker_threshold":
+		// Integer values, negative disables the breaker
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("invalid value for %s: must be an integer", key)
+		}
+	case "idempotency_window", "breaker_cooldown", "dedup_window":
+		// Duration values, validate
+		duration, err := time.ParseDuration(value)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("invalid value for %s: must be a positive duration (e.g. 30m, 24h)", key)
+		}
+	case "outbox":
+		// Boolean values, validate
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value for %s: must be true or false", key)
+		}
 	default:
-		return fmt.Errorf("invalid key '%s' (supported: token, timeout, max_retries, api_url)", key)
+		return fmt.Errorf("invalid key '%s' (supported: token, token_file, token_command, default_type, timeout, max_retries, api_url, base_url, endpoints.send, endpoints.notifai, idempotency_window, breaker_threshold, breaker_cooldown, dedup_window, outbox)", key)
 	}
 
-	if err := config.Set(key, value); err != nil {
+	// With a profile selected, the value is written into that profile
+	target := key
+	if profile := config.ActiveProfile(); profile != "" {
+		target = config.ProfileKey(profile, key)
+	}
+
+	if err := config.Set(target, value); err != nil {
 		return fmt.Errorf("failed to set config: %w", err)
 	}
 
 	configPath, _ := config.GetConfigPath()
-	fmt.Printf("✓ Set %s in %s\n", key, configPath)
+	fmt.Printf("✓ Set %s in %s\n", target, configPath)
 	return nil
 }
 
@@ -130,8 +185,8 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 		fmt.Printf("%s: (not set)\n", key)
 	} else {
 		// Mask sensitive values
-		if key == "token" && len(value) > 8 {
-			fmt.Printf("%s: %s...%s\n", key, value[:4], value[len(value)-4:])
+		if key == "token" {
+			fmt.Printf("%s: %s\n", key, maskToken(value))
 		} else {
 			fmt.Printf("%s: %s\n", key, value)
 		}
@@ -154,14 +209,24 @@ func runConfigList(cmd *cobra.Command, args []string) error {
 	}
 
 	configPath, _ := config.GetConfigPath()
-	fmt.Printf("Configuration from %s:\n\n", configPath)
+	if profile := config.ActiveProfile(); profile != "" {
+		fmt.Printf("Configuration from %s (profile %s):\n\n", configPath, profile)
+	} else {
+		fmt.Printf("Configuration from %s:\n\n", configPath)
+	}
 
 	for key, value := range all {
+		// Profiles may hold tokens, list only their names
+		if key == config.ProfilesKey {
+			fmt.Printf("  %s: %s\n", key, strings.Join(config.ProfileNames(), ", "))
+			continue
+		}
+
 		valueStr := fmt.Sprintf("%v", value)
 
 		// Mask sensitive values
-		if key == "token" && len(valueStr) > 8 {
-			fmt.Printf("  %s: %s...%s\n", key, valueStr[:4], valueStr[len(valueStr)-4:])
+		if key == "token" {
+			fmt.Printf("  %s: %s\n", key, maskToken(valueStr))
 		} else {
 			fmt.Printf("  %s: %s\n", key, valueStr)
 		}
@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/jobs"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/runner"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+	"github.com/spf13/cobra"
+)
+
+// cronCmd represents the cron command
+var cronCmd = &cobra.Command{
+	Use:   "cron --name <job> [flags] -- <command> [args...]",
+	Short: "Run a scheduled job and notify when its outcome changes",
+	Long: `Run a command from cron or another scheduler and notify only when its
+outcome changes: on the first failed run, on the first successful run after
+failures, and every --remind-every runs while it keeps failing. A job that
+runs every five minutes thus does not flood your phone, and you still learn
+when it recovers.
+
+Like exec, the command's output passes through unchanged, notifications
+include its exit code, resource usage and last lines of output, and pincho
+exits with the command's exit code.
+
+Each job keeps its failure streak and recent durations in
+~/.pincho/jobs/<name>.json; notifications include the usual duration of a
+successful run. A notification that could not be sent or queued stays
+pending in the job state and is retried on the next run. A run started while
+the previous one is still running is skipped and reported, so a hanging job
+is noticed. It exits with code 3.
+
+Examples:
+  # crontab: back up every five minutes
+  */5 * * * * pincho cron --name backup --exit-type failure=alert -- /usr/local/bin/backup.sh
+
+  # Remind every hour (12 runs) while still failing
+  pincho cron --name sync --remind-every 12 --tag sync -- ./sync.sh
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCron,
+}
+
+var (
+	cronName        string
+	cronRemindEvery int
+	cronType        string
+	cronExitTypes   []string
+	cronTags        []string
+	cronLines       int
+	cronActionURL   string
+)
+
+func init() {
+	rootCmd.AddCommand(cronCmd)
+
+	// Flags after the command belong to the command
+	cronCmd.Flags().SetInterspersed(false)
+
+	cronCmd.Flags().StringVar(&cronName, "name", "", "Job name, used for its state and in notifications (required)")
+	cronCmd.Flags().IntVar(&cronRemindEvery, "remind-every", jobs.DefaultRemindEvery, "Remind every this many failed runs while the job keeps failing (0 to never remind)")
+	cronCmd.Flags().StringVar(&cronType, "type", "", "Notification type for exit codes without an --exit-type")
+	cronCmd.Flags().StringArrayVar(&cronExitTypes, "exit-type", nil, "Notification type for an exit code, as code=type, success=type or failure=type (can be used multiple times)")
+	cronCmd.Flags().StringSliceVar(&cronTags, "tag", []string{}, "Tags for categorization (can be used multiple times)")
+	cronCmd.Flags().IntVar(&cronLines, "lines", 10, "Number of last output lines to include (0 for none)")
+	cronCmd.Flags().StringVar(&cronActionURL, "action-url", "", "Action URL to open when notification is tapped")
+	cronCmd.MarkFlagRequired("name")
+}
+
+func runCron(cmd *cobra.Command, args []string) error {
+	job, err := jobs.Open(cronName)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+	if cronRemindEvery < 0 {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--remind-every must not be negative"))
+	}
+	if cronLines < 0 || cronLines > maxOutputLines {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--lines must be between 0 and %d", maxOutputLines))
+	}
+	types, err := parseExitTypes(cronExitTypes)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	// Check the token before running, not after a long job
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
+	}
+	c := newClient(cmd, token)
+
+	lock, err := job.Lock()
+	if stderrors.Is(err, jobs.ErrLocked) {
+		return skipOverlappingRun(cmd, c, job, types)
+	}
+	if err != nil {
+		return clierrors.NewSystemError("Failed to lock job", err)
+	}
+	defer lock.Unlock()
+
+	err = job.Update(func(s *jobs.State) error {
+		if s.Running != nil {
+			logging.Debug("Previous run did not finish", "job", job.Name, "started_at", s.Running.StartedAt, "pid", s.Running.PID)
+		}
+		s.Running = &jobs.Run{StartedAt: time.Now().UTC(), PID: os.Getpid()}
+		return nil
+	})
+	if err != nil {
+		return clierrors.NewSystemError("Failed to update job state", err)
+	}
+
+	logging.Debug("Running job", "job", job.Name, "program", args[0])
+	result := runner.Run(context.Background(), args, runner.Options{TailLines: cronLines})
+	if result.StartErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to run %s: %v\n", args[0], result.StartErr)
+	}
+
+	// The usual duration is taken from the runs before this one
+	// A transition stays pending until its notification is delivered or queued
+	var (
+		transition jobs.Transition
+		pending    *jobs.Pending
+		previous   jobs.State
+	)
+	err = job.Update(func(s *jobs.State) error {
+		previous = *s
+		transition = s.Record(jobs.Run{
+			StartedAt: result.StartedAt.UTC(),
+			Duration:  result.Duration,
+			ExitCode:  result.ExitCode,
+		}, cronRemindEvery)
+		pending = s.Pending
+		return nil
+	})
+	if err != nil {
+		// Without state every run looks like the first; notify anyway rather than stay silent
+		fmt.Fprintf(os.Stderr, "Warning: failed to update job state: %v\n", err)
+		transition, pending = jobs.NoChange, nil
+		if !result.Success() {
+			transition = jobs.Failed
+			pending = &jobs.Pending{Transition: jobs.Failed, Failures: 1, FailingSince: result.StartedAt}
+		}
+	}
+	logging.Debug("Job finished", "job", job.Name, "exit_code", result.ExitCode, "duration", result.Duration, "transition", transition)
+
+	if pending != nil {
+		if pending.Transition != transition {
+			logging.Debug("Retrying pending notification", "job", job.Name, "transition", pending.Transition)
+		}
+		opts := &client.SendOptions{
+			Title:     jobTitle(job.Name, pending, result),
+			Message:   jobMessage(pending, result, &previous),
+			Type:      types.typeFor(result.ExitCode, cronType),
+			Tags:      cronTags,
+			ActionURL: cronActionURL,
+		}
+		if err := sendNotification(cmd, c, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to send notification, retrying on the next run: %v\n", err)
+		} else {
+			err := job.Update(func(s *jobs.State) error {
+				s.Notified()
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update job state: %v\n", err)
+			}
+		}
+	}
+
+	// Pass on the command's exit code without printing an error
+	if !result.Success() {
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		return clierrors.NewExitError(result.ExitCode)
+	}
+	return nil
+}
+
+// skipOverlappingRun reports a run started while the previous one is still running
+// The first skipped run is notified, and then every --remind-every skipped runs.
+func skipOverlappingRun(cmd *cobra.Command, c *client.Client, job *jobs.Job, types *exitTypes) error {
+	var (
+		running  *jobs.Run
+		overlaps int
+		notify   bool
+	)
+	err := job.Update(func(s *jobs.State) error {
+		running = s.Running
+		notify = s.RecordOverlap(cronRemindEvery)
+		overlaps = s.Overlaps
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update job state: %v\n", err)
+		notify = true
+	}
+
+	previousRun := "The previous run is still running."
+	cause := fmt.Errorf("job %s is still running", job.Name)
+	if running != nil {
+		previousRun = fmt.Sprintf("The run started %s (pid %d) is still running.", formatSince(running.StartedAt), running.PID)
+		cause = fmt.Errorf("job %s is still running: started %s (pid %d)", job.Name, formatSince(running.StartedAt), running.PID)
+	}
+	logging.Debug("Skipping overlapping run", "job", job.Name, "overlaps", overlaps, "notify", notify)
+
+	if notify {
+		message := fmt.Sprintf("%s\nSkipped runs so far: %d", previousRun, overlaps)
+		opts := &client.SendOptions{
+			Title:     fmt.Sprintf("%s skipped: previous run still running", job.Name),
+			Message:   message,
+			Type:      types.failureType(cronType),
+			Tags:      cronTags,
+			ActionURL: cronActionURL,
+		}
+		if err := sendNotification(cmd, c, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
+		}
+	}
+
+	cmd.SilenceUsage = true
+	return clierrors.NewSystemError("Run skipped", cause)
+}
+
+// jobTitle returns the notification title for a pending transition of the job called name
+func jobTitle(name string, pending *jobs.Pending, result *runner.Result) string {
+	switch pending.Transition {
+	case jobs.Recovered:
+		return fmt.Sprintf("%s recovered", name)
+	case jobs.StillFailing:
+		return fmt.Sprintf("%s still failing (%d runs)", name, pending.Failures)
+	default:
+		return fmt.Sprintf("%s %s", name, runStatus(result))
+	}
+}
+
+// jobMessage explains a pending transition, followed by the details of the run
+// previous is the job state before the run.
+func jobMessage(pending *jobs.Pending, result *runner.Result, previous *jobs.State) string {
+	var b strings.Builder
+	switch pending.Transition {
+	case jobs.Recovered:
+		fmt.Fprintf(&b, "Failed %d %s in a row, since %s\n", pending.Failures, templates.Plural(pending.Failures, "run", "runs"), formatSince(pending.FailingSince))
+	case jobs.StillFailing:
+		fmt.Fprintf(&b, "Failing since %s\n", formatSince(pending.FailingSince))
+	case jobs.Failed:
+		if last, ok := lastSuccess(previous); ok {
+			fmt.Fprintf(&b, "Last success: %s\n", formatSince(last.StartedAt))
+		}
+	}
+
+	if median, n := previous.MedianDuration(); n > 0 {
+		fmt.Fprintf(&b, "Usual duration: %s (median of the last %d successful %s)\n", formatDuration(median), n, templates.Plural(n, "run", "runs"))
+	}
+
+	if b.Len() == 0 {
+		return runMessage(result)
+	}
+	return b.String() + "\n" + runMessage(result)
+}
+
+// lastSuccess returns the most recent successful run in the job's history
+func lastSuccess(s *jobs.State) (jobs.Run, bool) {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		if s.History[i].Success() {
+			return s.History[i], true
+		}
+	}
+	return jobs.Run{}, false
+}
+
+// formatSince formats a past time with how long ago it was, e.g. "2025-01-02 09:00 (1h45m ago)"
+func formatSince(t time.Time) string {
+	ago := time.Since(t).Round(time.Minute)
+	if ago < time.Minute {
+		return fmt.Sprintf("%s (just now)", t.Local().Format("2006-01-02 15:04"))
+	}
+	return fmt.Sprintf("%s (%s ago)", t.Local().Format("2006-01-02 15:04"), strings.TrimSuffix(ago.String(), "0s"))
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/spf13/cobra"
+)
+
+// cryptoCmd represents the crypto command
+var cryptoCmd = &cobra.Command{
+	Use:   "crypto",
+	Short: "Encrypt and decrypt messages offline",
+	Long: `Encrypt and decrypt notification messages locally, exactly as the Pincho
+app does, without contacting the API.
+
+Use decrypt to debug notifications the app shows as garbage: take the message
+and IV the API stored and check whether the type's password decrypts them.
+Use encrypt to produce the payload a password yields before rolling it out.
+
+The password is read from --encryption-password-file, PINCHO_ENCRYPTION_PASSWORD
+or --encryption-password, or asked for when run in a terminal.
+
+Examples:
+  pincho crypto encrypt "Database password rotated"
+  pincho crypto decrypt "y2fzGqnZ..." --iv 0123456789abcdef0123456789abcdef --encryption-password-file ~/.pincho/secure.password
+`,
+}
+
+// cryptoEncryptCmd represents the 'crypto encrypt' command
+var cryptoEncryptCmd = &cobra.Command{
+	Use:   "encrypt [message]",
+	Short: "Encrypt a message",
+	Long: `Encrypt a message as the send command does (AES-128-CBC unless
+--encryption-scheme v2 is given) and print the encrypted message and its IV.
+
+A random IV is generated unless --iv is given; the same message, password and
+IV always give the same result, which is useful to compare with other SDKs.
+With --encryption-scheme v2 the result differs every time, as it includes a
+random salt.
+
+Examples:
+  pincho crypto encrypt "Sensitive data"
+  echo "Sensitive data" | pincho crypto encrypt --stdin --encryption-password-file ~/.pincho/secure.password
+  pincho crypto encrypt "Sensitive data" --iv 0123456789abcdef0123456789abcdef
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCryptoEncrypt,
+}
+
+// cryptoDecryptCmd represents the 'crypto decrypt' command
+var cryptoDecryptCmd = &cobra.Command{
+	Use:   "decrypt [encrypted-message]",
+	Short: "Decrypt a message",
+	Long: `Decrypt a message encrypted by the send command, an SDK or the app.
+
+The IV is the hex string sent with the notification (the "iv" field). The
+scheme (v1 or v2) is detected from the message. A wrong password usually
+fails with a padding error; rarely, a v1 message decrypts to garbage instead,
+which is reported as text that is not valid UTF-8. The padding added by
+send --pad-to is removed; trailing whitespace of the message itself is kept.
+
+Examples:
+  pincho crypto decrypt "y2fzGqnZ..." --iv 0123456789abcdef0123456789abcdef
+  echo "y2fzGqnZ..." | pincho crypto decrypt --stdin --iv 0123... --encryption-password-file ~/.pincho/secure.password
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCryptoDecrypt,
+}
+
+var (
+	cryptoPassword string
+	cryptoPwFile   string
+	cryptoScheme   string
+	cryptoIV       string
+	cryptoPadTo    int
+	cryptoStdin    bool
+	cryptoJSON     bool
+)
+
+func init() {
+	rootCmd.AddCommand(cryptoCmd)
+	cryptoCmd.AddCommand(cryptoEncryptCmd)
+	cryptoCmd.AddCommand(cryptoDecryptCmd)
+
+	for _, c := range []*cobra.Command{cryptoEncryptCmd, cryptoDecryptCmd} {
+		c.Flags().StringVar(&cryptoPassword, "encryption-password", "", "Password configured for the notification type in the app (visible in process listings, prefer --encryption-password-file)")
+		c.Flags().StringVar(&cryptoPwFile, "encryption-password-file", "", "File to read the password from (env: PINCHO_ENCRYPTION_PASSWORD for the password itself)")
+		c.Flags().BoolVar(&cryptoStdin, "stdin", false, "Read the message from stdin")
+		c.Flags().BoolVar(&cryptoJSON, "json", false, "Output result as JSON")
+	}
+	cryptoEncryptCmd.Flags().StringVar(&cryptoIV, "iv", "", "IV as 32 hex characters (default: random)")
+	cryptoEncryptCmd.Flags().IntVar(&cryptoPadTo, "pad-to", 0, "Pad the message to a multiple of this many bytes before encrypting, as send --pad-to does")
+	cryptoEncryptCmd.Flags().StringVar(&cryptoScheme, "encryption-scheme", string(crypto.SchemeV1), "Encryption scheme: v1 (AES-128-CBC, supported by the app) or v2 (salted PBKDF2, AES-256-GCM)")
+	cryptoDecryptCmd.Flags().StringVar(&cryptoIV, "iv", "", "IV the message was encrypted with, as 32 hex characters (required)")
+}
+
+// cryptoInput returns the message from args or stdin and the password
+// The password is asked for on an interactive terminal if not given otherwise.
+func cryptoInput(cmd *cobra.Command, args []string) (string, string, error) {
+	if cryptoStdin && len(args) > 0 {
+		return "", "", clierrors.NewUsageError("Invalid arguments", fmt.Errorf("give the message as an argument or with --stdin, not both"))
+	}
+	if !cryptoStdin && len(args) == 0 {
+		return "", "", clierrors.NewUsageError("Invalid arguments", fmt.Errorf("message is required (as an argument or with --stdin)"))
+	}
+
+	password, source, err := getEncryptionPassword(cmd)
+	if err != nil {
+		return "", "", err
+	}
+	if password == "" {
+		var prompted bool
+		if password, prompted, err = promptEncryptionPassword(cmd, "Encryption password: "); err != nil {
+			return "", "", err
+		}
+		if !prompted {
+			return "", "", clierrors.NewUsageError("Invalid arguments", fmt.Errorf("password is required (--encryption-password-file, %s or --encryption-password)", encryptionPasswordEnv))
+		}
+		source = "prompt"
+	}
+	logging.Debug("Encryption password source", "source", source)
+
+	if !cryptoStdin {
+		return args[0], password, nil
+	}
+	message, err := readStdin()
+	if err != nil {
+		return "", "", clierrors.NewUsageError("Invalid arguments", err)
+	}
+	return message, password, nil
+}
+
+func runCryptoEncrypt(cmd *cobra.Command, args []string) error {
+	message, password, err := cryptoInput(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	scheme, err := crypto.ParseScheme(cryptoScheme)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	var iv []byte
+	if cryptoIV != "" {
+		if iv, err = crypto.ParseIV(cryptoIV); err != nil {
+			return clierrors.NewUsageError("Invalid arguments", err)
+		}
+	} else if iv, _, err = crypto.GenerateIV(); err != nil {
+		return clierrors.NewSystemError("Encryption failed", err)
+	}
+
+	padded, err := crypto.PadMessage(message, cryptoPadTo)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	encrypted, err := crypto.Encrypt(padded, password, scheme, iv)
+	if err != nil {
+		return clierrors.NewSystemError("Encryption failed", err)
+	}
+
+	ivHex := hex.EncodeToString(iv)
+	if cryptoJSON {
+		return printCryptoJSON(map[string]string{"message": encrypted, "iv": ivHex})
+	}
+
+	fmt.Printf("Message: %s\n", encrypted)
+	fmt.Printf("IV: %s\n", ivHex)
+	return nil
+}
+
+func runCryptoDecrypt(cmd *cobra.Command, args []string) error {
+	encrypted, password, err := cryptoInput(cmd, args)
+	if err != nil {
+		return err
+	}
+	if cryptoIV == "" {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--iv is required"))
+	}
+
+	iv, err := crypto.ParseIV(cryptoIV)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	plaintext, err := crypto.DecryptMessage(strings.TrimSpace(encrypted), password, iv)
+	if err != nil {
+		return clierrors.NewUsageError("Decryption failed", err)
+	}
+
+	// Valid padding by chance still yields garbage for a wrong password
+	if !utf8.ValidString(plaintext) {
+		return clierrors.NewUsageError("Decryption failed", fmt.Errorf("decrypted text is not valid UTF-8 (wrong password?)"))
+	}
+	plaintext = crypto.StripPadding(plaintext)
+
+	if cryptoJSON {
+		return printCryptoJSON(map[string]string{"message": plaintext})
+	}
+
+	fmt.Println(plaintext)
+	return nil
+}
+
+// printCryptoJSON prints the result of a crypto command as JSON
+func printCryptoJSON(result map[string]string) error {
+	jsonBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format JSON response: %w", err)
+	}
+	fmt.Println(string(jsonBytes))
+	return nil
+}
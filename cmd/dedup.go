@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	"github.com/Pincho-App/pincho-cli/pkg/dedup"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// dedupCheck suppresses notifications identical to one sent recently from this machine
+type dedupCheck struct {
+	check *dedup.Check
+}
+
+// getDedupWindow retrieves how long an identical notification is suppressed
+// Priority: flag > env var > config file > default (0, disabled)
+func getDedupWindow(cmd *cobra.Command) time.Duration {
+	// Try flag first (only if explicitly set)
+	if cmd.Flags().Changed("dedup-window") {
+		if window, err := cmd.Flags().GetDuration("dedup-window"); err == nil {
+			return window
+		}
+	}
+
+	// Try environment variable
+	if windowStr := os.Getenv("PINCHO_DEDUP_WINDOW"); windowStr != "" {
+		if window, err := time.ParseDuration(windowStr); err == nil {
+			return window
+		}
+	}
+
+	// Try config file
+	return viper.GetDuration(config.Key("dedup_window"))
+}
+
+// newDedupCheck returns the duplicate check for a notification, or nil if suppression is disabled
+// parts identify the notification content; an explicit dedupKey replaces them
+func newDedupCheck(cmd *cobra.Command, token, endpoint, dedupKey string, parts ...string) (*dedupCheck, error) {
+	window := getDedupWindow(cmd)
+	if window <= 0 {
+		if dedupKey != "" {
+			return nil, fmt.Errorf("--dedup-key requires a dedup window (--dedup-window, PINCHO_DEDUP_WINDOW or dedup_window)")
+		}
+		return nil, nil
+	}
+
+	// Keys are scoped to the token so different recipients never suppress each other
+	check, err := dedup.Open(dedup.Key(token, endpoint, dedupKey, parts...), window)
+	if err != nil {
+		logging.Debug("Duplicate suppression unavailable", "error", err)
+		return nil, nil
+	}
+	return &dedupCheck{check: check}, nil
+}
+
+// lookup reports when an identical notification was last sent within the window
+func (d *dedupCheck) lookup() (time.Time, bool) {
+	if d == nil {
+		return time.Time{}, false
+	}
+	sentAt, found, err := d.check.Lookup()
+	if err != nil {
+		logging.Debug("Failed to read duplicate suppression state", "error", err)
+		return time.Time{}, false
+	}
+	return sentAt, found
+}
+
+// record marks the notification as sent successfully
+func (d *dedupCheck) record() {
+	if d == nil {
+		return
+	}
+	// Best effort: a delivered notification never fails because of local state
+	if err := d.check.Record(time.Now()); err != nil {
+		logging.Debug("Failed to record sent notification", "error", err)
+	}
+}
+
+// displaySuppressed reports a notification skipped because an identical one was sent recently
+// Suppression is not a failure: the recipient already has the notification
+func displaySuppressed(d *dedupCheck, sentAt time.Time, asJSON bool) error {
+	if asJSON {
+		jsonBytes, err := json.MarshalIndent(map[string]any{
+			"status":      "suppressed",
+			"sentAt":      sentAt,
+			"dedupWindow": d.check.Window.String(),
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+
+	fmt.Println("✓ Notification suppressed, an identical notification was sent recently")
+	fmt.Println()
+	fmt.Printf("Sent at: %s\n", sentAt.Local().Format(time.RFC3339))
+	fmt.Printf("Dedup window: %s\n", d.check.Window)
+	return nil
+}
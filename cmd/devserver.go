@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/pinchotest"
+	"github.com/Pincho-App/pincho-cli/pkg/secrets"
+	"github.com/spf13/cobra"
+)
+
+// devServerInspectPath is where captured notifications are exposed as JSON
+const devServerInspectPath = "/_notifications"
+
+// devServerCmd represents the dev-server command
+var devServerCmd = &cobra.Command{
+	Use:   "dev-server",
+	Short: "Run a local fake Pincho API and show received notifications",
+	Long: `Run a local fake of the Pincho API for developing alerting scripts.
+
+The server accepts /send and /notifai requests with any token, prints every
+notification it receives and never forwards anything, so iterating on a
+script neither uses your rate limit quota nor notifies your phone.
+
+Encrypted messages are decrypted when the password for the notification type
+is given with --type-password-file (a file holding the password) or
+--type-password. Captured notifications are available as JSON
+at /_notifications (GET to list, DELETE to clear).
+
+Examples:
+  # Start the server
+  pincho dev-server --port 8787
+
+  # In another terminal, point the CLI at it
+  export PINCHO_API_URL=http://127.0.0.1:8787/send
+  pincho send "Disk full" "/var is at 98%"
+
+  # Decrypt messages of type "secure"
+  pincho dev-server --type-password-file secure=~/.pincho/secure.password
+
+  # Inspect captured notifications
+  curl http://127.0.0.1:8787/_notifications
+`,
+	Args: cobra.NoArgs,
+	RunE: runDevServer,
+}
+
+var (
+	devServerPort              int
+	devServerHost              string
+	devServerTypePasswords     []string
+	devServerTypePasswordFiles []string
+)
+
+func init() {
+	rootCmd.AddCommand(devServerCmd)
+
+	devServerCmd.Flags().IntVar(&devServerPort, "port", 8787, "Port to listen on")
+	devServerCmd.Flags().StringVar(&devServerHost, "host", "127.0.0.1", "Address to listen on")
+	devServerCmd.Flags().StringArrayVar(&devServerTypePasswords, "type-password", nil, "Decryption password for a notification type as type=password (repeatable, visible in process listings, prefer --type-password-file)")
+	devServerCmd.Flags().StringArrayVar(&devServerTypePasswordFiles, "type-password-file", nil, "File holding the decryption password for a notification type as type=path (repeatable)")
+}
+
+// devNotification is a captured notification as shown by the inspection endpoint
+type devNotification struct {
+	Endpoint         string    `json:"endpoint"`
+	ReceivedAt       time.Time `json:"receivedAt"`
+	IdempotencyKey   string    `json:"idempotencyKey,omitempty"`
+	Title            string    `json:"title,omitempty"`
+	Message          string    `json:"message,omitempty"`
+	DecryptedMessage string    `json:"decryptedMessage,omitempty"`
+	Type             string    `json:"type,omitempty"`
+	Tags             []string  `json:"tags,omitempty"`
+	ImageURL         string    `json:"imageURL,omitempty"`
+	ActionURL        string    `json:"actionURL,omitempty"`
+	IV               string    `json:"iv,omitempty"`
+	Text             string    `json:"text,omitempty"`
+}
+
+func runDevServer(cmd *cobra.Command, args []string) error {
+	if len(devServerTypePasswords) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: --type-password is visible to other users in process listings and saved in shell history; use --type-password-file instead\n")
+	}
+	passwords, err := parseTypePasswords(devServerTypePasswords)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid --type-password", err)
+	}
+	if err := readTypePasswordFiles(devServerTypePasswordFiles, passwords); err != nil {
+		return clierrors.NewUsageError("Invalid --type-password-file", err)
+	}
+
+	handler := pinchotest.NewHandler()
+	handler.OnNotification = func(n pinchotest.Notification) {
+		printDevNotification(n, passwords)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(devServerInspectPath, func(w http.ResponseWriter, r *http.Request) {
+		serveDevInspection(w, r, handler, passwords)
+	})
+	mux.Handle("/", handler)
+
+	addr := net.JoinHostPort(devServerHost, strconv.Itoa(devServerPort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return clierrors.NewSystemError("Failed to start dev server", err)
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	baseURL := "http://" + listener.Addr().String()
+	fmt.Printf("Pincho dev server listening on %s\n", baseURL)
+	fmt.Println()
+	fmt.Printf("  export PINCHO_API_URL=%s/send\n", baseURL)
+	fmt.Printf("  Captured notifications: %s%s\n", baseURL, devServerInspectPath)
+	fmt.Println()
+	fmt.Println("Press Ctrl+C to stop")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return clierrors.NewSystemError("Dev server failed", err)
+	}
+
+	logging.Debug("Dev server stopped", "notifications", len(handler.Notifications()))
+	return nil
+}
+
+// parseTypePasswords parses type=password pairs into a map
+func parseTypePasswords(pairs []string) (map[string]string, error) {
+	passwords := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		notifType, password, ok := strings.Cut(pair, "=")
+		if !ok || notifType == "" || password == "" {
+			return nil, fmt.Errorf("expected type=password, got %q", pair)
+		}
+		passwords[notifType] = password
+	}
+	return passwords, nil
+}
+
+// readTypePasswordFiles reads the passwords of type=path pairs into passwords
+func readTypePasswordFiles(pairs []string, passwords map[string]string) error {
+	for _, pair := range pairs {
+		notifType, path, ok := strings.Cut(pair, "=")
+		if !ok || notifType == "" || path == "" {
+			return fmt.Errorf("expected type=path, got %q", pair)
+		}
+		password, err := secrets.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("type %s: %w", notifType, err)
+		}
+		passwords[notifType] = password
+	}
+	return nil
+}
+
+// decryptDevMessage decrypts an encrypted message with the password for its type
+// Returns an empty string and no error if the message is not encrypted
+func decryptDevMessage(n pinchotest.Notification, passwords map[string]string) (string, error) {
+	if n.IV == "" {
+		return "", nil
+	}
+
+	password, ok := passwords[n.Type]
+	if !ok {
+		return "", fmt.Errorf("encrypted, pass --type-password-file %s=<path> to decrypt", n.Type)
+	}
+
+	iv, err := crypto.ParseIV(n.IV)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := crypto.DecryptMessage(n.Message, password, iv)
+	if err != nil {
+		return "", err
+	}
+	return crypto.StripPadding(plaintext), nil
+}
+
+// printDevNotification pretty-prints a received notification
+func printDevNotification(n pinchotest.Notification, passwords map[string]string) {
+	fmt.Println()
+	header := fmt.Sprintf("━━━ %s  /%s", n.ReceivedAt.Local().Format("15:04:05"), n.Endpoint)
+	if n.Type != "" {
+		header += fmt.Sprintf("  [%s]", n.Type)
+	}
+	fmt.Println(header)
+
+	if n.Text != "" {
+		fmt.Printf("Text:    %s\n", n.Text)
+	}
+	if n.Title != "" {
+		fmt.Printf("Title:   %s\n", n.Title)
+	}
+	if n.Message != "" {
+		decrypted, err := decryptDevMessage(n, passwords)
+		switch {
+		case err != nil:
+			fmt.Printf("Message: %s (%v)\n", n.Message, err)
+		case decrypted != "":
+			fmt.Printf("Message: %s (decrypted)\n", decrypted)
+		default:
+			fmt.Printf("Message: %s\n", n.Message)
+		}
+	}
+	if len(n.Tags) > 0 {
+		fmt.Printf("Tags:    %s\n", strings.Join(n.Tags, ", "))
+	}
+	if n.ImageURL != "" {
+		fmt.Printf("Image:   %s\n", n.ImageURL)
+	}
+	if n.ActionURL != "" {
+		fmt.Printf("Action:  %s\n", n.ActionURL)
+	}
+	if n.IdempotencyKey != "" {
+		fmt.Printf("Key:     %s\n", n.IdempotencyKey)
+	}
+}
+
+// serveDevInspection lists (GET) or clears (DELETE) captured notifications
+func serveDevInspection(w http.ResponseWriter, r *http.Request, handler *pinchotest.Handler, passwords map[string]string) {
+	switch r.Method {
+	case http.MethodGet:
+		captured := handler.Notifications()
+		result := make([]devNotification, 0, len(captured))
+		for _, n := range captured {
+			decrypted, _ := decryptDevMessage(n, passwords)
+			result = append(result, devNotification{
+				Endpoint:         n.Endpoint,
+				ReceivedAt:       n.ReceivedAt,
+				IdempotencyKey:   n.IdempotencyKey,
+				Title:            n.Title,
+				Message:          n.Message,
+				DecryptedMessage: decrypted,
+				Type:             n.Type,
+				Tags:             n.Tags,
+				ImageURL:         n.ImageURL,
+				ActionURL:        n.ActionURL,
+				IV:               n.IV,
+				Text:             n.Text,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	case http.MethodDelete:
+		handler.Clear()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
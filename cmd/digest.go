@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	"github.com/Pincho-App/pincho-cli/pkg/digest"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/validation"
+	"github.com/spf13/cobra"
+)
+
+// digestCmd represents the digest command
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Aggregate notifications into a periodic summary",
+	Long: `Aggregate many events into a single summary notification.
+
+Noisy batch jobs can add an entry to a named digest for every event instead of
+sending a notification each time, then flush the digest on a schedule. The
+flush sends one notification with counts per type and tag, the first and last
+entries, and the merged tags, instead of dozens of pushes that would hit the
+rate limit.
+
+Entries are stored in ~/.pincho/digests/<name> until flushed.
+
+Examples:
+  # From the batch job, for every event
+  pincho digest add etl "Row import failed" "orders.csv line 42" --type alert --tag db
+
+  # From cron, once an hour
+  pincho digest flush etl
+`,
+}
+
+// digestAddCmd represents the 'digest add' command
+var digestAddCmd = &cobra.Command{
+	Use:   "add <name> <title> [message]",
+	Short: "Add an entry to a digest",
+	Long: `Add an entry to a named digest without sending anything.
+
+Example:
+  pincho digest add etl "Row import failed" "orders.csv line 42" --type alert --tag db
+`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: runDigestAdd,
+}
+
+// digestFlushCmd represents the 'digest flush' command
+var digestFlushCmd = &cobra.Command{
+	Use:   "flush <name>",
+	Short: "Send pending digest entries as one notification",
+	Long: `Collapse the pending entries of a digest into one notification and send it.
+
+The notification lists the number of entries per type and tag, the first and
+last entries as "title: message" (--titles of each, messages cut to 80
+characters), and carries the merged tags of all entries, most frequent first,
+up to the maximum of 10. Its type is the type shared by all entries, if any;
+override it with --type. The default type and tags from
+the config file apply as for send.
+
+Entries are removed once the summary is sent (or queued with --outbox) and
+kept if sending fails. Flushing an empty digest sends nothing. A flush waits
+for any other flush of the same digest to finish, so overlapping runs never
+send the same entries twice.
+
+Examples:
+  pincho digest flush etl
+  pincho digest flush etl --title "ETL hourly report" --titles 5
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDigestFlush,
+}
+
+var (
+	digestAddType   string
+	digestAddTags   []string
+	digestTitle     string
+	digestType      string
+	digestTitles    int
+	digestPassword  string
+	digestPwFile    string
+	digestScheme    string
+	digestNoEncrypt bool
+	digestPadTo     int
+	digestJSON      bool
+	digestOutbox    bool
+)
+
+func init() {
+	rootCmd.AddCommand(digestCmd)
+	digestCmd.AddCommand(digestAddCmd)
+	digestCmd.AddCommand(digestFlushCmd)
+
+	digestAddCmd.Flags().StringVar(&digestAddType, "type", "", "Notification type of the entry")
+	digestAddCmd.Flags().StringSliceVar(&digestAddTags, "tag", []string{}, "Tags of the entry (can be used multiple times)")
+
+	digestFlushCmd.Flags().StringVar(&digestTitle, "title", "", "Title of the summary (default: \"<name>: <count> events\")")
+	digestFlushCmd.Flags().StringVar(&digestType, "type", "", "Notification type of the summary (default: type shared by all entries)")
+	digestFlushCmd.Flags().IntVar(&digestTitles, "titles", digest.DefaultTitles, "Number of first and last entries to list")
+	digestFlushCmd.Flags().StringVar(&digestPassword, "encryption-password", "", "Password for AES-128-CBC encryption of the summary (visible in process listings, prefer --encryption-password-file)")
+	digestFlushCmd.Flags().StringVar(&digestPwFile, "encryption-password-file", "", "File to read the encryption password from (env: PINCHO_ENCRYPTION_PASSWORD for the password itself)")
+	digestFlushCmd.Flags().StringVar(&digestScheme, "encryption-scheme", string(crypto.SchemeV1), "Encryption scheme of the summary: v1 or v2")
+	digestFlushCmd.Flags().IntVar(&digestPadTo, "pad-to", 0, "Pad the encrypted summary to a multiple of this many bytes, e.g. 1024, to hide its length")
+	digestFlushCmd.Flags().BoolVar(&digestNoEncrypt, "no-encrypt", false, "Send unencrypted even if the type has an encryption password in the config file")
+	digestFlushCmd.Flags().BoolVar(&digestJSON, "json", false, "Output response as JSON")
+	digestFlushCmd.Flags().BoolVar(&digestOutbox, "outbox", false, "Queue the summary in the outbox if it cannot be delivered (env: PINCHO_OUTBOX)")
+}
+
+func runDigestAdd(cmd *cobra.Command, args []string) error {
+	d, err := digest.Open(args[0])
+	if err != nil {
+		return clierrors.NewUsageError("Invalid digest name", err)
+	}
+
+	entry := digest.Entry{
+		Title: args[1],
+		Type:  digestAddType,
+		Tags:  digestAddTags,
+	}
+	if len(args) == 3 {
+		entry.Message = args[2]
+	}
+
+	added, err := d.Add(entry)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid entry", err)
+	}
+
+	logging.Debug("Digest entry added", "digest", d.Name, "id", added.ID)
+	fmt.Printf("✓ Added to digest %s\n", d.Name)
+	return nil
+}
+
+func runDigestFlush(cmd *cobra.Command, args []string) error {
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
+	}
+
+	d, err := digest.Open(args[0])
+	if err != nil {
+		return clierrors.NewUsageError("Invalid digest name", err)
+	}
+
+	// The flush lock keeps an overlapping flush from sending the same entries
+	var flushErr error
+	err = d.Flush(func(entries []digest.Entry) error {
+		flushErr = flushDigest(cmd, token, d, entries)
+		return nil
+	})
+	if err != nil {
+		return clierrors.NewSystemError("Failed to read digest", err)
+	}
+	return flushErr
+}
+
+// flushDigest sends the summary of entries and removes them once delivered or queued
+func flushDigest(cmd *cobra.Command, token string, d *digest.Digest, entries []digest.Entry) error {
+	if len(entries) == 0 {
+		if digestJSON {
+			fmt.Println(`{"status": "empty"}`)
+		} else {
+			fmt.Printf("Digest %s is empty, nothing to send\n", d.Name)
+		}
+		return nil
+	}
+
+	summary := digest.Summarize(d.Name, entries, digestTitles)
+	logging.Debug("Digest summarized", "digest", d.Name, "entries", summary.Total, "tags", summary.Tags)
+
+	title := summary.Title
+	if digestTitle != "" {
+		title = digestTitle
+	}
+	notifType := summary.Type
+	if digestType != "" {
+		notifType = digestType
+	}
+
+	notifType = mergeTypeWithDefault(notifType)
+	tags := mergeTagsWithDefaults(summary.Tags)
+	if len(tags) > validation.MaxTags {
+		// Default tags are dropped first, like the least frequent summary tags
+		tags = tags[:validation.MaxTags]
+	}
+
+	encryption, err := resolveEncryption(cmd, notifType)
+	if err != nil {
+		return err
+	}
+
+	c := newClient(cmd, token)
+	prepared, err := c.PrepareSend(&client.SendOptions{
+		Title:              title,
+		Message:            summary.Message,
+		Type:               notifType,
+		Tags:               tags,
+		EncryptionPassword: encryption.password,
+		EncryptionScheme:   encryption.scheme,
+		PadTo:              encryption.padTo,
+	})
+	if err != nil {
+		return categorizeError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	sent, queued, err := deliverPrepared(ctx, c, prepared, getOutboxEnabled(cmd))
+	logBreakerStatus(c)
+	if queued != nil {
+		removeDigestEntries(d, entries)
+		return displayQueued(queued, err, digestJSON)
+	}
+	if err != nil {
+		// Entries stay pending for the next flush
+		return categorizeError(err)
+	}
+
+	removeDigestEntries(d, entries)
+
+	result, err := sent.SendResult()
+	if err != nil {
+		return categorizeError(err)
+	}
+
+	if digestJSON {
+		jsonBytes, err := json.MarshalIndent(map[string]any{
+			"summary": summary,
+			"result":  result,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+	} else {
+		fmt.Printf("Digest %s: %d entries\n", d.Name, summary.Total)
+		displaySendResult(result)
+	}
+
+	return nil
+}
+
+// removeDigestEntries removes sent entries, warning if they would be sent again
+func removeDigestEntries(d *digest.Digest, entries []digest.Entry) {
+	if err := d.Remove(entries); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v (entries may be included in the next flush)\n", err)
+	}
+}
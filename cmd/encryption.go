@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/secrets"
+	"github.com/spf13/cobra"
+)
+
+// encryptionPasswordEnv is the environment variable holding the encryption password
+const encryptionPasswordEnv = "PINCHO_ENCRYPTION_PASSWORD"
+
+// warnPasswordFlag warns once per run, even if the password is resolved per fan-out target
+var warnPasswordFlag sync.Once
+
+// resolvedEncryption holds the settings resolveEncryptionOnce resolved, by type
+var resolvedEncryption = struct {
+	sync.Mutex
+	byType map[string]encryptionSettings
+}{byType: make(map[string]encryptionSettings)}
+
+// getEncryptionPassword retrieves the encryption password from flags or env vars (in that order)
+// Returns the password and a description of its source, or empty strings if
+// none is given. The password itself must never be logged.
+func getEncryptionPassword(cmd *cobra.Command) (string, string, error) {
+	if password, _ := cmd.Flags().GetString("encryption-password"); password != "" {
+		warnPasswordFlag.Do(func() {
+			fmt.Fprintf(os.Stderr, "Warning: --encryption-password is visible to other users in process listings and saved in shell history; use --encryption-password-file or %s instead\n", encryptionPasswordEnv)
+		})
+		return password, "--encryption-password flag", nil
+	}
+
+	if path, _ := cmd.Flags().GetString("encryption-password-file"); path != "" {
+		password, err := secrets.ReadFile(path)
+		if err != nil {
+			return "", "", clierrors.NewUsageError("Failed to read encryption password", err)
+		}
+		return password, "--encryption-password-file flag", nil
+	}
+
+	if password := os.Getenv(encryptionPasswordEnv); password != "" {
+		return password, encryptionPasswordEnv, nil
+	}
+	return "", "", nil
+}
+
+// promptEncryptionPassword asks for the encryption password without echoing it
+// The boolean is false, and nothing is asked, if stdin is not an interactive
+// terminal or carries the message (--stdin).
+func promptEncryptionPassword(cmd *cobra.Command, prompt string) (string, bool, error) {
+	if useStdin, _ := cmd.Flags().GetBool("stdin"); useStdin || !secrets.IsTerminal(os.Stdin) {
+		return "", false, nil
+	}
+
+	password, err := secrets.PromptPassword(os.Stdin, prompt)
+	if err != nil {
+		return "", false, clierrors.NewUsageError("Failed to read encryption password", err)
+	}
+	return password, true, nil
+}
+
+// encryptionSettings is how a notification is encrypted; an empty password means not at all
+type encryptionSettings struct {
+	password string
+	scheme   crypto.Scheme
+	padTo    int
+}
+
+// resolveEncryption returns the encryption settings of a notification of notifType
+//
+// --no-encrypt turns encryption off. Otherwise the password is taken from
+// --encryption-password, --encryption-password-file or
+// PINCHO_ENCRYPTION_PASSWORD, or else from the type's entry in the encryption
+// section of the config file (the active profile's, if it has one). If a type
+// with an entry has no password, it is asked for on an interactive terminal;
+// without one the notification is sent unencrypted, with a warning. The
+// scheme and padding come from flags, or else from the type's entry. Padding
+// only applies to encrypted messages, so --pad-to without a password is an
+// error rather than a silently unpadded plaintext.
+func resolveEncryption(cmd *cobra.Command, notifType string) (encryptionSettings, error) {
+	if noEncrypt, _ := cmd.Flags().GetBool("no-encrypt"); noEncrypt {
+		if padToFlagSet(cmd) {
+			return encryptionSettings{}, clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--no-encrypt cannot be combined with --pad-to"))
+		}
+		for _, flag := range []string{"encryption-password", "encryption-password-file"} {
+			if value, _ := cmd.Flags().GetString(flag); value != "" {
+				return encryptionSettings{}, clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--no-encrypt cannot be combined with --%s", flag))
+			}
+		}
+		logging.Debug("Encryption disabled", "source", "--no-encrypt flag")
+		return encryptionSettings{}, nil
+	}
+
+	entry, configured, err := config.EncryptionFor(notifType)
+	if err != nil {
+		return encryptionSettings{}, clierrors.NewUsageError("Invalid config", err)
+	}
+
+	scheme, _ := cmd.Flags().GetString("encryption-scheme")
+	if configured && entry.Scheme != "" && !cmd.Flags().Changed("encryption-scheme") {
+		scheme = entry.Scheme
+	}
+	padTo, _ := cmd.Flags().GetInt("pad-to")
+	if configured && !cmd.Flags().Changed("pad-to") {
+		padTo = entry.PadTo
+	}
+	settings := encryptionSettings{scheme: crypto.Scheme(scheme), padTo: padTo}
+
+	password, source, err := getEncryptionPassword(cmd)
+	if err != nil {
+		return encryptionSettings{}, err
+	}
+	if password != "" {
+		logging.Debug("Encryption password source", "source", source)
+		settings.password = password
+		return settings, nil
+	}
+	if !configured {
+		if padToFlagSet(cmd) {
+			return encryptionSettings{}, errPadToWithoutPassword()
+		}
+		return encryptionSettings{}, nil
+	}
+
+	name := notifType
+	if profile := config.ActiveProfile(); profile != "" {
+		name = fmt.Sprintf("%s (profile %s)", notifType, profile)
+	}
+
+	password, err = entry.ResolvePassword(context.Background())
+	if err != nil {
+		return encryptionSettings{}, clierrors.NewUsageError("Failed to read encryption password", fmt.Errorf("type %s: %w", name, err))
+	}
+	if password != "" {
+		logging.Debug("Encryption password source", "type", name, "source", entry.Source())
+		settings.password = password
+		return settings, nil
+	}
+
+	password, prompted, err := promptEncryptionPassword(cmd, fmt.Sprintf("Encryption password for type %s: ", name))
+	if err != nil {
+		return encryptionSettings{}, err
+	}
+	if prompted {
+		logging.Debug("Encryption password source", "type", name, "source", "prompt")
+		settings.password = password
+		return settings, nil
+	}
+
+	if padToFlagSet(cmd) {
+		return encryptionSettings{}, errPadToWithoutPassword()
+	}
+	unpadded := ""
+	if settings.padTo > 0 {
+		unpadded = " and unpadded"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: type %s is configured for encryption, but no password was found (%s); sending unencrypted%s\n", name, entry.Source(), unpadded)
+	return encryptionSettings{}, nil
+}
+
+// padToFlagSet reports whether padding was asked for with --pad-to
+func padToFlagSet(cmd *cobra.Command) bool {
+	padTo, _ := cmd.Flags().GetInt("pad-to")
+	return cmd.Flags().Changed("pad-to") && padTo > 0
+}
+
+// errPadToWithoutPassword reports --pad-to given for a notification that is not encrypted
+func errPadToWithoutPassword() error {
+	return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--pad-to only applies to encrypted messages, but no encryption password was given; pass one with --encryption-password-file or %s", encryptionPasswordEnv))
+}
+
+// resolveEncryptionOnce is resolveEncryption for commands sending many notifications
+// The settings of each type are resolved once per run, so a long-running
+// command reads or asks for a password, or warns that there is none, once.
+func resolveEncryptionOnce(cmd *cobra.Command, notifType string) (encryptionSettings, error) {
+	resolvedEncryption.Lock()
+	defer resolvedEncryption.Unlock()
+
+	key := strings.ToLower(notifType)
+	if settings, ok := resolvedEncryption.byType[key]; ok {
+		return settings, nil
+	}
+
+	settings, err := resolveEncryption(cmd, notifType)
+	if err != nil {
+		return encryptionSettings{}, err
+	}
+	resolvedEncryption.byType[key] = settings
+	return settings, nil
+}
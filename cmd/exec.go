@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/runner"
+	"github.com/spf13/cobra"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] -- <command> [args...]",
+	Short: "Run a command and notify when it finishes",
+	Long: `Run a command, passing its output through unchanged, and send a
+notification when it finishes with its exit code, duration, CPU time, peak
+memory and the last lines of its output.
+
+pincho exits with the exit code of the command, so it can wrap any step of a
+script or CI pipeline. A command killed by a signal exits with 128 plus the
+signal number, one that cannot be started with 127 (not found) or 126. A
+notification that cannot be sent is reported as a warning and does not change
+the exit code.
+
+--exit-type picks the notification type by exit code: an exit code, "success"
+(0) or "failure" (any non-zero code) and a type. Other exit codes use --type.
+
+Examples:
+  # Notify when the tests finish
+  pincho exec -- make test
+
+  # Only notify about failures, as alerts
+  pincho exec --on failure --exit-type failure=alert -- ./backup.sh --full
+
+  # Name the job and include more output
+  pincho exec --name "Deploy prod" --tag deploy --lines 30 -- ./deploy.sh prod
+
+  # One type per outcome
+  pincho exec --exit-type success=success --exit-type 2=warning --exit-type failure=alert -- ./check.sh
+`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+// When to notify about a finished command (--on)
+const (
+	notifyAlways  = "always"
+	notifySuccess = "success"
+	notifyFailure = "failure"
+)
+
+const (
+	// maxOutputLines bounds the number of output lines in a notification
+	maxOutputLines = 100
+
+	// maxOutputLineLength is the length at which output lines are cut in a notification
+	maxOutputLineLength = 200
+)
+
+var (
+	execOn        string
+	execName      string
+	execType      string
+	execExitTypes []string
+	execTags      []string
+	execLines     int
+	execActionURL string
+)
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// Flags after the command belong to the command
+	execCmd.Flags().SetInterspersed(false)
+
+	execCmd.Flags().StringVar(&execOn, "on", notifyAlways, "When to notify: always, success or failure")
+	execCmd.Flags().StringVar(&execName, "name", "", "Name of the command in the notification (default: the program name)")
+	execCmd.Flags().StringVar(&execType, "type", "", "Notification type for exit codes without an --exit-type")
+	execCmd.Flags().StringArrayVar(&execExitTypes, "exit-type", nil, "Notification type for an exit code, as code=type, success=type or failure=type (can be used multiple times)")
+	execCmd.Flags().StringSliceVar(&execTags, "tag", []string{}, "Tags for categorization (can be used multiple times)")
+	execCmd.Flags().IntVar(&execLines, "lines", 10, "Number of last output lines to include (0 for none)")
+	execCmd.Flags().StringVar(&execActionURL, "action-url", "", "Action URL to open when notification is tapped, e.g. the CI run")
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	if err := checkNotifyOn(execOn); err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+	if execLines < 0 || execLines > maxOutputLines {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--lines must be between 0 and %d", maxOutputLines))
+	}
+	types, err := parseExitTypes(execExitTypes)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	// Check the token before running, not after a long job
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
+	}
+	c := newClient(cmd, token)
+
+	name := execName
+	if name == "" {
+		name = filepath.Base(args[0])
+	}
+
+	logging.Debug("Running command", "name", name, "program", args[0])
+	result := runner.Run(context.Background(), args, runner.Options{TailLines: execLines})
+	if result.StartErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to run %s: %v\n", args[0], result.StartErr)
+	}
+	logging.Debug("Command finished", "exit_code", result.ExitCode, "duration", result.Duration)
+
+	if shouldNotify(execOn, result) {
+		opts := &client.SendOptions{
+			Title:     fmt.Sprintf("%s %s", name, runStatus(result)),
+			Message:   runMessage(result),
+			Type:      types.typeFor(result.ExitCode, execType),
+			Tags:      execTags,
+			ActionURL: execActionURL,
+		}
+		if err := sendNotification(cmd, c, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
+		}
+	}
+
+	// Pass on the command's exit code without printing an error
+	if !result.Success() {
+		cmd.SilenceErrors, cmd.SilenceUsage = true, true
+		return clierrors.NewExitError(result.ExitCode)
+	}
+	return nil
+}
+
+// checkNotifyOn validates an --on value
+func checkNotifyOn(on string) error {
+	switch on {
+	case notifyAlways, notifySuccess, notifyFailure:
+		return nil
+	default:
+		return fmt.Errorf("invalid --on value %q (use %s, %s or %s)", on, notifyAlways, notifySuccess, notifyFailure)
+	}
+}
+
+// shouldNotify reports whether a finished command is notified with --on
+func shouldNotify(on string, result *runner.Result) bool {
+	switch on {
+	case notifySuccess:
+		return result.Success()
+	case notifyFailure:
+		return !result.Success()
+	default:
+		return true
+	}
+}
+
+// exitTypes maps exit codes to notification types (--exit-type)
+type exitTypes struct {
+	codes   map[int]string
+	failure string // Type of non-zero exit codes without their own entry
+}
+
+// parseExitTypes parses code=type pairs; the code can also be "success" (0) or "failure" (non-zero)
+func parseExitTypes(pairs []string) (*exitTypes, error) {
+	types := &exitTypes{codes: make(map[int]string)}
+	for _, pair := range pairs {
+		code, notifType, ok := strings.Cut(pair, "=")
+		if !ok || notifType == "" {
+			return nil, fmt.Errorf("invalid --exit-type %q (expected code=type)", pair)
+		}
+
+		switch code = strings.ToLower(strings.TrimSpace(code)); code {
+		case "success":
+			types.codes[0] = notifType
+		case "failure":
+			types.failure = notifType
+		default:
+			n, err := strconv.Atoi(code)
+			if err != nil || n < 0 || n > 255 {
+				return nil, fmt.Errorf("invalid exit code %q in --exit-type (expected 0-255, success or failure)", code)
+			}
+			types.codes[n] = notifType
+		}
+	}
+	return types, nil
+}
+
+// typeFor returns the notification type for an exit code, fallback if none is mapped
+func (t *exitTypes) typeFor(code int, fallback string) string {
+	if notifType, ok := t.codes[code]; ok {
+		return notifType
+	}
+	if code != 0 {
+		return t.failureType(fallback)
+	}
+	return fallback
+}
+
+// failureType returns the notification type of a failure without an exit code of its own
+func (t *exitTypes) failureType(fallback string) string {
+	if t.failure != "" {
+		return t.failure
+	}
+	return fallback
+}
+
+// runStatus describes how a command finished, for a notification title
+func runStatus(result *runner.Result) string {
+	switch {
+	case result.StartErr != nil:
+		return "could not be started"
+	case result.Signal != "":
+		return fmt.Sprintf("was killed (%s)", result.Signal)
+	case result.Success():
+		return "succeeded"
+	default:
+		return fmt.Sprintf("failed with exit code %d", result.ExitCode)
+	}
+}
+
+// runMessage reports exit code, resource usage and output tail of a finished command
+func runMessage(result *runner.Result) string {
+	var b strings.Builder
+	if result.StartErr != nil {
+		fmt.Fprintf(&b, "Error: %v\n", result.StartErr)
+	}
+	fmt.Fprintf(&b, "Exit code: %d\n", result.ExitCode)
+	fmt.Fprintf(&b, "Duration: %s\n", formatDuration(result.Duration))
+	if result.StartErr == nil {
+		fmt.Fprintf(&b, "CPU: %s user, %s system\n", formatDuration(result.UserTime), formatDuration(result.SystemTime))
+	}
+	if result.PeakRSS > 0 {
+		fmt.Fprintf(&b, "Peak memory: %s\n", formatBytes(result.PeakRSS))
+	}
+	if host, err := os.Hostname(); err == nil {
+		fmt.Fprintf(&b, "Host: %s\n", host)
+	}
+
+	if len(result.Output) > 0 {
+		b.WriteString("\nLast lines of output:\n")
+		for _, line := range result.Output {
+			b.WriteString(truncateRunes(line, maxOutputLineLength))
+			b.WriteByte('\n')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sendNotification sends a notification built by a command, such as the report of a finished command
+// Defaults and encryption configured for its type apply as with send.
+func sendNotification(cmd *cobra.Command, c *client.Client, opts *client.SendOptions) error {
+	opts.Type = mergeTypeWithDefault(opts.Type)
+	opts.Tags = mergeTagsWithDefaults(opts.Tags)
+
+	encryption, err := resolveEncryptionOnce(cmd, opts.Type)
+	if err != nil {
+		return err
+	}
+	opts.EncryptionPassword = encryption.password
+	opts.EncryptionScheme = encryption.scheme
+	opts.PadTo = encryption.padTo
+
+	prepared, err := c.PrepareSend(opts)
+	if err != nil {
+		return categorizeError(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	_, queued, err := deliverPrepared(ctx, c, prepared, getOutboxEnabled(cmd))
+	logBreakerStatus(c)
+	if queued != nil {
+		fmt.Fprintf(os.Stderr, "Notification queued in outbox for later delivery (%v)\n", err)
+		return nil
+	}
+	if dupErr, ok := err.(*clierrors.DuplicateError); ok {
+		// Returned as is, so callers with an idempotency key can tell
+		return dupErr
+	}
+	if err != nil {
+		return categorizeError(err)
+	}
+
+	logging.Debug("Notification sent", "title", opts.Title, "type", opts.Type)
+	return nil
+}
+
+// formatDuration rounds a duration for display
+func formatDuration(d time.Duration) string {
+	if d >= time.Minute {
+		return d.Round(time.Second).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. 45.2 MiB
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut with an ellipsis
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
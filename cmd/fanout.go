@@ -0,0 +1,314 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	"github.com/Pincho-App/pincho-cli/pkg/dedup"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+	"github.com/spf13/cobra"
+)
+
+// Values of --fail-on
+const (
+	fanoutFailAny = "any" // Fail if any target failed
+	fanoutFailAll = "all" // Fail only if every target failed
+)
+
+// tokenTargetPrefix marks a --to target given as a token instead of a profile name
+const tokenTargetPrefix = "token:"
+
+// Statuses of a fan-out target
+const (
+	fanoutSent       = "sent"
+	fanoutQueued     = "queued"
+	fanoutSuppressed = "suppressed"
+	fanoutDuplicate  = "duplicate"
+	fanoutFailed     = "failed"
+)
+
+// fanoutTarget is one recipient of a fan-out send, with the settings of its profile
+type fanoutTarget struct {
+	name      string // Profile name, or masked token
+	client    *client.Client
+	opts      *client.SendOptions
+	dedup     *dedupCheck
+	useOutbox bool
+}
+
+// fanoutResult is the outcome of a fan-out send for one target
+type fanoutResult struct {
+	Target   string             `json:"target"`
+	Status   string             `json:"status"`
+	Result   *client.SendResult `json:"result,omitempty"`
+	OutboxID string             `json:"outboxId,omitempty"`
+	SentAt   *time.Time         `json:"sentAt,omitempty"`
+	Error    string             `json:"error,omitempty"`
+
+	err error // Error of a failed target, for the exit code
+}
+
+// runSendFanout sends the same notification to every --to target in parallel
+func runSendFanout(cmd *cobra.Command, args []string) error {
+	if sendFailOn != fanoutFailAny && sendFailOn != fanoutFailAll {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--fail-on must be %q or %q, got %q", fanoutFailAny, fanoutFailAll, sendFailOn))
+	}
+	if cmd.Flags().Changed("token") {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--token cannot be combined with --to, use --to %s<token> instead", tokenTargetPrefix))
+	}
+
+	tmpl, title, message, err := parseSendContent(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveFanoutTargets(cmd, title, message, tmpl)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Sending notification to targets", "targets", len(targets))
+	results := make([]fanoutResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t *fanoutTarget) {
+			defer wg.Done()
+			results[i] = sendToTarget(t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	if sendJSON {
+		jsonBytes, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+	} else {
+		displayFanoutResults(results)
+	}
+
+	return fanoutError(results, sendFailOn)
+}
+
+// resolveFanoutTargets resolves the token, client and notification options of every --to target
+//
+// A profile target uses the token keys the profile itself sets, and the
+// settings of that profile (flags and env vars other than --token and
+// PINCHO_TOKEN still apply); a token target uses the settings of
+// the active profile. Targets are resolved one at a time because the active
+// profile is global; they are sent in parallel afterwards.
+func resolveFanoutTargets(cmd *cobra.Command, title, message string, tmpl *templates.Rendered) ([]*fanoutTarget, error) {
+	active := config.ActiveProfile()
+	defer config.SetProfile(active)
+
+	seen := make(map[string]string) // Token fingerprint -> target name
+	targets := make([]*fanoutTarget, 0, len(sendTo))
+	for _, to := range sendTo {
+		var name, token string
+		if raw, ok := strings.CutPrefix(to, tokenTargetPrefix); ok {
+			config.SetProfile(active)
+			name, token = tokenTargetPrefix+maskToken(raw), raw
+		} else {
+			if !config.ProfileExists(to) {
+				return nil, unknownProfileError(to)
+			}
+			config.SetProfile(to)
+			name = config.ActiveProfile()
+
+			// The profile's own token, never PINCHO_TOKEN or the top-level one,
+			// which every profile target would share
+			if !config.ProfileSetsToken(name) {
+				return nil, clierrors.NewUsageError(
+					"API token is required",
+					fmt.Errorf("profile %s sets no token, token_file or token_command", name),
+				)
+			}
+			var err error
+			token, err = readConfigToken(func(k string) string { return config.ProfileKey(name, k) })
+			if err != nil {
+				return nil, tokenSourceError(fmt.Errorf("target %s: %w", name, err))
+			}
+		}
+
+		if token == "" {
+			return nil, clierrors.NewUsageError(
+				"API token is required",
+				fmt.Errorf("no token configured for target %s", name),
+			)
+		}
+
+		// The same recipient twice would get the notification twice
+		fingerprint := client.TokenFingerprint(token)
+		if other, ok := seen[fingerprint]; ok {
+			return nil, clierrors.NewUsageError("Invalid arguments", fmt.Errorf("targets %s and %s use the same token", other, name))
+		}
+		seen[fingerprint] = name
+
+		opts, err := buildSendOptions(cmd, title, message, tmpl)
+		if err != nil {
+			return nil, err
+		}
+		dupCheck, err := newDedupCheck(cmd, token, client.EndpointSend, sendDedupKey, dedup.SendParts(opts)...)
+		if err != nil {
+			return nil, clierrors.NewUsageError("Invalid arguments", err)
+		}
+
+		logging.Debug("Target resolved", "target", name, "token_prefix", token[:min(8, len(token))])
+		targets = append(targets, &fanoutTarget{
+			name:      name,
+			client:    newClient(cmd, token),
+			opts:      opts,
+			dedup:     dupCheck,
+			useOutbox: getOutboxEnabled(cmd),
+		})
+	}
+	return targets, nil
+}
+
+// sendToTarget sends the notification to one target
+// Each target prepares its own request, so an encrypted message gets its own IV
+func sendToTarget(t *fanoutTarget) fanoutResult {
+	r := fanoutResult{Target: t.name}
+
+	if sentAt, found := t.dedup.lookup(); found {
+		logging.Debug("Identical notification sent recently, suppressing", "target", t.name, "sent_at", sentAt)
+		r.Status, r.SentAt = fanoutSuppressed, &sentAt
+		return r
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.client.Timeout)
+	defer cancel()
+
+	prepared, err := t.client.PrepareSend(t.opts)
+	if err != nil {
+		return r.failed(err)
+	}
+
+	sent, queued, err := deliverPrepared(ctx, t.client, prepared, t.useOutbox)
+	logBreakerStatus(t.client)
+	if queued != nil {
+		r.Status, r.OutboxID, r.Error = fanoutQueued, queued.ID, err.Error()
+		return r
+	}
+	if err != nil {
+		if dupErr, ok := err.(*clierrors.DuplicateError); ok {
+			r.Status, r.SentAt = fanoutDuplicate, &dupErr.SentAt
+			return r
+		}
+		return r.failed(err)
+	}
+
+	result, err := sent.SendResult()
+	if err != nil {
+		return r.failed(err)
+	}
+	t.dedup.record()
+
+	logging.Debug("Notification sent successfully", "target", t.name)
+	r.Status, r.Result = fanoutSent, result
+	return r
+}
+
+// failed marks the result as failed with err
+func (r fanoutResult) failed(err error) fanoutResult {
+	logging.Debug("Sending to target failed", "target", r.Target, "error", err)
+	r.Status, r.Error, r.err = fanoutFailed, err.Error(), err
+	return r
+}
+
+// detail summarizes the outcome of a target in one line
+func (r fanoutResult) detail() string {
+	switch r.Status {
+	case fanoutSent:
+		if r.Result.Response.TeamID != "" {
+			return fmt.Sprintf("Team %s, %d members notified", r.Result.Response.TeamID, r.Result.Response.MemberCount)
+		}
+		if notif := r.Result.Response.ReceivedNotification; notif != nil {
+			return "Notification ID " + notif.NotificationID
+		}
+		return ""
+	case fanoutQueued:
+		return fmt.Sprintf("Outbox ID %s (%s)", r.OutboxID, firstLine(r.Error))
+	case fanoutSuppressed:
+		return "Identical notification sent at " + r.SentAt.Local().Format(time.RFC3339)
+	case fanoutDuplicate:
+		return "Already sent at " + r.SentAt.Local().Format(time.RFC3339)
+	default:
+		return firstLine(r.Error)
+	}
+}
+
+// displayFanoutResults prints one row per target and a summary line
+// The summary counts every status separately, so queued and suppressed
+// targets are not reported as delivered.
+func displayFanoutResults(results []fanoutResult) {
+	counts := make(map[string]int)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tSTATUS\tDETAIL")
+	for _, r := range results {
+		mark := "✓"
+		if r.Status == fanoutFailed {
+			mark = "✗"
+		}
+		counts[r.Status]++
+		fmt.Fprintf(w, "%s\t%s %s\t%s\n", r.Target, mark, r.Status, r.detail())
+	}
+	w.Flush()
+
+	var parts []string
+	for _, status := range []string{fanoutSent, fanoutDuplicate, fanoutQueued, fanoutSuppressed, fanoutFailed} {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%d targets: %s\n", len(results), strings.Join(parts, ", "))
+}
+
+// fanoutError returns the error to exit with, or nil if the fan-out succeeded under failOn
+// The exit code is that of the first failed target.
+func fanoutError(results []fanoutResult, failOn string) error {
+	var failed []fanoutResult
+	for _, r := range results {
+		if r.Status == fanoutFailed {
+			failed = append(failed, r)
+		}
+	}
+	if len(failed) == 0 || (failOn == fanoutFailAll && len(failed) < len(results)) {
+		return nil
+	}
+
+	exitCode := clierrors.ExitSystemError
+	if cliErr, ok := categorizeError(failed[0].err).(*clierrors.CLIError); ok {
+		exitCode = cliErr.ExitCode
+	}
+
+	names := make([]string, len(failed))
+	for i, r := range failed {
+		names[i] = r.Target
+	}
+	return &clierrors.CLIError{
+		Message:  fmt.Sprintf("%d of %d targets failed (%s)", len(failed), len(results), strings.Join(names, ", ")),
+		ExitCode: exitCode,
+		Cause:    failed[0].err,
+	}
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -1,33 +1,117 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/secrets"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 // getTokenOptional retrieves the token from flags, env vars, or config (in that order)
-// Returns empty string if not found (caller should validate)
-func getTokenOptional(cmd *cobra.Command) string {
+// Returns empty string if not found (caller should validate); an error means
+// a configured token file or command could not be read
+func getTokenOptional(cmd *cobra.Command) (string, error) {
 	// Try flag first
 	token, _ := cmd.Flags().GetString("token")
 	if token != "" {
-		return token
+		logging.Debug("Token source", "source", "--token flag")
+		return token, nil
 	}
 
-	// Try environment variable
+	// Try environment variables
 	token = os.Getenv("PINCHO_TOKEN")
 	if token != "" {
-		return token
+		logging.Debug("Token source", "source", "PINCHO_TOKEN")
+		return token, nil
+	}
+	if path := os.Getenv("PINCHO_TOKEN_FILE"); path != "" {
+		logging.Debug("Token source", "source", "PINCHO_TOKEN_FILE", "path", path)
+		return secrets.ReadFile(path)
 	}
 
 	// Try config file
-	token = viper.GetString("token")
-	return token
+	return getConfigToken()
+}
+
+// getConfigToken retrieves the token configured in the active profile or config file
+//
+// The token is read from token, token_file or token_command, in that order.
+// If the active profile sets any of them, only the profile's keys are used,
+// like config.Load does (see config.TokenKey).
+func getConfigToken() (string, error) {
+	return readConfigToken(config.TokenKey)
+}
+
+// readConfigToken retrieves the token from the config keys key maps
+// token, token_file and token_command to, in that order
+func readConfigToken(key func(string) string) (string, error) {
+	if token := viper.GetString(key("token")); token != "" {
+		logging.Debug("Token source", "source", key("token"))
+		return token, nil
+	}
+	if path := viper.GetString(key("token_file")); path != "" {
+		logging.Debug("Token source", "source", key("token_file"), "path", path)
+		return secrets.ReadFile(path)
+	}
+	if command := viper.GetString(key("token_command")); command != "" {
+		logging.Debug("Token source", "source", key("token_command"), "command", command)
+		return secrets.RunCommand(context.Background(), command)
+	}
+	return "", nil
+}
+
+// tokenSourceError reports a token file or command that could not be read
+func tokenSourceError(err error) error {
+	return clierrors.NewUsageError("Failed to read API token", err)
+}
+
+// requireToken retrieves the token like getTokenOptional, failing if none is configured
+func requireToken(cmd *cobra.Command) (string, error) {
+	token, err := getTokenOptional(cmd)
+	if err != nil {
+		return "", tokenSourceError(err)
+	}
+	if token == "" {
+		return "", clierrors.NewUsageError(
+			"API token is required",
+			fmt.Errorf("no token provided via --token flag, PINCHO_TOKEN or PINCHO_TOKEN_FILE environment variable, or token, token_file or token_command in the config file"),
+		)
+	}
+	return token, nil
+}
+
+// readStdin reads stdin to the end, joining lines with "\n" (no trailing newline)
+func readStdin() (string, error) {
+	scanner := bufio.NewScanner(os.Stdin)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// maskToken shortens a token for display, keeping only its first and last 4 characters
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return token
+	}
+	return token[:4] + "..." + token[len(token)-4:]
 }
 
 // getAPIURL retrieves the API URL from env vars or config (in that order)
@@ -40,10 +124,27 @@ func getAPIURL(cmd *cobra.Command) string {
 	}
 
 	// Try config file
-	apiURL = viper.GetString("api_url")
+	apiURL = viper.GetString(config.Key("api_url"))
 	return apiURL
 }
 
+// getBaseURL retrieves the base URL that endpoint paths are resolved against
+// Priority: env var > config file; returns empty string if not found (legacy api_url applies)
+func getBaseURL() string {
+	// Try environment variable first
+	if baseURL := os.Getenv("PINCHO_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+
+	// Try config file
+	return viper.GetString(config.Key("base_url"))
+}
+
+// getEndpoints retrieves per-endpoint path or URL overrides from the config file
+func getEndpoints() map[string]string {
+	return viper.GetStringMapString(config.Key("endpoints"))
+}
+
 // min returns the minimum of two integers
 func min(a, b int) int {
 	if a < b {
@@ -56,8 +157,8 @@ func min(a, b int) int {
 // Priority: flag > env var > config file > default
 // Returns timeout in seconds as time.Duration
 func getTimeout(cmd *cobra.Command) time.Duration {
-	// Try flag first
-	if timeout, err := cmd.Flags().GetInt("timeout"); err == nil && timeout > 0 {
+	// Try flag first, only if given: its default would shadow env and config
+	if timeout, err := cmd.Flags().GetInt("timeout"); err == nil && timeout > 0 && cmd.Flags().Changed("timeout") {
 		return time.Duration(timeout) * time.Second
 	}
 
@@ -69,7 +170,7 @@ func getTimeout(cmd *cobra.Command) time.Duration {
 	}
 
 	// Try config file
-	if timeout := viper.GetInt("timeout"); timeout > 0 {
+	if timeout := viper.GetInt(config.Key("timeout")); timeout > 0 {
 		return time.Duration(timeout) * time.Second
 	}
 
@@ -80,8 +181,8 @@ func getTimeout(cmd *cobra.Command) time.Duration {
 // getMaxRetries retrieves the max retry count from flags, env vars, config file, or returns default
 // Priority: flag > env var > config file > default
 func getMaxRetries(cmd *cobra.Command) int {
-	// Try flag first
-	if retries, err := cmd.Flags().GetInt("max-retries"); err == nil && retries >= 0 {
+	// Try flag first, only if given: its default would shadow env and config
+	if retries, err := cmd.Flags().GetInt("max-retries"); err == nil && retries >= 0 && cmd.Flags().Changed("max-retries") {
 		return retries
 	}
 
@@ -93,8 +194,8 @@ func getMaxRetries(cmd *cobra.Command) int {
 	}
 
 	// Try config file (use viper.IsSet to distinguish 0 from unset)
-	if viper.IsSet("max_retries") {
-		return viper.GetInt("max_retries")
+	if viper.IsSet(config.Key("max_retries")) {
+		return viper.GetInt(config.Key("max_retries"))
 	}
 
 	// Return default
@@ -105,14 +206,14 @@ func getMaxRetries(cmd *cobra.Command) int {
 // Only checks config file (not flag or env var, as flags are command-specific)
 // Returns empty string if not configured
 func getDefaultType() string {
-	return viper.GetString("default_type")
+	return viper.GetString(config.Key("default_type"))
 }
 
 // getDefaultTags retrieves the default tags from config file
 // Only checks config file (not flag or env var, as flags are command-specific)
 // Returns empty slice if not configured
 func getDefaultTags() []string {
-	return viper.GetStringSlice("default_tags")
+	return viper.GetStringSlice(config.Key("default_tags"))
 }
 
 // mergeTypeWithDefault returns the provided type if non-empty, otherwise returns configured default
@@ -158,3 +259,183 @@ func mergeTagsWithDefaults(providedTags []string) []string {
 
 	return merged
 }
+
+// displayRateLimit prints the rate limit quota in human-readable format
+func displayRateLimit(rateLimit *client.RateLimitInfo) {
+	if rateLimit == nil {
+		return
+	}
+
+	fmt.Println()
+	if rateLimit.Limit > 0 {
+		fmt.Printf("Rate Limit: %d/%d remaining", rateLimit.Remaining, rateLimit.Limit)
+	} else {
+		// The response carried no RateLimit-Limit header
+		fmt.Printf("Rate Limit: %d remaining", rateLimit.Remaining)
+	}
+	if !rateLimit.Reset.IsZero() {
+		fmt.Printf(" (resets at %s)", rateLimit.Reset.Local().Format(time.RFC3339))
+	}
+	fmt.Println()
+}
+
+// getIdempotencyWindow retrieves how long a sent idempotency key is refused
+// Priority: env var > config file > default
+func getIdempotencyWindow() time.Duration {
+	// Try environment variable
+	if windowStr := os.Getenv("PINCHO_IDEMPOTENCY_WINDOW"); windowStr != "" {
+		if window, err := time.ParseDuration(windowStr); err == nil && window > 0 {
+			return window
+		}
+	}
+
+	// Try config file
+	if window := viper.GetDuration(config.Key("idempotency_window")); window > 0 {
+		return window
+	}
+
+	// Return default
+	return client.DefaultIdempotencyWindow
+}
+
+// getBreakerThreshold retrieves the consecutive failures that open the circuit breaker
+// Priority: env var > config file > default (0); a negative value disables the breaker
+func getBreakerThreshold() int {
+	// Try environment variable
+	if thresholdStr := os.Getenv("PINCHO_BREAKER_THRESHOLD"); thresholdStr != "" {
+		if threshold, err := strconv.Atoi(thresholdStr); err == nil {
+			return threshold
+		}
+	}
+
+	// Try config file (zero lets the client use its default)
+	return viper.GetInt(config.Key("breaker_threshold"))
+}
+
+// getBreakerCooldown retrieves how long an open circuit breaker fails fast
+// Priority: env var > config file > default (0)
+func getBreakerCooldown() time.Duration {
+	// Try environment variable
+	if cooldownStr := os.Getenv("PINCHO_BREAKER_COOLDOWN"); cooldownStr != "" {
+		if cooldown, err := time.ParseDuration(cooldownStr); err == nil && cooldown > 0 {
+			return cooldown
+		}
+	}
+
+	// Try config file (zero lets the client use its default)
+	return viper.GetDuration(config.Key("breaker_cooldown"))
+}
+
+// getOutboxEnabled reports whether failed sends are queued in the outbox
+// Priority: flag > env var > config file > default (disabled)
+func getOutboxEnabled(cmd *cobra.Command) bool {
+	// Try flag first (only if explicitly set)
+	if cmd.Flags().Changed("outbox") {
+		if enabled, err := cmd.Flags().GetBool("outbox"); err == nil {
+			return enabled
+		}
+	}
+
+	// Try environment variable
+	if enabledStr := os.Getenv("PINCHO_OUTBOX"); enabledStr != "" {
+		if enabled, err := strconv.ParseBool(enabledStr); err == nil {
+			return enabled
+		}
+	}
+
+	// Try config file
+	return viper.GetBool(config.Key("outbox"))
+}
+
+// renderTemplate renders the named template from the config file with vars (name=value pairs)
+func renderTemplate(name string, vars []string) (*templates.Rendered, error) {
+	var defined map[string]templates.Template
+	if err := viper.UnmarshalKey(config.Key("templates"), &defined); err != nil {
+		return nil, fmt.Errorf("invalid templates section in config: %w", err)
+	}
+
+	// Viper lowercases keys, so template names are case-insensitive
+	tmpl, ok := defined[strings.ToLower(name)]
+	if !ok {
+		if len(defined) == 0 {
+			return nil, fmt.Errorf("template %q not found (no templates defined in the config file)", name)
+		}
+		return nil, fmt.Errorf("template %q not found (available: %s)", name, strings.Join(templates.Names(defined), ", "))
+	}
+
+	parsed, err := templates.ParseVars(vars)
+	if err != nil {
+		return nil, err
+	}
+	return templates.Render(tmpl, parsed)
+}
+
+// resolveConfig collects the client settings from flags, env vars and config file
+func resolveConfig(cmd *cobra.Command, token string) *config.Config {
+	return &config.Config{
+		Token:             token,
+		APIURL:            getAPIURL(cmd),
+		BaseURL:           getBaseURL(),
+		Endpoints:         getEndpoints(),
+		Timeout:           int(getTimeout(cmd) / time.Second),
+		MaxRetries:        getMaxRetries(cmd),
+		MaxRetriesSet:     true,
+		IdempotencyWindow: getIdempotencyWindow(),
+		BreakerThreshold:  getBreakerThreshold(),
+		BreakerCooldown:   getBreakerCooldown(),
+	}
+}
+
+// newClient builds an API client from the resolved flags, env vars and config file
+// Rate limit quotas, idempotency keys and circuit breaker state are shared with
+// other invocations through the config directory
+func newClient(cmd *cobra.Command, token string) *client.Client {
+	c := client.NewFromConfig(resolveConfig(cmd, token))
+
+	sendURL, _ := c.EndpointURL(client.EndpointSend)
+	notifaiURL, _ := c.EndpointURL(client.EndpointNotifAI)
+	logging.Debug("API client configured", "send_url", sendURL, "notifai_url", notifaiURL)
+	logging.Debug("Client settings", "timeout", c.Timeout, "max_retries", c.MaxRetries)
+	if c.Breaker == nil {
+		logging.Debug("Circuit breaker disabled")
+	}
+	logBreakerStatus(c)
+
+	return c
+}
+
+// logBreakerStatus logs the circuit breaker state in verbose mode (no-op if disabled)
+func logBreakerStatus(c *client.Client) {
+	if c.Breaker == nil {
+		return
+	}
+	status := c.BreakerStatus()
+	if status.OpenUntil.IsZero() {
+		logging.Debug("Circuit breaker", "state", status.State, "consecutive_failures", status.ConsecutiveFailures)
+		return
+	}
+	logging.Debug("Circuit breaker", "state", status.State, "consecutive_failures", status.ConsecutiveFailures, "open_until", status.OpenUntil.Local().Format(time.RFC3339))
+}
+
+// displayDuplicate reports a send refused because its idempotency key was already used
+// A duplicate is not a failure: the notification was delivered by an earlier run
+func displayDuplicate(dupErr *clierrors.DuplicateError, asJSON bool) error {
+	if asJSON {
+		jsonBytes, err := json.MarshalIndent(map[string]any{
+			"status":         "duplicate",
+			"idempotencyKey": dupErr.Key,
+			"sentAt":         dupErr.SentAt,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+
+	fmt.Println("✓ Notification already sent, not sending again")
+	fmt.Println()
+	fmt.Printf("Idempotency key: %s\n", dupErr.Key)
+	fmt.Printf("Sent at: %s\n", dupErr.SentAt.Local().Format(time.RFC3339))
+	return nil
+}
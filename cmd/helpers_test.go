@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newTestCommand returns a command with the global timeout and retry flags
+func newTestCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().Int("timeout", 30, "")
+	cmd.Flags().Int("max-retries", 3, "")
+	return cmd
+}
+
+// setupTestConfig writes a config file to a temporary home and loads it
+func setupTestConfig(t *testing.T, content string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"PINCHO_TIMEOUT", "PINCHO_MAX_RETRIES", "PINCHO_PROFILE"} {
+		t.Setenv(key, "")
+	}
+
+	dir := filepath.Join(home, config.ConfigDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, config.ConfigFileName+".yaml"), []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		config.SetProfile("")
+	})
+	if err := config.InitConfig(); err != nil {
+		t.Fatalf("InitConfig() failed: %v", err)
+	}
+}
+
+func TestNewClient_ProfileTimeoutAndRetries(t *testing.T) {
+	setupTestConfig(t, "timeout: 20\nprofiles:\n  slow:\n    timeout: 90\n    max_retries: 0\n")
+
+	c := newClient(newTestCommand(), "test-token")
+	if c.Timeout != 20*time.Second || c.MaxRetries != 3 {
+		t.Errorf("without a profile: timeout %s, retries %d; want 20s and the default 3", c.Timeout, c.MaxRetries)
+	}
+
+	config.SetProfile("slow")
+	c = newClient(newTestCommand(), "test-token")
+	if c.Timeout != 90*time.Second || c.MaxRetries != 0 {
+		t.Errorf("with profile slow: timeout %s, retries %d; want 90s and 0", c.Timeout, c.MaxRetries)
+	}
+
+	t.Setenv("PINCHO_TIMEOUT", "45")
+	if c = newClient(newTestCommand(), "test-token"); c.Timeout != 45*time.Second {
+		t.Errorf("with PINCHO_TIMEOUT: timeout %s, want 45s", c.Timeout)
+	}
+
+	cmd := newTestCommand()
+	if err := cmd.Flags().Set("timeout", "5"); err != nil {
+		t.Fatalf("failed to set flag: %v", err)
+	}
+	if c = newClient(cmd, "test-token"); c.Timeout != 5*time.Second {
+		t.Errorf("with --timeout: timeout %s, want 5s", c.Timeout)
+	}
+}
@@ -59,9 +59,13 @@ Examples:
 }
 
 var (
-	notifaiType  string
-	notifaiStdin bool
-	notifaiJSON  bool
+	notifaiType           string
+	notifaiStdin          bool
+	notifaiJSON           bool
+	notifaiIdempotencyKey string
+	notifaiOutbox         bool
+	notifaiDedupWindow    time.Duration
+	notifaiDedupKey       string
 )
 
 func init() {
@@ -71,17 +75,17 @@ func init() {
 	notifaiCmd.Flags().StringVar(&notifaiType, "type", "", "Notification type (optional)")
 	notifaiCmd.Flags().BoolVar(&notifaiStdin, "stdin", false, "Read text from stdin")
 	notifaiCmd.Flags().BoolVar(&notifaiJSON, "json", false, "Output response as JSON")
+	notifaiCmd.Flags().StringVar(&notifaiIdempotencyKey, "idempotency-key", "", "Key identifying this notification; a re-run with the same key is not sent again")
+	notifaiCmd.Flags().BoolVar(&notifaiOutbox, "outbox", false, "Queue the request in the outbox if it cannot be delivered (env: PINCHO_OUTBOX)")
+	notifaiCmd.Flags().DurationVar(&notifaiDedupWindow, "dedup-window", 0, "Suppress the request if an identical one was sent within this window, e.g. 10m (env: PINCHO_DEDUP_WINDOW)")
+	notifaiCmd.Flags().StringVar(&notifaiDedupKey, "dedup-key", "", "Judge duplicates by this key instead of text and type")
 }
 
 func runNotifAI(cmd *cobra.Command, args []string) error {
 	// Get token from flags, env vars, or config
-	token := getTokenOptional(cmd)
-
-	if token == "" {
-		return clierrors.NewUsageError(
-			"API token is required",
-			fmt.Errorf("no token provided via --token flag, PINCHO_TOKEN environment variable, or config file"),
-		)
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
 	}
 
 	logging.Debug("Token configured", "token_prefix", token[:min(8, len(token))])
@@ -102,27 +106,8 @@ func runNotifAI(cmd *cobra.Command, args []string) error {
 
 	logging.Debug("NotifAI input parsed", "text_length", len(text))
 
-	// Create client and send notifai request
-	c := client.New()
-
-	// Set token for authentication
-	c.SetToken(token)
-
-	// Set API URL if configured (via env, config file, or default)
-	if apiURL := getAPIURL(cmd); apiURL != "" {
-		c.APIURL = apiURL
-	}
-	logging.Debug("API client configured", "api_url", c.APIURL)
-
-	// Set timeout if configured (via flag, env var, or default)
-	timeout := getTimeout(cmd)
-	c.SetTimeout(timeout)
-
-	// Set retry configuration
-	maxRetries := getMaxRetries(cmd)
-	c.SetRetryConfig(maxRetries, client.DefaultInitialBackoff)
-
-	logging.Debug("Client settings", "timeout", timeout, "max_retries", maxRetries)
+	// Create client from resolved settings and send notifai request
+	c := newClient(cmd, token)
 
 	// Merge type with default from config
 	finalType := mergeTypeWithDefault(notifaiType)
@@ -130,20 +115,49 @@ func runNotifAI(cmd *cobra.Command, args []string) error {
 	logging.Debug("NotifAI options", "type", finalType)
 
 	opts := &client.NotifAIOptions{
-		Text: text,
-		Type: finalType,
+		Text:           text,
+		Type:           finalType,
+		IdempotencyKey: notifaiIdempotencyKey,
+	}
+
+	// Skip requests identical to one sent recently from this machine
+	dupCheck, err := newDedupCheck(cmd, token, client.EndpointNotifAI, notifaiDedupKey, opts.Text, opts.Type)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+	if sentAt, found := dupCheck.lookup(); found {
+		logging.Debug("Identical request sent recently, suppressing", "sent_at", sentAt)
+		return displaySuppressed(dupCheck, sentAt, notifaiJSON)
 	}
 
 	logging.Debug("Sending AI request to API")
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
 	defer cancel()
 
-	result, err := c.NotifAI(ctx, opts)
+	prepared, err := c.PrepareNotifAI(opts)
 	if err != nil {
 		return categorizeNotifAIError(err)
 	}
 
+	sent, queued, err := deliverPrepared(ctx, c, prepared, getOutboxEnabled(cmd))
+	logBreakerStatus(c)
+	if queued != nil {
+		return displayQueued(queued, err, notifaiJSON)
+	}
+	if err != nil {
+		if dupErr, ok := err.(*clierrors.DuplicateError); ok {
+			return displayDuplicate(dupErr, notifaiJSON)
+		}
+		return categorizeNotifAIError(err)
+	}
+
+	result, err := sent.NotifAIResult()
+	if err != nil {
+		return categorizeNotifAIError(err)
+	}
+	dupCheck.record()
+
 	logging.Debug("AI-generated notification sent successfully")
 
 	// Output response
@@ -203,6 +217,8 @@ func categorizeNotifAIError(err error) error {
 		return clierrors.NewAPIError("Rate limit exceeded", fmt.Errorf("%v\n\nThe notifai endpoint allows 50 requests per hour. Please wait before trying again.", e))
 	case *clierrors.ServerError:
 		return clierrors.NewAPIError("Server error", e)
+	case *clierrors.CircuitOpenError:
+		return clierrors.NewAPIError("API unavailable", fmt.Errorf("%v\n\nRequests fail fast until the API recovers. Set breaker_threshold to -1 to disable this.", e))
 	case *clierrors.NetworkError:
 		return clierrors.NewSystemError("Network error", fmt.Errorf("%v\n\nPlease check your internet connection and try again.", e))
 	default:
@@ -248,12 +264,5 @@ func displayNotifAIResult(result *client.NotifAIResult) {
 	}
 
 	// Display rate limit info if available
-	if result.RateLimit != nil && result.RateLimit.Limit != "" {
-		fmt.Println()
-		fmt.Printf("Rate Limit: %s/%s remaining", result.RateLimit.Remaining, result.RateLimit.Limit)
-		if result.RateLimit.Reset != "" {
-			fmt.Printf(" (resets at %s)", result.RateLimit.Reset)
-		}
-		fmt.Println()
-	}
+	displayRateLimit(result.RateLimit)
 }
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/outbox"
+	"github.com/spf13/cobra"
+)
+
+// outboxCmd represents the outbox command
+var outboxCmd = &cobra.Command{
+	Use:   "outbox",
+	Short: "Manage notifications queued for later delivery",
+	Long: `Manage the outbox of notifications queued for later delivery.
+
+When the outbox is enabled (--outbox, PINCHO_OUTBOX=true or
+"pincho config set outbox true"), a send or notifai request that fails with a
+network error, server error, rate limit or open circuit breaker is queued in
+~/.pincho/outbox instead of being lost. Queued requests are stored exactly as
+they will be sent: messages are already encrypted, and neither the encryption
+password nor the API token is written to disk.
+
+Queued notifications are delivered oldest first by "pincho outbox flush", and
+before every new send while the outbox is enabled.
+`,
+}
+
+// outboxListCmd represents the 'outbox list' command
+var outboxListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List queued notifications",
+	Long: `List queued notifications, oldest first.
+
+Example:
+  pincho outbox list
+  pincho outbox list --json
+`,
+	Args: cobra.NoArgs,
+	RunE: runOutboxList,
+}
+
+// outboxFlushCmd represents the 'outbox flush' command
+var outboxFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Deliver queued notifications",
+	Long: `Deliver queued notifications for the current token, oldest first.
+
+Delivered notifications are removed from the outbox. The flush stops at the
+first transient failure (network error, server error, rate limit or open
+circuit breaker) so that notifications are never delivered out of order; run
+it again later, e.g. from cron. Notifications the API rejects are marked
+failed and kept for inspection until purged.
+
+Notifications queued with a different token are left untouched.
+
+Example:
+  pincho outbox flush
+
+  # Flush every 5 minutes from cron
+  */5 * * * * pincho outbox flush
+`,
+	Args: cobra.NoArgs,
+	RunE: runOutboxFlush,
+}
+
+// outboxPurgeCmd represents the 'outbox purge' command
+var outboxPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete queued notifications",
+	Long: `Delete queued notifications without sending them.
+
+Example:
+  # Delete everything
+  pincho outbox purge
+
+  # Delete only notifications the API rejected
+  pincho outbox purge --failed
+`,
+	Args: cobra.NoArgs,
+	RunE: runOutboxPurge,
+}
+
+var (
+	outboxJSON        bool
+	outboxPurgeFailed bool
+)
+
+func init() {
+	rootCmd.AddCommand(outboxCmd)
+	outboxCmd.AddCommand(outboxListCmd)
+	outboxCmd.AddCommand(outboxFlushCmd)
+	outboxCmd.AddCommand(outboxPurgeCmd)
+
+	outboxListCmd.Flags().BoolVar(&outboxJSON, "json", false, "Output as JSON")
+	outboxFlushCmd.Flags().BoolVar(&outboxJSON, "json", false, "Output as JSON")
+	outboxPurgeCmd.Flags().BoolVar(&outboxPurgeFailed, "failed", false, "Only delete notifications the API rejected")
+}
+
+// openOutbox returns the outbox in the config directory
+func openOutbox() (*outbox.Outbox, error) {
+	box, err := outbox.Default()
+	if err != nil {
+		return nil, clierrors.NewSystemError("Failed to open outbox", err)
+	}
+	return box, nil
+}
+
+func runOutboxList(cmd *cobra.Command, args []string) error {
+	box, err := openOutbox()
+	if err != nil {
+		return err
+	}
+
+	entries, err := box.List()
+	if err != nil {
+		return clierrors.NewSystemError("Failed to read outbox", err)
+	}
+
+	if outboxJSON {
+		jsonBytes, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Outbox is empty")
+		return nil
+	}
+
+	// Without a readable token, every entry is shown as queued for a different token
+	token, _ := getTokenOptional(cmd)
+	tokenID := client.TokenFingerprint(token)
+	for _, entry := range entries {
+		fmt.Printf("%s  /%s  %s\n", entry.ID, entry.Request.Endpoint, outboxSummary(entry))
+		fmt.Printf("  Queued: %s\n", entry.CreatedAt.Local().Format(time.RFC3339))
+		if entry.Attempts > 0 {
+			fmt.Printf("  Attempts: %d (last %s)\n", entry.Attempts, entry.LastAttempt.Local().Format(time.RFC3339))
+		}
+		if entry.LastError != "" {
+			fmt.Printf("  Last error: %s\n", entry.LastError)
+		}
+		if entry.Failed {
+			fmt.Println("  Status: failed (rejected by the API, will not be retried)")
+		} else if entry.TokenID != tokenID {
+			fmt.Println("  Status: queued for a different token")
+		}
+	}
+	fmt.Println()
+	fmt.Printf("%d queued in %s\n", len(entries), box.Dir())
+	return nil
+}
+
+func runOutboxFlush(cmd *cobra.Command, args []string) error {
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
+	}
+
+	box, err := openOutbox()
+	if err != nil {
+		return err
+	}
+
+	c := newClient(cmd, token)
+
+	// The timeout applies to each request, not to the whole flush
+	result, err := box.Flush(context.Background(), c)
+	logBreakerStatus(c)
+	if err != nil {
+		return clierrors.NewSystemError("Failed to update outbox", err)
+	}
+
+	logging.Debug("Outbox flushed", "sent", len(result.Sent), "rejected", len(result.Rejected), "skipped", result.Skipped, "pending", result.Pending)
+
+	if outboxJSON {
+		output := map[string]any{
+			"sent":     len(result.Sent),
+			"rejected": len(result.Rejected),
+			"skipped":  result.Skipped,
+			"pending":  result.Pending,
+		}
+		if result.StopErr != nil {
+			output["error"] = result.StopErr.Error()
+		}
+		jsonBytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+	} else {
+		for _, entry := range result.Sent {
+			fmt.Printf("✓ Sent %s  %s\n", entry.ID, outboxSummary(entry))
+		}
+		for _, entry := range result.Rejected {
+			fmt.Printf("✗ Rejected %s  %s: %s\n", entry.ID, outboxSummary(entry), entry.LastError)
+		}
+		if len(result.Sent) == 0 && len(result.Rejected) == 0 && result.StopErr == nil {
+			fmt.Println("Nothing to flush")
+		}
+		if result.Skipped > 0 {
+			fmt.Printf("Skipped %d (failed earlier or queued for a different token)\n", result.Skipped)
+		}
+	}
+
+	if result.StopErr != nil {
+		// Entries stay queued; report why delivery stopped with the usual exit code
+		return categorizeError(result.StopErr)
+	}
+	return nil
+}
+
+func runOutboxPurge(cmd *cobra.Command, args []string) error {
+	box, err := openOutbox()
+	if err != nil {
+		return err
+	}
+
+	purged, err := box.Purge(outboxPurgeFailed)
+	if err != nil {
+		return clierrors.NewSystemError("Failed to purge outbox", err)
+	}
+
+	fmt.Printf("✓ Deleted %d queued notification(s)\n", purged)
+	return nil
+}
+
+// outboxSummary returns a short description of a queued request
+func outboxSummary(entry *outbox.Entry) string {
+	var body struct {
+		Title string `json:"title"`
+		Text  string `json:"text"`
+	}
+	_ = json.Unmarshal(entry.Request.Body, &body)
+
+	summary := body.Title
+	if summary == "" {
+		summary = body.Text
+	}
+	if len(summary) > 60 {
+		summary = summary[:57] + "..."
+	}
+	return fmt.Sprintf("%q", summary)
+}
+
+// deliverPrepared sends a prepared request, going through the outbox if useOutbox is set
+//
+// With the outbox enabled, queued notifications for the token are flushed
+// first so that delivery order is preserved. If a transient error stops the
+// flush, or the request fails with one, the request is queued and the
+// returned entry is non-nil. The error is then the reason it was queued.
+// Other errors, such as an authentication failure, are returned as is.
+//
+// The flush is not bound by ctx, so a backlog does not use up the timeout
+// of the request itself; each queued request has the client's timeout.
+func deliverPrepared(ctx context.Context, c *client.Client, prepared *client.PreparedRequest, useOutbox bool) (*client.PreparedResult, *outbox.Entry, error) {
+	if !useOutbox {
+		result, err := c.SendPrepared(ctx, prepared)
+		return result, nil, err
+	}
+
+	box, err := outbox.Default()
+	if err != nil {
+		logging.Debug("Outbox unavailable, sending directly", "error", err)
+		result, err := c.SendPrepared(ctx, prepared)
+		return result, nil, err
+	}
+
+	// Earlier notifications go first
+	flushed, err := box.Flush(context.Background(), c)
+	if err != nil {
+		logging.Debug("Failed to flush outbox", "error", err)
+	} else {
+		logging.Debug("Outbox flushed", "sent", len(flushed.Sent), "rejected", len(flushed.Rejected), "pending", flushed.Pending)
+		if flushed.Transient() {
+			// Still failing: queue behind the earlier notifications
+			return queuePrepared(box, c, prepared, flushed.StopErr)
+		}
+		if flushed.StopErr != nil {
+			return nil, nil, flushed.StopErr
+		}
+	}
+
+	result, err := c.SendPrepared(ctx, prepared)
+	if err != nil && outbox.ShouldQueue(err) {
+		return queuePrepared(box, c, prepared, err)
+	}
+	return result, nil, err
+}
+
+// queuePrepared adds a request to the outbox, returning cause if queueing fails
+func queuePrepared(box *outbox.Outbox, c *client.Client, prepared *client.PreparedRequest, cause error) (*client.PreparedResult, *outbox.Entry, error) {
+	entry, err := box.Add(client.TokenFingerprint(c.Token), prepared, cause)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to queue notification in outbox: %v\n", err)
+		return nil, nil, cause
+	}
+	logging.Debug("Notification queued in outbox", "id", entry.ID, "reason", cause)
+	return nil, entry, cause
+}
+
+// displayQueued reports a notification that was queued instead of sent
+// Queueing is not a failure: the notification is delivered by a later flush
+func displayQueued(entry *outbox.Entry, cause error, asJSON bool) error {
+	if asJSON {
+		jsonBytes, err := json.MarshalIndent(map[string]any{
+			"status":         "queued",
+			"outboxId":       entry.ID,
+			"idempotencyKey": entry.Request.IdempotencyKey,
+			"error":          cause.Error(),
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON response: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+
+	fmt.Println("✓ Notification queued in outbox for later delivery")
+	fmt.Println()
+	fmt.Printf("Reason: %v\n", cause)
+	fmt.Printf("Outbox ID: %s\n", entry.ID)
+	fmt.Println("Deliver with: pincho outbox flush")
+	return nil
+}
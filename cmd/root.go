@@ -5,14 +5,23 @@
 //   - send: Send push notifications with title, message, and optional parameters
 //   - notifai: Use AI to generate notifications from free-form text
 //   - config: Manage CLI configuration settings
+//   - cron: Run a scheduled job and notify when its outcome changes
+//   - crypto: Encrypt and decrypt messages offline
+//   - dev-server: Run a local fake API that prints received notifications
+//   - digest: Collect events and send them as one periodic summary
+//   - exec: Run a command and notify when it finishes
+//   - outbox: List, flush or purge notifications queued for later delivery
+//   - serve: Receive webhooks and turn them into notifications
+//   - watch: Follow a log file and notify about lines matching rules
 //   - version: Display version information
 //
 // Commands support configuration via flags, environment variables, or config
-// files (in order of precedence: flags > env vars > config file).
+// files (in order of precedence: flags > env vars > active profile > config file).
 //
 // Global flags:
 //
 //	--token, -t: API token for authentication
+//	--profile: Named profile from the config file to use
 //	--verbose: Enable detailed logging output
 //	--timeout: HTTP request timeout in seconds
 //	--max-retries: Maximum number of retry attempts
@@ -20,14 +29,21 @@
 // Environment variables:
 //
 //	PINCHO_TOKEN: API token
-//	PINCHO_API_URL: Custom API endpoint
+//	PINCHO_TOKEN_FILE: File to read the API token from
+//	PINCHO_PROFILE: Named profile from the config file to use
+//	PINCHO_API_URL: Custom send endpoint (legacy, prefer PINCHO_BASE_URL)
+//	PINCHO_BASE_URL: Base URL that endpoint paths are resolved against
 //	PINCHO_TIMEOUT: Request timeout in seconds
 //	PINCHO_MAX_RETRIES: Maximum retry attempts
+//	PINCHO_DEDUP_WINDOW: Suppress identical notifications within this window
+//	PINCHO_OUTBOX: Queue sends that fail with a transient error
+//	PINCHO_ENCRYPTION_PASSWORD: Password to encrypt messages with
 package cmd
 
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/Pincho-App/pincho-cli/pkg/config"
 	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
@@ -52,12 +68,14 @@ and automation workflows.
 
 Documentation: https://github.com/Pincho-App/pincho-cli
 API Reference: https://pincho.app/help`,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		// Enable verbose logging if flag is set
 		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
 			logging.SetVerbose(true)
 			logging.Debug("Verbose logging enabled")
 		}
+
+		return checkProfile(cmd)
 	},
 }
 
@@ -76,6 +94,7 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringP("token", "t", "", "Pincho API token (env: PINCHO_TOKEN)")
+	rootCmd.PersistentFlags().String("profile", "", "Named profile from the config file to use (env: PINCHO_PROFILE)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "Enable verbose output")
 	rootCmd.PersistentFlags().Int("timeout", 30, "HTTP request timeout in seconds (env: PINCHO_TIMEOUT)")
 	rootCmd.PersistentFlags().Int("max-retries", 3, "Maximum number of retry attempts (env: PINCHO_MAX_RETRIES)")
@@ -86,6 +105,39 @@ func initConfig() {
 	if err := config.InitConfig(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to initialize config: %v\n", err)
 	}
+
+	// Select the profile (flag > env var)
+	profile, _ := rootCmd.PersistentFlags().GetString("profile")
+	if profile == "" {
+		profile = os.Getenv("PINCHO_PROFILE")
+	}
+	config.SetProfile(profile)
+}
+
+// checkProfile verifies that the selected profile exists in the config file
+// 'config set' is exempt, as it creates the profile.
+func checkProfile(cmd *cobra.Command) error {
+	profile := config.ActiveProfile()
+	if profile == "" || cmd == configSetCmd {
+		return nil
+	}
+	if config.ProfileExists(profile) {
+		logging.Debug("Using profile", "profile", profile)
+		return nil
+	}
+	return unknownProfileError(profile)
+}
+
+// unknownProfileError reports a profile missing from the config file, listing the available ones
+func unknownProfileError(profile string) error {
+	available := "none defined"
+	if names := config.ProfileNames(); len(names) > 0 {
+		available = "available: " + strings.Join(names, ", ")
+	}
+	return clierrors.NewUsageError(
+		"Unknown profile",
+		fmt.Errorf("profile %q not found in the config file (%s)", profile, available),
+	)
 }
 
 // GetVersionInfo returns version information as a formatted string
@@ -1,17 +1,18 @@
 package cmd
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	"github.com/Pincho-App/pincho-cli/pkg/dedup"
 	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
 	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
 	"github.com/spf13/cobra"
 )
 
@@ -48,10 +49,35 @@ Examples:
     --encryption-password "secret123" \
     --type secure
 
-  # Read message from stdin with encryption
+  # With the versioned scheme (salted PBKDF2, AES-256-GCM)
+  pincho send "Secure Alert" "Sensitive data here" \
+    --encryption-password "secret123" --encryption-scheme v2
+
+  # Hide the message length: "OK" and a long report encrypt to the same size
+  pincho send "Security Alert" "$REPORT" --type secure --pad-to 1024
+
+  # Encrypt with the password configured for the type (encryption section)
+  pincho send "Secure Alert" "Sensitive data here" --type secure
+
+  # Read message from stdin, password from a file (not visible in ps output)
   echo "Confidential report" | pincho send "Report" --stdin \
-    --encryption-password "secret123"
+    --encryption-password-file ~/.pincho/secure.password
+
+  # Safe to re-run: a CI step retried with the same key is not sent twice
+  pincho send "Deploy" "v1.2.3 deployed" --idempotency-key "deploy-$CI_PIPELINE_ID"
+
+  # Fill in the notification from a template in the config file
+  pincho send --template deploy --var version=1.2.3 --var env=prod
 
+  # Send a repeating alert at most once every 10 minutes
+  pincho send "Disk full" "/var is at 98%" --dedup-window 10m
+
+  # Queue the notification if the API cannot be reached, deliver it later
+  pincho send "Backup" "Nightly backup done" --outbox
+  pincho outbox flush
+
+  # Send the same notification through several profiles at once
+  pincho send "Outage" "API is down" --to oncall --to personal --to team-infra
 
   # Override config with flags
   pincho send "Test" "Message" --token abc123
@@ -66,7 +92,19 @@ var (
 	sendActionURL          string
 	sendStdin              bool
 	sendEncryptionPassword string
+	sendEncryptionPwFile   string
+	sendEncryptionScheme   string
+	sendNoEncrypt          bool
+	sendPadTo              int
 	sendJSON               bool
+	sendIdempotencyKey     string
+	sendOutbox             bool
+	sendDedupWindow        time.Duration
+	sendDedupKey           string
+	sendTemplate           string
+	sendVars               []string
+	sendTo                 []string
+	sendFailOn             string
 )
 
 func init() {
@@ -78,81 +116,86 @@ func init() {
 	sendCmd.Flags().StringVar(&sendImageURL, "image-url", "", "Image URL to display with notification")
 	sendCmd.Flags().StringVar(&sendActionURL, "action-url", "", "Action URL to open when notification is tapped")
 	sendCmd.Flags().BoolVar(&sendStdin, "stdin", false, "Read message from stdin")
-	sendCmd.Flags().StringVar(&sendEncryptionPassword, "encryption-password", "", "Password for AES-128-CBC encryption (must match type configuration in app)")
+	sendCmd.Flags().StringVar(&sendEncryptionPassword, "encryption-password", "", "Password for AES-128-CBC encryption (must match type configuration in app; visible in process listings, prefer --encryption-password-file)")
+	sendCmd.Flags().StringVar(&sendEncryptionPwFile, "encryption-password-file", "", "File to read the encryption password from (env: PINCHO_ENCRYPTION_PASSWORD for the password itself)")
+	sendCmd.Flags().StringVar(&sendEncryptionScheme, "encryption-scheme", string(crypto.SchemeV1), "Encryption scheme: v1 (AES-128-CBC, supported by the app) or v2 (salted PBKDF2, AES-256-GCM)")
+	sendCmd.Flags().IntVar(&sendPadTo, "pad-to", 0, "Pad the encrypted message to a multiple of this many bytes, e.g. 256, to hide its length")
+	sendCmd.Flags().BoolVar(&sendNoEncrypt, "no-encrypt", false, "Send unencrypted even if the type has an encryption password in the config file")
 	sendCmd.Flags().BoolVar(&sendJSON, "json", false, "Output response as JSON")
+	sendCmd.Flags().StringVar(&sendIdempotencyKey, "idempotency-key", "", "Key identifying this notification; a re-run with the same key is not sent again")
+	sendCmd.Flags().BoolVar(&sendOutbox, "outbox", false, "Queue the notification in the outbox if it cannot be delivered (env: PINCHO_OUTBOX)")
+	sendCmd.Flags().DurationVar(&sendDedupWindow, "dedup-window", 0, "Suppress the notification if an identical one was sent within this window, e.g. 10m (env: PINCHO_DEDUP_WINDOW)")
+	sendCmd.Flags().StringVar(&sendTemplate, "template", "", "Name of a template from the config file to fill in the notification")
+	sendCmd.Flags().StringArrayVar(&sendVars, "var", nil, "Template variable as name=value (can be used multiple times)")
+	sendCmd.Flags().StringVar(&sendDedupKey, "dedup-key", "", "Judge duplicates by this key instead of title, message, type and tags")
+	sendCmd.Flags().StringArrayVar(&sendTo, "to", nil, "Send to this profile, or token:<token>, in parallel with other --to targets (can be used multiple times)")
+	sendCmd.Flags().StringVar(&sendFailOn, "fail-on", fanoutFailAny, "With --to, exit with an error if \"any\" or only if \"all\" targets failed")
 }
 
 func runSend(cmd *cobra.Command, args []string) error {
-	// Get token and ID from flags, env vars, or config
-	token := getTokenOptional(cmd)
+	if len(sendTo) > 0 {
+		return runSendFanout(cmd, args)
+	}
 
-	if token == "" {
-		return clierrors.NewUsageError(
-			"API token is required",
-			fmt.Errorf("no token provided via --token flag, PINCHO_TOKEN environment variable, or config file"),
-		)
+	// Get token and ID from flags, env vars, or config
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
 	}
 
 	logging.Debug("Token configured", "token_prefix", token[:min(8, len(token))])
 
-	// Parse title and message
-	title, message, err := parseTitleAndMessage(cmd, args)
+	tmpl, title, message, err := parseSendContent(cmd, args)
 	if err != nil {
-		return clierrors.NewUsageError("Invalid arguments", err)
+		return err
 	}
 
-	logging.Debug("Notification content parsed", "title", title, "message_length", len(message))
-
-	// Create client and send notification
-	c := client.New()
+	// Create client from resolved settings and send notification
+	c := newClient(cmd, token)
 
-	// Set token for authentication
-	c.SetToken(token)
-
-	// Set API URL if configured (via env, config file, or default)
-	if apiURL := getAPIURL(cmd); apiURL != "" {
-		c.APIURL = apiURL
+	opts, err := buildSendOptions(cmd, title, message, tmpl)
+	if err != nil {
+		return err
 	}
-	logging.Debug("API client configured", "api_url", c.APIURL)
-
-	// Set timeout if configured (via flag, env var, or default)
-	timeout := getTimeout(cmd)
-	c.SetTimeout(timeout)
-
-	// Set retry configuration
-	maxRetries := getMaxRetries(cmd)
-	c.SetRetryConfig(maxRetries, client.DefaultInitialBackoff)
-
-	logging.Debug("Client settings", "timeout", timeout, "max_retries", maxRetries)
 
-	// Merge type with default from config
-	finalType := mergeTypeWithDefault(sendType)
-
-	// Merge tags with defaults from config
-	finalTags := mergeTagsWithDefaults(sendTags)
-
-	logging.Debug("Notification options", "type", finalType, "tags", finalTags, "has_encryption", sendEncryptionPassword != "")
-
-	opts := &client.SendOptions{
-		Title:              title,
-		Message:            message,
-		Type:               finalType,
-		Tags:               finalTags,
-		ImageURL:           sendImageURL,
-		ActionURL:          sendActionURL,
-		EncryptionPassword: sendEncryptionPassword,
+	// Skip notifications identical to one sent recently from this machine
+	dupCheck, err := newDedupCheck(cmd, token, client.EndpointSend, sendDedupKey, dedup.SendParts(opts)...)
+	if err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+	if sentAt, found := dupCheck.lookup(); found {
+		logging.Debug("Identical notification sent recently, suppressing", "sent_at", sentAt)
+		return displaySuppressed(dupCheck, sentAt, sendJSON)
 	}
 
 	logging.Debug("Sending notification to API")
 	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
 	defer cancel()
 
-	result, err := c.Send(ctx, opts)
+	prepared, err := c.PrepareSend(opts)
+	if err != nil {
+		return categorizeError(err)
+	}
+
+	sent, queued, err := deliverPrepared(ctx, c, prepared, getOutboxEnabled(cmd))
+	logBreakerStatus(c)
+	if queued != nil {
+		return displayQueued(queued, err, sendJSON)
+	}
 	if err != nil {
+		if dupErr, ok := err.(*clierrors.DuplicateError); ok {
+			return displayDuplicate(dupErr, sendJSON)
+		}
 		return categorizeError(err)
 	}
 
+	result, err := sent.SendResult()
+	if err != nil {
+		return categorizeError(err)
+	}
+	dupCheck.record()
+
 	logging.Debug("Notification sent successfully")
 
 	// Output response
@@ -171,40 +214,120 @@ func runSend(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// parseSendContent renders the --template, if any, and parses title and message
+func parseSendContent(cmd *cobra.Command, args []string) (*templates.Rendered, string, string, error) {
+	// Render the template, if any; flags and arguments override its fields
+	var tmpl *templates.Rendered
+	if sendTemplate != "" {
+		var err error
+		tmpl, err = renderTemplate(sendTemplate, sendVars)
+		if err != nil {
+			return nil, "", "", clierrors.NewUsageError("Invalid template", err)
+		}
+		logging.Debug("Template rendered", "template", sendTemplate, "title", tmpl.Title)
+	} else if len(sendVars) > 0 {
+		return nil, "", "", clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--var requires --template"))
+	}
+
+	// Parse title and message
+	title, message, err := parseTitleAndMessage(cmd, args, tmpl)
+	if err != nil {
+		return nil, "", "", clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	logging.Debug("Notification content parsed", "title", title, "message_length", len(message))
+	return tmpl, title, message, nil
+}
+
+// buildSendOptions combines the send flags, the template and the configured defaults
+// Defaults and encryption passwords are read from the active profile, so call
+// it once per profile when fanning out
+func buildSendOptions(cmd *cobra.Command, title, message string, tmpl *templates.Rendered) (*client.SendOptions, error) {
+	notifType, tags, imageURL, actionURL := sendType, sendTags, sendImageURL, sendActionURL
+	if tmpl != nil {
+		if notifType == "" {
+			notifType = tmpl.Type
+		}
+		tags = append(append([]string{}, tmpl.Tags...), tags...)
+		if imageURL == "" {
+			imageURL = tmpl.ImageURL
+		}
+		if actionURL == "" {
+			actionURL = tmpl.ActionURL
+		}
+	}
+
+	// Merge type with default from config
+	finalType := mergeTypeWithDefault(notifType)
+
+	// Merge tags with defaults from config
+	finalTags := mergeTagsWithDefaults(tags)
+
+	// Encrypt with the password given or configured for the type
+	encryption, err := resolveEncryption(cmd, finalType)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Debug("Notification options", "type", finalType, "tags", finalTags, "has_encryption", encryption.password != "", "pad_to", encryption.padTo)
+
+	return &client.SendOptions{
+		Title:              title,
+		Message:            message,
+		Type:               finalType,
+		Tags:               finalTags,
+		ImageURL:           imageURL,
+		ActionURL:          actionURL,
+		EncryptionPassword: encryption.password,
+		EncryptionScheme:   encryption.scheme,
+		PadTo:              encryption.padTo,
+		IdempotencyKey:     sendIdempotencyKey,
+	}, nil
+}
+
 // parseTitleAndMessage extracts title and message from args or stdin
 // Message is optional - can be empty string
-func parseTitleAndMessage(cmd *cobra.Command, args []string) (string, string, error) {
+// If tmpl is set, its title and message are used when not given in args or stdin
+func parseTitleAndMessage(cmd *cobra.Command, args []string, tmpl *templates.Rendered) (string, string, error) {
 	var title, message string
 
 	if sendStdin {
 		// Read message from stdin
-		if len(args) < 1 {
+		if len(args) < 1 && tmpl == nil {
 			return "", "", fmt.Errorf("title is required when using --stdin")
 		}
-		title = args[0]
+		if len(args) >= 1 {
+			title = args[0]
+		}
 
 		// Read from stdin
-		scanner := bufio.NewScanner(os.Stdin)
-		var lines []string
-		for scanner.Scan() {
-			lines = append(lines, scanner.Text())
+		var err error
+		if message, err = readStdin(); err != nil {
+			return "", "", err
 		}
-		if err := scanner.Err(); err != nil {
-			return "", "", fmt.Errorf("failed to read from stdin: %w", err)
-		}
-		message = strings.Join(lines, "\n")
 
 		// Message can be empty - backend allows null message
 	} else {
 		// Get from positional arguments
-		if len(args) < 1 {
+		if len(args) < 1 && tmpl == nil {
 			return "", "", fmt.Errorf("title is required")
 		}
-		title = args[0]
+		if len(args) >= 1 {
+			title = args[0]
+		}
 
 		// Message is optional (can be omitted)
 		if len(args) >= 2 {
 			message = args[1]
+		} else if tmpl != nil {
+			message = tmpl.Message
+		}
+	}
+
+	if title == "" && tmpl != nil {
+		title = tmpl.Title
+		if title == "" {
+			return "", "", fmt.Errorf("template has no title and none was given")
 		}
 	}
 
@@ -223,6 +346,8 @@ func categorizeError(err error) error {
 		return clierrors.NewAPIError("Rate limit exceeded", fmt.Errorf("%v\n\nThe send endpoint allows 30 requests per hour. Please wait before trying again.", e))
 	case *clierrors.ServerError:
 		return clierrors.NewAPIError("Server error", e)
+	case *clierrors.CircuitOpenError:
+		return clierrors.NewAPIError("API unavailable", fmt.Errorf("%v\n\nRequests fail fast until the API recovers. Set breaker_threshold to -1 to disable this.", e))
 	case *clierrors.NetworkError:
 		return clierrors.NewSystemError("Network error", fmt.Errorf("%v\n\nPlease check your internet connection and try again.", e))
 	default:
@@ -262,12 +387,5 @@ func displaySendResult(result *client.SendResult) {
 	}
 
 	// Display rate limit info if available
-	if result.RateLimit != nil && result.RateLimit.Limit != "" {
-		fmt.Println()
-		fmt.Printf("Rate Limit: %s/%s remaining", result.RateLimit.Remaining, result.RateLimit.Limit)
-		if result.RateLimit.Reset != "" {
-			fmt.Printf(" (resets at %s)", result.RateLimit.Reset)
-		}
-		fmt.Println()
-	}
+	displayRateLimit(result.RateLimit)
 }
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/github"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/secrets"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// maxWebhookSize is the largest webhook payload accepted (GitHub caps payloads at 25 MB)
+	maxWebhookSize = 25 << 20
+
+	// webhookQueueSize is the number of notifications waiting to be sent before deliveries are refused
+	webhookQueueSize = 100
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Receive webhooks and turn them into notifications",
+	Long: `Run a server receiving webhooks from other services and sending a
+notification for each event, so you get pushes without writing a bot.`,
+}
+
+// serveGithubCmd represents the 'serve github' command
+var serveGithubCmd = &cobra.Command{
+	Use:   "github --secret-file <file>",
+	Short: "Receive GitHub webhooks",
+	Long: `Receive GitHub webhooks and send a notification for workflow runs,
+releases, pull requests and pushes.
+
+Add a webhook to a repository or organization (Settings → Webhooks) with
+content type application/json, the URL of this server and a secret, and
+select the events to send. Deliveries without a valid X-Hub-Signature-256
+signature for the secret are rejected.
+
+By default notifications are sent for completed workflow runs (as alerts if
+they failed), published releases, pull requests opened, reopened, closed or
+ready for review, and pushes. They are tagged with the repository name and
+the event and open the run, release, pull request or comparison when tapped.
+The github section of the config file overrides this per event.
+
+Deliveries are answered at once and the notifications sent in order in the
+background, with the usual retries and rate limit handling. A redelivered
+event is not notified twice.
+
+Examples:
+  # Listen on all interfaces, behind a reverse proxy providing TLS
+  pincho serve github --secret-file ~/.pincho/github.secret --host 0.0.0.0 --port 8788
+
+  # Test locally with a forwarding service
+  pincho serve github --secret-file ./secret --port 8788
+`,
+	Args: cobra.NoArgs,
+	RunE: runServeGithub,
+}
+
+var (
+	serveSecretFile string
+	serveHost       string
+	servePort       int
+	servePath       string
+	serveOutbox     bool
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.AddCommand(serveGithubCmd)
+
+	serveGithubCmd.Flags().StringVar(&serveSecretFile, "secret-file", "", "File holding the webhook secret (required)")
+	serveGithubCmd.Flags().StringVar(&serveHost, "host", "127.0.0.1", "Address to listen on")
+	serveGithubCmd.Flags().IntVar(&servePort, "port", 8788, "Port to listen on")
+	serveGithubCmd.Flags().StringVar(&servePath, "path", "/", "URL path receiving webhooks")
+	serveGithubCmd.Flags().BoolVar(&serveOutbox, "outbox", false, "Queue notifications in the outbox if they cannot be delivered (env: PINCHO_OUTBOX)")
+	serveGithubCmd.MarkFlagRequired("secret-file")
+}
+
+// webhookDelivery is a notification waiting to be sent
+type webhookDelivery struct {
+	event string
+	opts  *client.SendOptions
+}
+
+func runServeGithub(cmd *cobra.Command, args []string) error {
+	secret, err := secrets.ReadFile(serveSecretFile)
+	if err != nil {
+		return clierrors.NewUsageError("Failed to read webhook secret", err)
+	}
+
+	var mappings map[string]github.Mapping
+	if err := viper.UnmarshalKey(config.Key("github"), &mappings); err != nil {
+		return clierrors.NewUsageError("Invalid config", fmt.Errorf("invalid github section in config: %w", err))
+	}
+	for event := range mappings {
+		if !slices.Contains(github.Events, event) {
+			fmt.Fprintf(os.Stderr, "Warning: github section of the config file maps unsupported event %q\n", event)
+		}
+	}
+
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
+	}
+	c := newClient(cmd, token)
+
+	// One sender, so notifications go out in order and share the rate limit
+	queue := make(chan webhookDelivery, webhookQueueSize)
+	senderDone := make(chan struct{})
+	go func() {
+		defer close(senderDone)
+		for d := range queue {
+			sendWebhookNotification(cmd, c, d)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(servePath, func(w http.ResponseWriter, r *http.Request) {
+		serveGithubWebhook(w, r, secret, mappings, queue)
+	})
+
+	addr := net.JoinHostPort(serveHost, strconv.Itoa(servePort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return clierrors.NewSystemError("Failed to start server", err)
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("Receiving GitHub webhooks on http://%s%s\n", listener.Addr().String(), servePath)
+	fmt.Println("Press Ctrl+C to stop")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	serveErr := server.Serve(listener)
+
+	// Send what was accepted before stopping
+	close(queue)
+	<-senderDone
+
+	if serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+		return clierrors.NewSystemError("Server failed", serveErr)
+	}
+	logging.Debug("Server stopped")
+	return nil
+}
+
+// serveGithubWebhook handles one webhook delivery
+func serveGithubWebhook(w http.ResponseWriter, r *http.Request, secret string, mappings map[string]github.Mapping, queue chan<- webhookDelivery) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	name := r.Header.Get(github.EventHeader)
+	delivery := r.Header.Get(github.DeliveryHeader)
+	if err := github.VerifySignature(secret, body, r.Header.Get(github.SignatureHeader)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: rejected webhook from %s: %v\n", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if name == "ping" {
+		logging.Debug("Webhook ping", "delivery", delivery)
+		fmt.Fprintln(w, "pong")
+		return
+	}
+
+	event, err := github.Parse(name, body)
+	if errors.Is(err, github.ErrUnsupportedEvent) {
+		logging.Debug("Ignoring unsupported event", "event", name, "delivery", delivery)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "ignored: unsupported event %s\n", name)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := event.Notification(mappings[name])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to map %s delivery %s: %v\n", name, delivery, err)
+		http.Error(w, "invalid mapping", http.StatusInternalServerError)
+		return
+	}
+	if opts == nil {
+		logging.Debug("Ignoring event", "event", name, "action", event.Action, "delivery", delivery)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "ignored: %s %s\n", name, event.Action)
+		return
+	}
+
+	// A redelivery reuses the delivery ID, so it is recognized as a duplicate
+	if delivery != "" {
+		opts.IdempotencyKey = "github-" + delivery
+	}
+
+	select {
+	case queue <- webhookDelivery{event: name, opts: opts}:
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "queued")
+	default:
+		http.Error(w, "too many pending notifications", http.StatusServiceUnavailable)
+	}
+}
+
+// sendWebhookNotification sends the notification of a delivery, reporting failures as warnings
+func sendWebhookNotification(cmd *cobra.Command, c *client.Client, d webhookDelivery) {
+	err := sendNotification(cmd, c, d.opts)
+	if dupErr, ok := err.(*clierrors.DuplicateError); ok {
+		logging.Debug("Skipping redelivered event", "event", d.event, "sent_at", dupErr.SentAt)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
+		return
+	}
+	fmt.Printf("%s  %s: %s\n", time.Now().Format("15:04:05"), d.event, d.opts.Title)
+}
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/logging"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+	"github.com/Pincho-App/pincho-cli/pkg/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch <file>",
+	Short: "Follow a log file and notify about lines matching rules",
+	Long: `Follow a log file like tail -F and send a notification for lines
+matching a list of rules, until interrupted. The file is followed across
+rotation and truncation.
+
+Rules come from --match, or from a named rule set in the watch section of the
+config file (--rules). The first rule matching a line applies. Title,
+message, type and tags are templates with the named groups of the pattern
+available as .Vars, together with file, rule, line and lines. By default the
+title names the rule and file and the message holds the matched lines.
+
+A rule with a continuation pattern groups the lines following a match that
+match it, such as the frames of a stack trace, into one notification. A
+throttle sends at most one notification per interval for the rule; the next
+one reports how many matches were throttled.
+
+Notifications are sent with the usual retries and rate limit handling; with
+--outbox, those that cannot be delivered are queued.
+
+Examples:
+  # Alert on errors, at most once every 5 minutes
+  pincho watch /var/log/app.log --match 'ERROR' --type alert --throttle 5m
+
+  # Name the component in the title
+  pincho watch /var/log/app.log --match 'ERROR \[(?P<component>\w+)\]' --title '{{.Vars.component}} error'
+
+  # Group Java stack traces into one notification
+  pincho watch app.log --match 'Exception' --continuation '^\s+at |^Caused by:'
+
+  # Use the rules defined as watch.app in the config file
+  pincho watch /var/log/app.log --rules app
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWatch,
+}
+
+var (
+	watchRules        string
+	watchMatch        []string
+	watchTitle        string
+	watchMessage      string
+	watchType         string
+	watchTags         []string
+	watchThrottle     time.Duration
+	watchContinuation string
+	watchMaxLines     int
+	watchFromStart    bool
+	watchPoll         time.Duration
+	watchOutbox       bool
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVar(&watchRules, "rules", "", "Rule set from the watch section of the config file")
+	watchCmd.Flags().StringArrayVar(&watchMatch, "match", nil, "Regular expression to notify about, a rule with the flags below (can be used multiple times)")
+	watchCmd.Flags().StringVar(&watchTitle, "title", "", "Title template of --match rules (default: rule and file name)")
+	watchCmd.Flags().StringVar(&watchMessage, "message", "", "Message template of --match rules (default: the matched lines)")
+	watchCmd.Flags().StringVar(&watchType, "type", "", "Notification type of --match rules")
+	watchCmd.Flags().StringSliceVar(&watchTags, "tag", []string{}, "Tags of --match rules (can be used multiple times)")
+	watchCmd.Flags().DurationVar(&watchThrottle, "throttle", 0, "Minimum time between notifications of a --match rule, e.g. 5m")
+	watchCmd.Flags().StringVar(&watchContinuation, "continuation", "", "Regular expression of lines grouped with a --match match, e.g. '^\\s' for stack traces")
+	watchCmd.Flags().IntVar(&watchMaxLines, "max-lines", watch.DefaultMaxLines, "Maximum number of lines grouped into one notification")
+	watchCmd.Flags().BoolVar(&watchFromStart, "from-start", false, "Read the lines already in the file instead of starting at its end")
+	watchCmd.Flags().DurationVar(&watchPoll, "poll", watch.DefaultPollInterval, "How often to check the file for new lines")
+	watchCmd.Flags().BoolVar(&watchOutbox, "outbox", false, "Queue notifications in the outbox if they cannot be delivered (env: PINCHO_OUTBOX)")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	rules, err := watchRuleSet()
+	if err != nil {
+		return err
+	}
+	if watchPoll <= 0 {
+		return clierrors.NewUsageError("Invalid arguments", fmt.Errorf("--poll must be positive"))
+	}
+	if _, err := os.Stat(path); err != nil {
+		return clierrors.NewUsageError("Invalid arguments", err)
+	}
+
+	token, err := requireToken(cmd)
+	if err != nil {
+		return err
+	}
+	c := newClient(cmd, token)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	lines := make(chan string, 100)
+	followErr := make(chan error, 1)
+	go func() {
+		followErr <- watch.Follow(ctx, path, watch.FollowOptions{FromStart: watchFromStart, PollInterval: watchPoll}, lines)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Watching %s with %d %s (Ctrl+C to stop)\n", path, len(rules), templates.Plural(len(rules), "rule", "rules"))
+	logging.Debug("Watching file", "path", path, "rules", len(rules), "poll", watchPoll)
+
+	// A group is complete once no line followed it for a while
+	groupTimeout := max(2*watchPoll, time.Second)
+	ticker := time.NewTicker(watchPoll)
+	defer ticker.Stop()
+
+	matcher := watch.NewMatcher(rules)
+	lastLine := time.Now()
+	for {
+		select {
+		case line := <-lines:
+			lastLine = time.Now()
+			for _, match := range matcher.Feed(line, lastLine) {
+				notifyMatch(cmd, c, matcher, path, match)
+			}
+
+		case <-ticker.C:
+			if matcher.Pending() && time.Since(lastLine) >= groupTimeout {
+				if match := matcher.Flush(); match != nil {
+					notifyMatch(cmd, c, matcher, path, match)
+				}
+			}
+
+		case err := <-followErr:
+			// Lines read before stopping are still notified
+			for done := false; !done; {
+				select {
+				case line := <-lines:
+					for _, match := range matcher.Feed(line, time.Now()) {
+						notifyMatch(cmd, c, matcher, path, match)
+					}
+				default:
+					done = true
+				}
+			}
+			if match := matcher.Flush(); match != nil {
+				notifyMatch(cmd, c, matcher, path, match)
+			}
+
+			if err != nil && ctx.Err() == nil {
+				return clierrors.NewSystemError("Failed to watch file", err)
+			}
+			return nil
+		}
+	}
+}
+
+// watchRuleSet compiles the rules of the config file rule set and the --match flags
+func watchRuleSet() ([]*watch.Rule, error) {
+	var configs []watch.RuleConfig
+	if watchRules != "" {
+		var sets map[string][]watch.RuleConfig
+		if err := viper.UnmarshalKey(config.Key("watch"), &sets); err != nil {
+			return nil, clierrors.NewUsageError("Invalid config", fmt.Errorf("invalid watch section in config: %w", err))
+		}
+
+		// Viper lowercases keys, so rule set names are case-insensitive
+		set, ok := sets[strings.ToLower(watchRules)]
+		if !ok {
+			return nil, clierrors.NewUsageError("Invalid arguments", fmt.Errorf("rule set %q not found in the watch section of the config file", watchRules))
+		}
+		configs = append(configs, set...)
+	}
+
+	for i, pattern := range watchMatch {
+		name := "match"
+		if len(watchMatch) > 1 {
+			name = fmt.Sprintf("match %d", i+1)
+		}
+		configs = append(configs, watch.RuleConfig{
+			Name:         name,
+			Pattern:      pattern,
+			Continuation: watchContinuation,
+			MaxLines:     watchMaxLines,
+			Throttle:     watchThrottle,
+			Template: templates.Template{
+				Title:   watchTitle,
+				Message: watchMessage,
+				Type:    watchType,
+				Tags:    watchTags,
+			},
+		})
+	}
+
+	if len(configs) == 0 {
+		return nil, clierrors.NewUsageError("Invalid arguments", fmt.Errorf("no rules given (use --match or --rules)"))
+	}
+	rules, err := watch.Compile(configs)
+	if err != nil {
+		return nil, clierrors.NewUsageError("Invalid rule", err)
+	}
+	return rules, nil
+}
+
+// notifyMatch sends the notification of a match, reporting failures as warnings
+// A long-running watch keeps going if a notification cannot be rendered or sent;
+// only a delivered notification starts the rule's throttle window.
+func notifyMatch(cmd *cobra.Command, c *client.Client, matcher *watch.Matcher, path string, match *watch.Match) {
+	rendered, err := match.Render(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to render notification: %v\n", err)
+		return
+	}
+
+	opts := &client.SendOptions{
+		Title:     rendered.Title,
+		Message:   rendered.Message,
+		Type:      rendered.Type,
+		Tags:      rendered.Tags,
+		ImageURL:  rendered.ImageURL,
+		ActionURL: rendered.ActionURL,
+	}
+	if err := sendNotification(cmd, c, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
+		return
+	}
+	matcher.Sent(match)
+	fmt.Printf("%s  %s: %s\n", time.Now().Format("15:04:05"), match.Rule.Name, rendered.Title)
+}
@@ -9,15 +9,14 @@ pincho send "Build Started" "Pipeline #$CI_PIPELINE_ID started on $CI_COMMIT_BRA
   --type info \
   --tag ci
 
-# Notify on build success
-if [ $? -eq 0 ]; then
-  pincho send "Build Passed" "Pipeline #$CI_PIPELINE_ID completed successfully" \
-    --type success \
-    --tag ci \
-    --action-url "https://github.com/your-project/actions/runs/$CI_PIPELINE_ID"
-else
-  pincho send "Build Failed" "Pipeline #$CI_PIPELINE_ID failed" \
-    --type alert \
-    --tag ci \
-    --action-url "https://github.com/your-project/actions/runs/$CI_PIPELINE_ID"
-fi
+# Run the build and notify when it finishes: "success" if it passed, "alert"
+# with the last lines of output if it failed. The build's exit code is passed
+# on, so the pipeline still fails when the build does.
+pincho exec \
+  --name "Pipeline #$CI_PIPELINE_ID" \
+  --exit-type success=success \
+  --exit-type failure=alert \
+  --tag ci \
+  --lines 20 \
+  --action-url "https://github.com/your-project/actions/runs/$CI_PIPELINE_ID" \
+  -- make build test
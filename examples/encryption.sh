@@ -48,13 +48,12 @@ echo "API Key: abc123xyz456" | pincho send "API Credentials" --stdin \
   --encryption-password "credentials-password"
 echo
 
-# Example 6: Environment variable for password (more secure)
+# Example 6: Environment variable for password (more secure, not visible in ps)
 echo "6. Using environment variable for password"
-export ENCRYPTION_PASSWORD="env-secret-password"
+export PINCHO_ENCRYPTION_PASSWORD="env-secret-password"
 pincho send "Deployment Credentials" "SSH key generated: $(date)" \
-  --type deployment \
-  --encryption-password "$ENCRYPTION_PASSWORD"
-unset ENCRYPTION_PASSWORD
+  --type deployment
+unset PINCHO_ENCRYPTION_PASSWORD
 echo
 
 # Example 7: Multiple notifications with same password
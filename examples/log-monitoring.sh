@@ -1,14 +1,20 @@
 #!/bin/bash
 # Log monitoring example - send notification when errors are detected
 
-# Monitor log file for errors
-tail -f /var/log/app.log | grep --line-buffered "ERROR" | while read line
-do
-  echo "$line" | pincho send "Error Detected" --stdin --type alert --tag monitoring
-done
-
-# Alternative: Check for critical errors once
+# Check for critical errors once
 if grep -q "CRITICAL" /var/log/app.log; then
   grep "CRITICAL" /var/log/app.log | tail -1 | \
     pincho send "Critical Error" --stdin --type alert --tag critical
 fi
+
+# Follow the log file until interrupted, surviving log rotation, and alert on
+# errors at most once every 5 minutes; throttled errors are counted in the
+# next notification
+pincho watch /var/log/app.log \
+  --match 'ERROR' \
+  --type alert \
+  --tag monitoring \
+  --throttle 5m
+
+# Alternative: group Java stack traces into one notification per exception
+# pincho watch /var/log/app.log --match 'Exception' --continuation '^\s+at |^Caused by:'
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+// DefaultBatchConcurrency is the default number of notifications sent in parallel by SendBatch
+const DefaultBatchConcurrency = 4
+
+// BatchOptions controls how SendBatch fans out requests
+type BatchOptions struct {
+	Concurrency int // Maximum number of in-flight requests (uses DefaultBatchConcurrency if zero)
+	RetryBudget int // Total retries shared by the whole batch (uses MaxRetries per worker if zero)
+}
+
+// BatchResult holds the outcome of a single notification in a batch
+type BatchResult struct {
+	Index  int         // Position of the notification in the input slice
+	Result *SendResult // Set when the notification was sent successfully
+	Err    error       // Set when the notification failed
+}
+
+// SendBatch sends many notifications through the client's HTTPClient with bounded
+// concurrency. A failing notification does not abort the batch: every input gets a
+// BatchResult at the same index, carrying either a result or an error. A nil item
+// fails with a ValidationError.
+//
+// The default transport keeps only 2 idle connections per host, so workers beyond
+// that open new connections; use WithTransport with MaxIdleConnsPerHost set to the
+// concurrency to reuse one connection per worker.
+//
+// Retries are drawn from a budget shared by the whole batch, and a 429 response
+// pauses every worker until the rate limit backoff has elapsed, so a throttled
+// batch backs off as a unit instead of each request retrying independently.
+func (c *Client) SendBatch(ctx context.Context, items []*SendOptions, batchOpts BatchOptions) []BatchResult {
+	results := make([]BatchResult, len(items))
+	if len(items) == 0 {
+		return results
+	}
+
+	concurrency := batchOpts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	if concurrency > len(items) {
+		concurrency = len(items)
+	}
+
+	budget := batchOpts.RetryBudget
+	if budget <= 0 {
+		maxRetries := c.MaxRetries
+		if maxRetries == 0 {
+			maxRetries = DefaultMaxRetries
+		}
+		budget = maxRetries * concurrency
+	}
+	gate := newRetryGate(budget)
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if items[i] == nil {
+					results[i] = BatchResult{Index: i, Err: errors.NewValidationError("notification is nil")}
+					continue
+				}
+				result, err := c.send(ctx, items[i], gate)
+				results[i] = BatchResult{Index: i, Result: result, Err: err}
+			}
+		}()
+	}
+
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// retryGate coordinates the retries of requests that share a budget and a
+// rate limit pause, such as the items of a batch
+type retryGate struct {
+	mu         sync.Mutex
+	budget     int
+	pauseUntil time.Time
+}
+
+// newRetryGate creates a gate allowing budget retries in total
+func newRetryGate(budget int) *retryGate {
+	return &retryGate{budget: budget}
+}
+
+// take consumes one retry from the shared budget, reporting false once it is spent
+func (g *retryGate) take() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.budget <= 0 {
+		return false
+	}
+	g.budget--
+	return true
+}
+
+// pause holds every request using the gate for at least d
+func (g *retryGate) pause(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(g.pauseUntil) {
+		g.pauseUntil = until
+	}
+}
+
+// wait blocks until any active pause has elapsed or ctx is done
+func (g *retryGate) wait(ctx context.Context) error {
+	for {
+		// Re-check after sleeping, another request may have extended the pause
+		g.mu.Lock()
+		remaining := time.Until(g.pauseUntil)
+		g.mu.Unlock()
+
+		if remaining <= 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(remaining):
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+func TestClient_SendBatch_Success(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success", "message": "Notification sent"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+
+	items := make([]*SendOptions, 10)
+	for i := range items {
+		items[i] = &SendOptions{Title: "Test", Message: "Message"}
+	}
+
+	results := client.SendBatch(context.Background(), items, BatchOptions{Concurrency: 3})
+	if len(results) != len(items) {
+		t.Fatalf("expected %d results, got %d", len(items), len(results))
+	}
+
+	for i, r := range results {
+		if r.Index != i {
+			t.Errorf("result %d has index %d", i, r.Index)
+		}
+		if r.Err != nil {
+			t.Errorf("result %d: expected no error, got: %v", i, r.Err)
+		}
+		if r.Result == nil || r.Result.Response == nil {
+			t.Errorf("result %d: expected response, got nil", i)
+		}
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got > 3 {
+		t.Errorf("expected at most 3 concurrent requests, got %d", got)
+	}
+}
+
+func TestClient_SendBatch_PartialFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body SendOptions
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		if body.Title == "bad" {
+			w.WriteHeader(400)
+			_, _ = w.Write([]byte(`{"status": "error", "error": {"type": "validation_error", "code": "invalid_title", "message": "Invalid title"}}`))
+			return
+		}
+
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+
+	items := []*SendOptions{
+		{Title: "first"},
+		{Title: "bad"},
+		{Title: "third"},
+		{Title: ""}, // Fails client-side validation
+		nil,         // Fails without crashing the batch
+	}
+
+	results := client.SendBatch(context.Background(), items, BatchOptions{Concurrency: 2})
+
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("expected successful items to succeed, got errors: %v, %v", results[0].Err, results[2].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("expected API validation error for item 1")
+	}
+	if results[3].Err == nil {
+		t.Error("expected client validation error for item 3")
+	}
+	if _, ok := results[4].Err.(*errors.ValidationError); !ok || results[4].Index != 4 {
+		t.Errorf("expected *ValidationError at index 4 for a nil item, got %T: %v", results[4].Err, results[4].Err)
+	}
+	if results[1].Result != nil || results[3].Result != nil || results[4].Result != nil {
+		t.Error("expected nil results for failed items")
+	}
+}
+
+func TestClient_SendBatch_RateLimitPausesAllWorkers(t *testing.T) {
+	const concurrency = 4
+
+	var mu sync.Mutex
+	var requestTimes []time.Time
+	var limitedAt time.Time
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requestTimes = append(requestTimes, time.Now())
+		first := len(requestTimes) == 1
+		if first {
+			limitedAt = time.Now()
+		}
+		mu.Unlock()
+
+		// The first request is rate limited at once; the other workers' first
+		// requests are still in flight and finish after the pause has begun
+		if first {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(429)
+			_, _ = w.Write([]byte(`{"status": "error", "error": {"type": "rate_limit_error", "code": "rate_limit_exceeded", "message": "Too many requests"}}`))
+			return
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+
+	items := make([]*SendOptions, 8)
+	for i := range items {
+		items[i] = &SendOptions{Title: fmt.Sprintf("item %d", i)}
+	}
+
+	results := client.SendBatch(context.Background(), items, BatchOptions{Concurrency: concurrency})
+
+	for i := range results {
+		if results[i].Err != nil {
+			t.Errorf("result %d: expected no error, got: %v", i, results[i].Err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// The rate limited item is retried once
+	if len(requestTimes) != len(items)+1 {
+		t.Fatalf("expected %d requests, got %d", len(items)+1, len(requestTimes))
+	}
+	// Only the requests started together before the 429 may precede the pause
+	for i, at := range requestTimes[concurrency:] {
+		if gap := at.Sub(limitedAt); gap < time.Second {
+			t.Errorf("request %d started %v after the 429, expected every worker to wait for Retry-After", concurrency+i+1, gap)
+		}
+	}
+}
+
+func TestClient_SendBatch_Empty(t *testing.T) {
+	client := New()
+	results := client.SendBatch(context.Background(), nil, BatchOptions{})
+	if len(results) != 0 {
+		t.Errorf("expected no results, got %d", len(results))
+	}
+}
+
+func TestRetryGate_Budget(t *testing.T) {
+	gate := newRetryGate(2)
+
+	if !gate.take() || !gate.take() {
+		t.Fatal("expected the first two retries to be allowed")
+	}
+	if gate.take() {
+		t.Error("expected retry budget to be exhausted")
+	}
+}
+
+func TestRetryGate_WaitRespectsContext(t *testing.T) {
+	gate := newRetryGate(1)
+	gate.pause(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := gate.wait(ctx); err == nil {
+		t.Error("expected wait to return the context error")
+	}
+}
@@ -0,0 +1,346 @@
+package client
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+const (
+	// BreakerFileName is the name of the state file holding circuit breaker state
+	BreakerFileName = "breaker.json"
+
+	// DefaultBreakerThreshold is the number of consecutive failures that opens the breaker
+	DefaultBreakerThreshold = 5
+
+	// DefaultBreakerCooldown is how long an open breaker fails fast before allowing a probe
+	DefaultBreakerCooldown = 60 * time.Second
+
+	// DefaultBreakerProbeLease is how long a half-open probe holds off other requests
+	// If the probe neither succeeds nor fails within the lease, another one is let through.
+	DefaultBreakerProbeLease = 30 * time.Second
+)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState string
+
+const (
+	// BreakerClosed lets every request through
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen fails every request fast until the cooldown has elapsed
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets a single probe request through after the cooldown;
+	// success closes the breaker, failure opens it again
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerRecord is the persisted state of a circuit breaker for one API host
+type BreakerRecord struct {
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	OpenUntil           time.Time `json:"openUntil,omitempty"`
+	ProbeUntil          time.Time `json:"probeUntil,omitempty"` // Lease of the probe in flight while half-open
+}
+
+// BreakerStatus is a snapshot of a circuit breaker
+type BreakerStatus struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	OpenUntil           time.Time // Zero unless the breaker has opened
+}
+
+// BreakerStore persists circuit breaker state so that separate processes share it
+type BreakerStore interface {
+	// Load returns the record for key, or a zero record if none exists
+	Load(key string) (BreakerRecord, error)
+
+	// Update replaces the record for key with fn applied to it and returns the new record
+	// Updates must be atomic, also between processes sharing the store, so
+	// that concurrent failures are all counted.
+	Update(key string, fn func(BreakerRecord) BreakerRecord) (BreakerRecord, error)
+}
+
+// CircuitBreaker stops sending requests to an API host after sustained
+// server or network failures, failing fast until a cooldown has elapsed.
+// It is safe for concurrent use.
+type CircuitBreaker struct {
+	Threshold  int           // Consecutive failures that open the breaker (uses DefaultBreakerThreshold if zero)
+	Cooldown   time.Duration // How long the breaker stays open (uses DefaultBreakerCooldown if zero)
+	ProbeLease time.Duration // How long a half-open probe holds off other requests (uses DefaultBreakerProbeLease if zero)
+	Store      BreakerStore  // Shares state between processes (kept in memory if nil)
+
+	mu     sync.Mutex
+	memory map[string]BreakerRecord
+}
+
+// NewCircuitBreaker creates a circuit breaker persisting its state to store
+func NewCircuitBreaker(threshold int, cooldown time.Duration, store BreakerStore) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		Store:     store,
+	}
+}
+
+// Allow returns a CircuitOpenError if requests to key must fail fast
+// While half-open, only the caller claiming the probe is allowed; other
+// callers, also in other processes sharing the store, fail fast until the
+// probe succeeds, fails, or its lease expires.
+func (b *CircuitBreaker) Allow(key string) error {
+	status := b.Status(key)
+	switch status.State {
+	case BreakerOpen:
+		return errors.NewCircuitOpenError(status.ConsecutiveFailures, status.OpenUntil)
+	case BreakerHalfOpen:
+		return b.claimProbe(key)
+	}
+	return nil
+}
+
+// claimProbe records a probe in flight for key, unless another caller holds one
+func (b *CircuitBreaker) claimProbe(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	claimed := false
+	record := b.update(key, func(record BreakerRecord) BreakerRecord {
+		// Another caller may have closed or reopened the breaker meanwhile
+		if record.OpenUntil.IsZero() || now.Before(record.OpenUntil) || now.Before(record.ProbeUntil) {
+			return record
+		}
+		record.ProbeUntil = now.Add(b.probeLease())
+		claimed = true
+		return record
+	})
+	if claimed || record.OpenUntil.IsZero() {
+		return nil
+	}
+
+	until := record.OpenUntil
+	if record.ProbeUntil.After(until) {
+		until = record.ProbeUntil
+	}
+	return errors.NewCircuitOpenError(record.ConsecutiveFailures, until)
+}
+
+// releaseProbe gives up the probe in flight for key without a verdict,
+// so that the next caller can probe at once
+func (b *CircuitBreaker) releaseProbe(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if record := b.load(key); record.ProbeUntil.IsZero() {
+		return
+	}
+	b.update(key, func(record BreakerRecord) BreakerRecord {
+		record.ProbeUntil = time.Time{}
+		return record
+	})
+}
+
+// Status returns the current state of the breaker for key
+func (b *CircuitBreaker) Status(key string) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record := b.load(key)
+	status := BreakerStatus{
+		State:               BreakerClosed,
+		ConsecutiveFailures: record.ConsecutiveFailures,
+		OpenUntil:           record.OpenUntil,
+	}
+
+	if !record.OpenUntil.IsZero() {
+		if time.Now().Before(record.OpenUntil) {
+			status.State = BreakerOpen
+		} else {
+			status.State = BreakerHalfOpen
+		}
+	}
+
+	return status
+}
+
+// RecordSuccess closes the breaker for key
+func (b *CircuitBreaker) RecordSuccess(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if record := b.load(key); record.ConsecutiveFailures == 0 && record.OpenUntil.IsZero() {
+		return // Already closed, avoid rewriting the state file on every request
+	}
+	b.update(key, func(BreakerRecord) BreakerRecord {
+		return BreakerRecord{}
+	})
+}
+
+// RecordFailure counts a failure for key, opening the breaker once the threshold is reached
+// A failed probe while half-open opens the breaker again for a full cooldown
+func (b *CircuitBreaker) RecordFailure(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update(key, func(record BreakerRecord) BreakerRecord {
+		record.ProbeUntil = time.Time{}
+		record.ConsecutiveFailures++
+		if record.ConsecutiveFailures >= b.threshold() {
+			record.OpenUntil = time.Now().Add(b.cooldown())
+		}
+		return record
+	})
+}
+
+// threshold returns the configured threshold or the default
+func (b *CircuitBreaker) threshold() int {
+	if b.Threshold > 0 {
+		return b.Threshold
+	}
+	return DefaultBreakerThreshold
+}
+
+// cooldown returns the configured cooldown or the default
+func (b *CircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown > 0 {
+		return b.Cooldown
+	}
+	return DefaultBreakerCooldown
+}
+
+// probeLease returns the configured probe lease or the default
+func (b *CircuitBreaker) probeLease() time.Duration {
+	if b.ProbeLease > 0 {
+		return b.ProbeLease
+	}
+	return DefaultBreakerProbeLease
+}
+
+// load reads the record for key from the store, or from memory without a store
+// A store that cannot be read is treated as closed so the breaker never blocks by mistake
+func (b *CircuitBreaker) load(key string) BreakerRecord {
+	if b.Store != nil {
+		record, err := b.Store.Load(key)
+		if err != nil {
+			return BreakerRecord{}
+		}
+		return record
+	}
+	return b.memory[key]
+}
+
+// update changes the record for key in the store, or in memory without a store
+func (b *CircuitBreaker) update(key string, fn func(BreakerRecord) BreakerRecord) BreakerRecord {
+	if b.Store != nil {
+		// Persistence is best effort, a failed write only loses shared state
+		record, _ := b.Store.Update(key, fn)
+		return record
+	}
+	if b.memory == nil {
+		b.memory = make(map[string]BreakerRecord)
+	}
+	b.memory[key] = fn(b.memory[key])
+	return b.memory[key]
+}
+
+// FileBreakerStore is a BreakerStore backed by a JSON file
+// Updates lock the file, so concurrent processes do not lose each other's failures.
+type FileBreakerStore struct {
+	path string
+}
+
+// NewFileBreakerStore creates a breaker store persisting to the file at path
+func NewFileBreakerStore(path string) *FileBreakerStore {
+	return &FileBreakerStore{path: path}
+}
+
+// Load returns the record for key
+func (s *FileBreakerStore) Load(key string) (BreakerRecord, error) {
+	records := make(map[string]BreakerRecord)
+	if _, err := state.ReadJSON(s.path, &records); err != nil {
+		return BreakerRecord{}, err
+	}
+	return records[key], nil
+}
+
+// Update changes the record for key, dropping closed records to keep the file small
+func (s *FileBreakerStore) Update(key string, fn func(BreakerRecord) BreakerRecord) (BreakerRecord, error) {
+	var record BreakerRecord
+	err := state.WithLock(s.path+".lock", func() error {
+		records := make(map[string]BreakerRecord)
+		if _, err := state.ReadJSON(s.path, &records); err != nil {
+			// Start over rather than failing forever on a corrupt file
+			records = make(map[string]BreakerRecord)
+		}
+
+		previous := records[key]
+		record = fn(previous)
+		if record == previous {
+			return nil
+		}
+		if record == (BreakerRecord{}) {
+			delete(records, key)
+		} else {
+			records[key] = record
+		}
+		return state.WriteJSON(s.path, records)
+	})
+	return record, err
+}
+
+// breakerKey identifies the API host a URL belongs to
+func breakerKey(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// BreakerStatus returns the circuit breaker state for the client's API host
+// Returns a closed status if the client has no circuit breaker
+func (c *Client) BreakerStatus() BreakerStatus {
+	if c.Breaker == nil {
+		return BreakerStatus{State: BreakerClosed}
+	}
+
+	endpointURL, err := c.EndpointURL(EndpointSend)
+	if err != nil {
+		return BreakerStatus{State: BreakerClosed}
+	}
+	u, err := url.Parse(endpointURL)
+	if err != nil {
+		return BreakerStatus{State: BreakerClosed}
+	}
+	return c.Breaker.Status(breakerKey(u))
+}
+
+// doRequestWithBreaker performs a request with retries, guarded by the circuit breaker
+// A request counts as failed if it ends in a network error, a 5xx response or an
+// expired deadline after all retries; any other response proves the API is reachable.
+// Only a caller cancellation leaves the breaker untouched
+func (c *Client) doRequestWithBreaker(ctx context.Context, req *http.Request, gate *retryGate) (*http.Response, error) {
+	if c.Breaker == nil {
+		return c.doRequestWithRetry(ctx, req, gate)
+	}
+
+	key := breakerKey(req.URL)
+	if err := c.Breaker.Allow(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequestWithRetry(ctx, req, gate)
+	switch {
+	case stderrors.Is(ctx.Err(), context.Canceled):
+		// Cancelled by the caller, says nothing about the API
+		c.Breaker.releaseProbe(key)
+	case err != nil, resp != nil && resp.StatusCode >= 500:
+		c.Breaker.RecordFailure(key)
+	default:
+		c.Breaker.RecordSuccess(key)
+	}
+
+	return resp, err
+}
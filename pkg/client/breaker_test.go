@@ -0,0 +1,275 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+func TestCircuitBreaker_Transitions(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 50*time.Millisecond, nil)
+	key := "https://api.example.com"
+
+	if got := breaker.Status(key).State; got != BreakerClosed {
+		t.Fatalf("expected new breaker to be closed, got %s", got)
+	}
+
+	breaker.RecordFailure(key)
+	if err := breaker.Allow(key); err != nil {
+		t.Fatalf("expected breaker below threshold to allow requests, got: %v", err)
+	}
+
+	breaker.RecordFailure(key)
+	status := breaker.Status(key)
+	if status.State != BreakerOpen || status.ConsecutiveFailures != 2 {
+		t.Fatalf("expected open breaker with 2 failures, got %+v", status)
+	}
+
+	err := breaker.Allow(key)
+	openErr, ok := err.(*errors.CircuitOpenError)
+	if !ok {
+		t.Fatalf("expected *CircuitOpenError, got %T: %v", err, err)
+	}
+	if openErr.Failures != 2 || openErr.OpenUntil.IsZero() {
+		t.Errorf("unexpected circuit open error: %+v", openErr)
+	}
+
+	// Other hosts are unaffected
+	if err := breaker.Allow("https://other.example.com"); err != nil {
+		t.Errorf("expected other host to be allowed, got: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if got := breaker.Status(key).State; got != BreakerHalfOpen {
+		t.Fatalf("expected half-open after cooldown, got %s", got)
+	}
+	if err := breaker.Allow(key); err != nil {
+		t.Fatalf("expected half-open breaker to allow a probe, got: %v", err)
+	}
+
+	// A failed probe opens the breaker again
+	breaker.RecordFailure(key)
+	if got := breaker.Status(key).State; got != BreakerOpen {
+		t.Fatalf("expected failed probe to reopen the breaker, got %s", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	breaker.RecordSuccess(key)
+	status = breaker.Status(key)
+	if status.State != BreakerClosed || status.ConsecutiveFailures != 0 {
+		t.Errorf("expected successful probe to close the breaker, got %+v", status)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenAllowsOneProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 10*time.Millisecond, nil)
+	breaker.ProbeLease = 50 * time.Millisecond
+	key := "https://api.example.com"
+
+	breaker.RecordFailure(key)
+	time.Sleep(20 * time.Millisecond)
+
+	if err := breaker.Allow(key); err != nil {
+		t.Fatalf("expected the first caller to probe, got: %v", err)
+	}
+	err := breaker.Allow(key)
+	openErr, ok := err.(*errors.CircuitOpenError)
+	if !ok {
+		t.Fatalf("expected other callers to fail fast while the probe is in flight, got %T: %v", err, err)
+	}
+	if openErr.OpenUntil.Before(time.Now()) {
+		t.Errorf("expected to fail fast until the probe lease expires, got %v", openErr.OpenUntil)
+	}
+
+	// A probe that never reports back gives way once its lease expires
+	time.Sleep(60 * time.Millisecond)
+	if err := breaker.Allow(key); err != nil {
+		t.Fatalf("expected a new probe after the lease expired, got: %v", err)
+	}
+
+	// A cancelled probe gives way at once
+	breaker.releaseProbe(key)
+	if err := breaker.Allow(key); err != nil {
+		t.Fatalf("expected a new probe after the previous one was released, got: %v", err)
+	}
+
+	breaker.RecordSuccess(key)
+	if err := breaker.Allow(key); err != nil {
+		t.Errorf("expected a successful probe to let everyone through, got: %v", err)
+	}
+}
+
+func TestFileBreakerStore_OneProbeAcrossBreakers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), BreakerFileName)
+	key := "https://api.example.com"
+
+	NewCircuitBreaker(1, 10*time.Millisecond, NewFileBreakerStore(path)).RecordFailure(key)
+	time.Sleep(20 * time.Millisecond)
+
+	// Breakers on the same file stand in for separate processes
+	var allowed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if NewCircuitBreaker(1, 10*time.Millisecond, NewFileBreakerStore(path)).Allow(key) == nil {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 1 {
+		t.Errorf("expected exactly one probe while half-open, got %d", allowed)
+	}
+}
+
+func TestFileBreakerStore_SharedBetweenBreakers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), BreakerFileName)
+	key := "https://api.example.com"
+
+	// Two breakers on the same file behave like two CLI processes
+	first := NewCircuitBreaker(2, time.Minute, NewFileBreakerStore(path))
+	second := NewCircuitBreaker(2, time.Minute, NewFileBreakerStore(path))
+
+	first.RecordFailure(key)
+	second.RecordFailure(key)
+
+	if got := first.Status(key).State; got != BreakerOpen {
+		t.Fatalf("expected failures from both breakers to open the circuit, got %s", got)
+	}
+
+	second.RecordSuccess(key)
+	if got := first.Status(key).State; got != BreakerClosed {
+		t.Errorf("expected success to close the shared circuit, got %s", got)
+	}
+}
+
+func TestFileBreakerStore_ConcurrentFailures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), BreakerFileName)
+	key := "https://api.example.com"
+
+	// Breakers on the same file stand in for parallel fan-out targets
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			NewCircuitBreaker(100, time.Minute, NewFileBreakerStore(path)).RecordFailure(key)
+		}()
+	}
+	wg.Wait()
+
+	status := NewCircuitBreaker(100, time.Minute, NewFileBreakerStore(path)).Status(key)
+	if status.ConsecutiveFailures != 20 {
+		t.Errorf("expected 20 failures to be counted, got %d", status.ConsecutiveFailures)
+	}
+}
+
+func TestClient_Send_BreakerOpensAfterFailures(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(503)
+		_, _ = w.Write([]byte(`{"status": "error", "message": "maintenance"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.SetRetryPolicy(&DefaultRetryPolicy{MaxRetries: 0})
+	client.Breaker = NewCircuitBreaker(2, time.Minute, NewFileBreakerStore(filepath.Join(t.TempDir(), BreakerFileName)))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+		if _, ok := err.(*errors.ServerError); !ok {
+			t.Fatalf("send %d: expected *ServerError, got %T: %v", i, err, err)
+		}
+	}
+
+	_, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+	if _, ok := err.(*errors.CircuitOpenError); !ok {
+		t.Fatalf("expected *CircuitOpenError, got %T: %v", err, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("expected 2 requests to reach the API, got %d", got)
+	}
+	if got := client.BreakerStatus().State; got != BreakerOpen {
+		t.Errorf("expected BreakerStatus to report open, got %s", got)
+	}
+}
+
+func TestClient_Send_ClientErrorsDoNotTripBreaker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		_, _ = w.Write([]byte(`{"status": "error"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.Breaker = NewCircuitBreaker(1, time.Minute, nil)
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Fatalf("send %d: expected *ValidationError, got %T: %v", i, err, err)
+		}
+	}
+	if got := client.BreakerStatus().State; got != BreakerClosed {
+		t.Errorf("expected breaker to stay closed on client errors, got %s", got)
+	}
+}
+
+func TestClient_Send_BreakerOpensAfterTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	const timeout = 50 * time.Millisecond
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.SetTimeout(timeout)
+	client.SetRetryPolicy(&DefaultRetryPolicy{MaxRetries: 0})
+	client.Breaker = NewCircuitBreaker(2, time.Minute, NewFileBreakerStore(filepath.Join(t.TempDir(), BreakerFileName)))
+
+	send := func() error {
+		// Same deadline as the HTTP client, like the CLI's request context
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_, err := client.Send(ctx, &SendOptions{Title: "Test"})
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := send(); err == nil {
+			t.Fatalf("send %d: expected a timeout error", i)
+		}
+	}
+
+	if _, ok := send().(*errors.CircuitOpenError); !ok {
+		t.Fatal("expected *CircuitOpenError after the threshold of timed out sends")
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("expected 2 requests to reach the API, got %d", got)
+	}
+}
@@ -7,9 +7,13 @@
 // Features:
 //   - Configurable timeout and retry logic with exponential backoff
 //   - Automatic tag validation and normalization
-//   - AES-128-CBC message encryption support
+//   - AES-128-CBC message encryption support (opt-in AES-256-GCM with crypto.SchemeV2)
 //   - Rate limit information extraction from response headers
+//   - Optional quota persistence to fail fast before a guaranteed 429
 //   - Structured error responses with detailed error information
+//   - Batch sending with bounded concurrency and a shared retry budget
+//   - Idempotency keys reused across retries so a retry never notifies twice
+//   - Optional circuit breaker to fail fast during sustained API outages
 //
 // Basic usage:
 //
@@ -26,26 +30,23 @@
 //
 // The client automatically retries on:
 //   - Network errors (connection refused, timeout, etc.)
-//   - Server errors (5xx status codes)
+//   - Server errors (500, 502, 503, 504)
 //   - Rate limit errors (429) with longer backoff
 //
-// Retries use exponential backoff (1s, 2s, 4s, 8s) capped at 30 seconds.
+// Retries use exponential backoff (1s, 2s, 4s, 8s) with full jitter, capped at
+// 30 seconds per delay and 60 seconds in total. A Retry-After header takes
+// precedence. Set Client.RetryPolicy to customize the behavior.
 package client
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/Pincho-App/pincho-cli/pkg/crypto"
 	"github.com/Pincho-App/pincho-cli/pkg/errors"
-	"github.com/Pincho-App/pincho-cli/pkg/validation"
 )
 
 const (
@@ -55,6 +56,9 @@ const (
 	// DefaultNotifAIURL is the default Pincho NotifAI API endpoint
 	DefaultNotifAIURL = "https://api.pincho.app/notifai"
 
+	// DefaultBaseURL is the default base URL that endpoint paths are resolved against
+	DefaultBaseURL = "https://api.pincho.app/"
+
 	// DefaultTimeout is the default HTTP client timeout
 	DefaultTimeout = 30 * time.Second
 
@@ -64,31 +68,64 @@ const (
 	// DefaultInitialBackoff is the default initial backoff duration for retries
 	DefaultInitialBackoff = 1 * time.Second
 
+	// EndpointSend is the name of the send endpoint
+	EndpointSend = "send"
+
+	// EndpointNotifAI is the name of the NotifAI endpoint
+	EndpointNotifAI = "notifai"
+
 	// Version is the client library version (can be overridden)
 	Version = "1.0.0"
 )
 
 // Client represents a Pincho API client
 type Client struct {
-	APIURL         string
+	APIURL         string            // Legacy send endpoint URL, used as the base when BaseURL is empty
+	BaseURL        string            // Base URL that endpoint paths are resolved against (overrides APIURL if set)
+	Endpoints      map[string]string // Per-endpoint path or absolute URL overrides (see EndpointURL)
 	HTTPClient     *http.Client
-	Timeout        time.Duration // Custom timeout duration (uses DefaultTimeout if zero)
-	MaxRetries     int           // Maximum number of retry attempts (uses DefaultMaxRetries if zero)
-	InitialBackoff time.Duration // Initial backoff duration for retries (uses DefaultInitialBackoff if zero)
-	Token          string        // API token for authentication (sent as Bearer token in Authorization header)
-	UserAgent      string        // User-Agent header value (defaults to pincho-cli/{version})
+	Timeout        time.Duration   // Custom timeout duration (uses DefaultTimeout if zero)
+	MaxRetries     int             // Maximum number of retry attempts (uses DefaultMaxRetries if zero)
+	InitialBackoff time.Duration   // Initial backoff duration for retries (uses DefaultInitialBackoff if zero)
+	Token          string          // API token for authentication (sent as Bearer token in Authorization header)
+	UserAgent      string          // User-Agent header value (defaults to pincho-cli/{version})
+	RetryPolicy    RetryPolicy     // Decides whether and when to retry (built from MaxRetries and InitialBackoff if nil)
+	QuotaStore     QuotaStore      // Persists rate limit quotas between requests (disabled if nil)
+	Breaker        *CircuitBreaker // Fails fast during sustained API outages (disabled if nil)
+
+	IdempotencyStore  IdempotencyStore // Records caller-supplied idempotency keys to refuse duplicates (disabled if nil)
+	IdempotencyWindow time.Duration    // How long a sent idempotency key is refused (uses DefaultIdempotencyWindow if zero)
 }
 
 // SendOptions contains parameters for sending a notification
 type SendOptions struct {
-	Title              string   `json:"title"`
-	Message            string   `json:"message"`
-	Type               string   `json:"type,omitempty"`
-	Tags               []string `json:"tags,omitempty"`
-	ImageURL           string   `json:"imageURL,omitempty"`
-	ActionURL          string   `json:"actionURL,omitempty"`
-	IV                 string   `json:"iv,omitempty"`
-	EncryptionPassword string   `json:"-"` // Not sent to API, used for local encryption
+	Title              string        `json:"title"`
+	Message            string        `json:"message"`
+	Type               string        `json:"type,omitempty"`
+	Tags               []string      `json:"tags,omitempty"`
+	ImageURL           string        `json:"imageURL,omitempty"`
+	ActionURL          string        `json:"actionURL,omitempty"`
+	IV                 string        `json:"iv,omitempty"`
+	EncryptionPassword string        `json:"-"` // Not sent to API, used for local encryption
+	EncryptionScheme   crypto.Scheme `json:"-"` // Encryption scheme (default: crypto.SchemeV1, as used by the app)
+	PadTo              int           `json:"-"` // Pad an encrypted message to a multiple of this many bytes to hide its length (0 disables)
+	IdempotencyKey     string        `json:"-"` // Sent as Idempotency-Key header (generated if empty)
+}
+
+// LogValue implements slog.LogValuer so that logged options never include
+// the encryption password or, if encrypted, the message
+func (o SendOptions) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("title", o.Title),
+		slog.Int("message_length", len(o.Message)),
+		slog.String("type", o.Type),
+		slog.Any("tags", o.Tags),
+		slog.Bool("encrypted", o.EncryptionPassword != ""),
+	}
+	if o.EncryptionPassword != "" {
+		attrs = append(attrs, slog.String("encryption_scheme", string(o.EncryptionScheme)), slog.Int("pad_to", o.PadTo))
+	}
+	return slog.GroupValue(attrs...)
 }
 
 // SendResponse represents the API success response
@@ -125,23 +162,18 @@ type NotificationDetails struct {
 	ExpiresAt      FirestoreTimestamp `json:"expiresAt"`
 }
 
-// RateLimitInfo contains rate limiting information from response headers
-type RateLimitInfo struct {
-	Limit     string
-	Remaining string
-	Reset     string
-}
-
 // SendResult combines the response with additional metadata
 type SendResult struct {
-	Response  *SendResponse
-	RateLimit *RateLimitInfo
+	Response       *SendResponse
+	RateLimit      *RateLimitInfo
+	IdempotencyKey string // Key sent with the request, reuse it to make a resend idempotent
 }
 
 // NotifAIOptions contains parameters for sending a NotifAI request
 type NotifAIOptions struct {
-	Text string `json:"text"`
-	Type string `json:"type,omitempty"`
+	Text           string `json:"text"`
+	Type           string `json:"type,omitempty"`
+	IdempotencyKey string `json:"-"` // Sent as Idempotency-Key header (generated if empty)
 }
 
 // NotifAIResponse represents the NotifAI API response
@@ -165,8 +197,9 @@ type NotifAISummary struct {
 
 // NotifAIResult combines the NotifAI response with additional metadata
 type NotifAIResult struct {
-	Response  *NotifAIResponse
-	RateLimit *RateLimitInfo
+	Response       *NotifAIResponse
+	RateLimit      *RateLimitInfo
+	IdempotencyKey string // Key sent with the request, reuse it to make a resend idempotent
 }
 
 // ErrorResponse represents the API error response with nested structure
@@ -210,6 +243,11 @@ func (c *Client) SetToken(token string) {
 	c.Token = token
 }
 
+// SetRetryPolicy replaces the client's retry policy
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.RetryPolicy = policy
+}
+
 // SetRetryConfig updates the client's retry configuration
 func (c *Client) SetRetryConfig(maxRetries int, initialBackoff time.Duration) {
 	if maxRetries >= 0 {
@@ -220,89 +258,17 @@ func (c *Client) SetRetryConfig(maxRetries int, initialBackoff time.Duration) {
 	}
 }
 
-// isRetryableError determines if an error should trigger a retry
-func isRetryableError(err error, statusCode int) bool {
-	if err == nil {
-		return false
-	}
-
-	// Check if error implements APIError with IsRetryable method
-	if errors.IsRetryableError(err) {
-		return true
-	}
-
-	// Check error string for network errors (wrapped errors may not implement APIError)
-	errStr := err.Error()
-	if strings.Contains(errStr, "connection refused") ||
-		strings.Contains(errStr, "connection reset") ||
-		strings.Contains(errStr, "timeout") ||
-		strings.Contains(errStr, "temporary failure") ||
-		strings.Contains(errStr, "EOF") {
-		return true
-	}
-
-	// Retry on 429 (rate limit) - but with longer backoff
-	if statusCode == 429 {
-		return true
-	}
-
-	// Retry on 500, 502, 503, 504 (server errors)
-	if statusCode >= 500 && statusCode < 600 {
-		return true
-	}
-
-	return false
-}
-
-// calculateBackoff calculates the backoff duration for a given attempt using exponential backoff
-// If retryAfter is provided (from Retry-After header), it takes precedence for rate limit errors
-func (c *Client) calculateBackoff(attempt int, statusCode int, retryAfter string) time.Duration {
-	// If Retry-After header is provided for rate limit errors, use it
-	if statusCode == 429 && retryAfter != "" {
-		// Try to parse as seconds
-		if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
-			backoff := time.Duration(seconds) * time.Second
-			// Cap at 30 seconds
-			if backoff > 30*time.Second {
-				backoff = 30 * time.Second
-			}
-			return backoff
-		}
-	}
-
-	baseBackoff := c.InitialBackoff
-	if baseBackoff == 0 {
-		baseBackoff = DefaultInitialBackoff
-	}
-
-	// For rate limit errors, use longer backoff
-	if statusCode == 429 {
-		baseBackoff = 5 * time.Second
-	}
-
-	// Exponential backoff: 1s, 2s, 4s, 8s, etc.
-	backoff := baseBackoff * time.Duration(1<<uint(attempt))
-
-	// Cap at 30 seconds
-	if backoff > 30*time.Second {
-		backoff = 30 * time.Second
-	}
-
-	return backoff
-}
-
-// doRequestWithRetry performs an HTTP request with retry logic
-func (c *Client) doRequestWithRetry(ctx context.Context, req *http.Request) (*http.Response, error) {
-	maxRetries := c.MaxRetries
-	if maxRetries == 0 {
-		maxRetries = DefaultMaxRetries
-	}
-
-	var lastErr error
-	var lastStatusCode int
-	var lastRetryAfter string
+// doRequestWithRetry performs an HTTP request, retrying failed attempts as decided by the RetryPolicy
+// If gate is non-nil, retries are drawn from its shared budget and rate limit
+// pauses are shared with every other request using the same gate
+//
+// Once retries are exhausted, the last error response is returned as-is so
+// the caller can turn its body into a typed error
+func (c *Client) doRequestWithRetry(ctx context.Context, req *http.Request, gate *retryGate) (*http.Response, error) {
+	policy := c.retryPolicy()
+	start := time.Now()
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
+	for attempt := 0; ; attempt++ {
 		// Check for context cancellation before each attempt
 		select {
 		case <-ctx.Done():
@@ -310,6 +276,13 @@ func (c *Client) doRequestWithRetry(ctx context.Context, req *http.Request) (*ht
 		default:
 		}
 
+		// Wait out any rate limit pause triggered by another request in the batch
+		if gate != nil {
+			if err := gate.wait(ctx); err != nil {
+				return nil, errors.NewNetworkError("request cancelled during rate limit pause", err)
+			}
+		}
+
 		// Clone the request for retry (body needs to be reset)
 		reqClone := req.Clone(ctx)
 		if req.Body != nil {
@@ -331,18 +304,38 @@ func (c *Client) doRequestWithRetry(ctx context.Context, req *http.Request) (*ht
 			return resp, nil
 		}
 
-		// Store error and status code for retry decision
-		lastErr = err
-		if resp != nil {
-			lastStatusCode = resp.StatusCode
-			// Extract Retry-After header for rate limit responses
-			lastRetryAfter = resp.Header.Get("Retry-After")
+		outcome := RetryAttempt{
+			Attempt:  attempt,
+			Elapsed:  time.Since(start),
+			Response: resp,
+			Err:      err,
+		}
+		retry := policy.ShouldRetry(outcome)
+		rateLimited := resp != nil && resp.StatusCode == http.StatusTooManyRequests
+
+		var delay time.Duration
+		if retry || (gate != nil && rateLimited) {
+			delay = policy.NextDelay(outcome)
+		}
+
+		// Pause every request sharing the gate when the API rate limits us
+		if gate != nil && rateLimited {
+			gate.pause(delay)
+		}
+
+		// Stop early once the shared retry budget is spent
+		if retry && gate != nil && !gate.take() {
+			retry = false
 		}
 
-		// Check if error is retryable
-		if !isRetryableError(lastErr, lastStatusCode) {
-			// Not retryable, return the response/error immediately
-			return resp, lastErr
+		if !retry {
+			if err != nil {
+				if attempt > 0 {
+					return nil, errors.NewNetworkError(fmt.Sprintf("request failed after %d retries", attempt), err)
+				}
+				return nil, errors.NewNetworkError("request failed", err)
+			}
+			return resp, nil
 		}
 
 		// Will retry - close response body before retrying
@@ -350,31 +343,24 @@ func (c *Client) doRequestWithRetry(ctx context.Context, req *http.Request) (*ht
 			resp.Body.Close()
 		}
 
-		// Don't sleep after the last attempt
-		if attempt < maxRetries {
-			backoff := c.calculateBackoff(attempt, lastStatusCode, lastRetryAfter)
-			// Use select to respect context cancellation during sleep
-			select {
-			case <-ctx.Done():
-				return nil, errors.NewNetworkError("request cancelled during retry backoff", ctx.Err())
-			case <-time.After(backoff):
-				// Continue with next retry attempt
-			}
+		// Use select to respect context cancellation during sleep
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewNetworkError("request cancelled during retry backoff", ctx.Err())
+		case <-time.After(delay):
+			// Continue with next retry attempt
 		}
 	}
-
-	// All retries exhausted
-	if lastErr != nil {
-		return nil, errors.NewNetworkError(fmt.Sprintf("request failed after %d retries", maxRetries), lastErr)
-	}
-
-	// This shouldn't happen, but just in case
-	return nil, errors.NewServerErrorWithStatus(fmt.Sprintf("request failed after %d retries", maxRetries), lastStatusCode)
 }
 
 // Send sends a notification via the Pincho API
 // Returns SendResult with response details and rate limit info, or error if failed
 func (c *Client) Send(ctx context.Context, opts *SendOptions) (*SendResult, error) {
+	return c.send(ctx, opts, nil)
+}
+
+// send implements Send, optionally sharing retries and rate limit pauses through gate
+func (c *Client) send(ctx context.Context, opts *SendOptions, gate *retryGate) (*SendResult, error) {
 	// Validate required fields
 	if opts.Title == "" {
 		return nil, errors.NewValidationError("title is required")
@@ -384,140 +370,22 @@ func (c *Client) Send(ctx context.Context, opts *SendOptions) (*SendResult, erro
 		return nil, errors.NewAuthenticationError("token is required")
 	}
 
-	// Normalize and validate tags
-	if len(opts.Tags) > 0 {
-		normalizedTags, err := validation.NormalizeAndValidateTags(opts.Tags)
-		if err != nil {
-			return nil, errors.NewValidationErrorWithDetails(fmt.Sprintf("tag validation failed: %v", err), "tags", "invalid_tags")
-		}
-		opts.Tags = normalizedTags
-	}
-
-	// Handle encryption if password provided
-	finalMessage := opts.Message
-	var ivHex string
-
-	if opts.EncryptionPassword != "" {
-		// Only encrypt if message is not empty
-		if opts.Message != "" {
-			ivBytes, ivHexStr, err := crypto.GenerateIV()
-			if err != nil {
-				return nil, errors.NewNetworkError("failed to generate IV", err)
-			}
-
-			encrypted, err := crypto.EncryptMessage(opts.Message, opts.EncryptionPassword, ivBytes)
-			if err != nil {
-				return nil, errors.NewNetworkError("failed to encrypt message", err)
-			}
-
-			finalMessage = encrypted
-			ivHex = ivHexStr
-		}
-	}
-
-	// Build request with encrypted message if applicable
-	requestOpts := *opts
-	requestOpts.Message = finalMessage
-	requestOpts.IV = ivHex
-	requestOpts.EncryptionPassword = "" // Don't send password to API
-
-	// Build request body
-	jsonData, err := json.Marshal(requestOpts)
-	if err != nil {
-		return nil, errors.NewNetworkError("failed to marshal request", err)
-	}
-
-	// Create HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, "POST", c.APIURL, bytes.NewReader(jsonData))
+	// One key per logical request, reused by every retry attempt
+	idempotencyKey, explicitKey, err := c.resolveIdempotencyKey(EndpointSend, opts.IdempotencyKey)
 	if err != nil {
-		return nil, errors.NewNetworkError("failed to create request", err)
-	}
-
-	// Set GetBody for retry support
-	req.GetBody = func() (io.ReadCloser, error) {
-		return io.NopCloser(bytes.NewReader(jsonData)), nil
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
-	req.Header.Set("User-Agent", c.UserAgent)
-
-	// Send request with retry logic
-	resp, err := c.doRequestWithRetry(ctx, req)
+	prepared, err := c.prepareSend(opts, idempotencyKey, explicitKey)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// Extract rate limit headers
-	rateLimit := &RateLimitInfo{
-		Limit:     resp.Header.Get("RateLimit-Limit"),
-		Remaining: resp.Header.Get("RateLimit-Remaining"),
-		Reset:     resp.Header.Get("RateLimit-Reset"),
-	}
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	result, err := c.execute(ctx, prepared, gate)
 	if err != nil {
-		return nil, errors.NewNetworkError("failed to read response", err)
-	}
-
-	// Handle error status codes
-	if resp.StatusCode >= 400 {
-		// Try to parse nested error response
-		var errorResp ErrorResponse
-		if err := json.Unmarshal(bodyBytes, &errorResp); err == nil && errorResp.Error.Message != "" {
-			// Return typed error based on status code
-			switch resp.StatusCode {
-			case 400, 404:
-				return nil, errors.NewValidationErrorWithDetails(errorResp.Error.Message, errorResp.Error.Param, errorResp.Error.Code)
-			case 401, 403:
-				return nil, errors.NewAuthenticationErrorWithStatus(errorResp.Error.Message, resp.StatusCode)
-			case 429:
-				retryAfter := 0
-				if retryAfterStr := resp.Header.Get("Retry-After"); retryAfterStr != "" {
-					retryAfter, _ = strconv.Atoi(retryAfterStr)
-				}
-				return nil, errors.NewRateLimitErrorWithRetryAfter(errorResp.Error.Message, retryAfter)
-			default:
-				if resp.StatusCode >= 500 {
-					return nil, errors.NewServerErrorWithStatus(errorResp.Error.Message, resp.StatusCode)
-				}
-				return nil, errors.NewValidationErrorWithDetails(errorResp.Error.Message, errorResp.Error.Param, errorResp.Error.Code)
-			}
-		}
-
-		// Fallback to generic error message if parsing fails
-		errorMsg := string(bodyBytes)
-		switch resp.StatusCode {
-		case 400, 404:
-			return nil, errors.NewValidationError(fmt.Sprintf("validation error: %s", errorMsg))
-		case 401, 403:
-			return nil, errors.NewAuthenticationErrorWithStatus(fmt.Sprintf("authentication error: %s", errorMsg), resp.StatusCode)
-		case 429:
-			retryAfter := 0
-			if retryAfterStr := resp.Header.Get("Retry-After"); retryAfterStr != "" {
-				retryAfter, _ = strconv.Atoi(retryAfterStr)
-			}
-			return nil, errors.NewRateLimitErrorWithRetryAfter(fmt.Sprintf("rate limit exceeded: %s", errorMsg), retryAfter)
-		default:
-			if resp.StatusCode >= 500 {
-				return nil, errors.NewServerErrorWithStatus(fmt.Sprintf("server error: %s", errorMsg), resp.StatusCode)
-			}
-			return nil, errors.NewValidationError(fmt.Sprintf("API error (%d): %s", resp.StatusCode, errorMsg))
-		}
-	}
-
-	// Parse success response
-	var apiResp SendResponse
-	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		return nil, errors.NewNetworkError("failed to parse response", err)
+		return nil, err
 	}
-
-	return &SendResult{
-		Response:  &apiResp,
-		RateLimit: rateLimit,
-	}, nil
+	return result.SendResult()
 }
 
 // NotifAI sends a text-to-notification request via the Pincho NotifAI API
@@ -532,117 +400,20 @@ func (c *Client) NotifAI(ctx context.Context, opts *NotifAIOptions) (*NotifAIRes
 		return nil, errors.NewAuthenticationError("token is required")
 	}
 
-	// Validate text length
-	if len(opts.Text) < 5 {
-		return nil, errors.NewValidationError("text must be at least 5 characters long")
-	}
-	if len(opts.Text) > 2500 {
-		return nil, errors.NewValidationError("text must be at most 2500 characters long")
-	}
-
-	// Build request body
-	jsonData, err := json.Marshal(opts)
-	if err != nil {
-		return nil, errors.NewNetworkError("failed to marshal request", err)
-	}
-
-	// Determine NotifAI URL based on configured API URL
-	notifaiURL := DefaultNotifAIURL
-	if c.APIURL != "" && c.APIURL != DefaultAPIURL {
-		// If custom API URL is set, derive NotifAI URL from it
-		// Replace /send with /notifai
-		notifaiURL = strings.Replace(c.APIURL, "/send", "/notifai", 1)
-	}
-
-	// Create HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, "POST", notifaiURL, bytes.NewReader(jsonData))
+	// One key per logical request, reused by every retry attempt
+	idempotencyKey, explicitKey, err := c.resolveIdempotencyKey(EndpointNotifAI, opts.IdempotencyKey)
 	if err != nil {
-		return nil, errors.NewNetworkError("failed to create request", err)
-	}
-
-	// Set GetBody for retry support
-	req.GetBody = func() (io.ReadCloser, error) {
-		return io.NopCloser(bytes.NewReader(jsonData)), nil
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
-	req.Header.Set("User-Agent", c.UserAgent)
-
-	// Send request with retry logic
-	resp, err := c.doRequestWithRetry(ctx, req)
+	prepared, err := c.prepareNotifAI(opts, idempotencyKey, explicitKey)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// Extract rate limit headers
-	rateLimit := &RateLimitInfo{
-		Limit:     resp.Header.Get("RateLimit-Limit"),
-		Remaining: resp.Header.Get("RateLimit-Remaining"),
-		Reset:     resp.Header.Get("RateLimit-Reset"),
-	}
 
-	// Read response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	result, err := c.execute(ctx, prepared, nil)
 	if err != nil {
-		return nil, errors.NewNetworkError("failed to read response", err)
-	}
-
-	// Handle error status codes
-	if resp.StatusCode >= 400 {
-		// Try to parse nested error response
-		var errorResp ErrorResponse
-		if err := json.Unmarshal(bodyBytes, &errorResp); err == nil && errorResp.Error.Message != "" {
-			// Return typed error based on status code
-			switch resp.StatusCode {
-			case 400, 404:
-				return nil, errors.NewValidationErrorWithDetails(errorResp.Error.Message, errorResp.Error.Param, errorResp.Error.Code)
-			case 401, 403:
-				return nil, errors.NewAuthenticationErrorWithStatus(errorResp.Error.Message, resp.StatusCode)
-			case 429:
-				retryAfter := 0
-				if retryAfterStr := resp.Header.Get("Retry-After"); retryAfterStr != "" {
-					retryAfter, _ = strconv.Atoi(retryAfterStr)
-				}
-				return nil, errors.NewRateLimitErrorWithRetryAfter(errorResp.Error.Message, retryAfter)
-			default:
-				if resp.StatusCode >= 500 {
-					return nil, errors.NewServerErrorWithStatus(errorResp.Error.Message, resp.StatusCode)
-				}
-				return nil, errors.NewValidationErrorWithDetails(errorResp.Error.Message, errorResp.Error.Param, errorResp.Error.Code)
-			}
-		}
-
-		// Fallback to generic error message if parsing fails
-		errorMsg := string(bodyBytes)
-		switch resp.StatusCode {
-		case 400, 404:
-			return nil, errors.NewValidationError(fmt.Sprintf("validation error: %s", errorMsg))
-		case 401, 403:
-			return nil, errors.NewAuthenticationErrorWithStatus(fmt.Sprintf("authentication error: %s", errorMsg), resp.StatusCode)
-		case 429:
-			retryAfter := 0
-			if retryAfterStr := resp.Header.Get("Retry-After"); retryAfterStr != "" {
-				retryAfter, _ = strconv.Atoi(retryAfterStr)
-			}
-			return nil, errors.NewRateLimitErrorWithRetryAfter(fmt.Sprintf("rate limit exceeded: %s", errorMsg), retryAfter)
-		default:
-			if resp.StatusCode >= 500 {
-				return nil, errors.NewServerErrorWithStatus(fmt.Sprintf("server error: %s", errorMsg), resp.StatusCode)
-			}
-			return nil, errors.NewValidationError(fmt.Sprintf("API error (%d): %s", resp.StatusCode, errorMsg))
-		}
-	}
-
-	// Parse success response
-	var apiResp NotifAIResponse
-	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		return nil, errors.NewNetworkError("failed to parse response", err)
+		return nil, err
 	}
-
-	return &NotifAIResult{
-		Response:  &apiResp,
-		RateLimit: rateLimit,
-	}, nil
+	return result.NotifAIResult()
 }
@@ -1,12 +1,14 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -239,6 +241,7 @@ func TestClient_Send_HTTPErrors(t *testing.T) {
 			client := New()
 			client.APIURL = server.URL
 			client.SetToken("token")
+			client.RetryPolicy = fastRetryPolicy()
 
 			opts := &SendOptions{
 				Title:   "Test",
@@ -309,61 +312,36 @@ func TestClient_Send_WithEncryption(t *testing.T) {
 	if strings.Contains(receivedBody, "\"token\":") {
 		t.Error("expected token to be in Authorization header, not in request body")
 	}
+
+	// Verify the password is neither sent nor in the result printed by --json
+	if strings.Contains(receivedBody, "test-password") {
+		t.Error("expected encryption password not to be sent to the API")
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("failed to marshal result: %v", err)
+	}
+	if strings.Contains(string(resultJSON), "test-password") {
+		t.Errorf("expected encryption password not to be in the result, got: %s", resultJSON)
+	}
 }
 
-func TestClient_CalculateBackoff_WithRetryAfter(t *testing.T) {
-	client := New()
+func TestSendOptions_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
 
-	tests := []struct {
-		name            string
-		attempt         int
-		statusCode      int
-		retryAfter      string
-		expectedBackoff time.Duration
-	}{
-		{
-			name:            "rate limit with Retry-After header",
-			attempt:         0,
-			statusCode:      429,
-			retryAfter:      "5",
-			expectedBackoff: 5 * time.Second,
-		},
-		{
-			name:            "rate limit with Retry-After header - capped at 30s",
-			attempt:         0,
-			statusCode:      429,
-			retryAfter:      "60",
-			expectedBackoff: 30 * time.Second,
-		},
-		{
-			name:            "rate limit without Retry-After header",
-			attempt:         0,
-			statusCode:      429,
-			retryAfter:      "",
-			expectedBackoff: 5 * time.Second, // Default for 429
-		},
-		{
-			name:            "non-rate-limit error ignores Retry-After",
-			attempt:         0,
-			statusCode:      500,
-			retryAfter:      "10",
-			expectedBackoff: 1 * time.Second, // Uses default backoff
-		},
-		{
-			name:            "invalid Retry-After uses default",
-			attempt:         0,
-			statusCode:      429,
-			retryAfter:      "invalid",
-			expectedBackoff: 5 * time.Second,
-		},
-	}
+	logger.Info("sending", "options", SendOptions{
+		Title:              "Secure Alert",
+		Message:            "Secret message",
+		Type:               "secure",
+		EncryptionPassword: "test-password",
+	})
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			backoff := client.calculateBackoff(tt.attempt, tt.statusCode, tt.retryAfter)
-			if backoff != tt.expectedBackoff {
-				t.Errorf("expected backoff %v, got %v", tt.expectedBackoff, backoff)
-			}
-		})
+	output := buf.String()
+	if strings.Contains(output, "test-password") || strings.Contains(output, "Secret message") {
+		t.Errorf("expected password and message not to be logged, got: %s", output)
+	}
+	if !strings.Contains(output, "options.encrypted=true") || !strings.Contains(output, "options.type=secure") {
+		t.Errorf("expected options summary in log, got: %s", output)
 	}
 }
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultEndpoints maps endpoint names to their paths relative to the base URL
+var DefaultEndpoints = map[string]string{
+	EndpointSend:    "send",
+	EndpointNotifAI: "notifai",
+}
+
+// SetBaseURL sets the base URL that endpoint paths are resolved against,
+// e.g. "https://gateway.internal/pincho/v1/"
+func (c *Client) SetBaseURL(baseURL string) {
+	c.BaseURL = baseURL
+}
+
+// SetEndpoint overrides the path or absolute URL of a named endpoint
+// A relative path is resolved against the base URL
+func (c *Client) SetEndpoint(name, pathOrURL string) {
+	if c.Endpoints == nil {
+		c.Endpoints = make(map[string]string)
+	}
+	c.Endpoints[name] = pathOrURL
+}
+
+// EndpointURL returns the full URL of a named endpoint
+//
+// Resolution order:
+//  1. An absolute URL set with SetEndpoint is used as-is
+//  2. The endpoint path (override or default) is joined to BaseURL
+//  3. Without a BaseURL, the legacy APIURL is the send endpoint URL and,
+//     with a trailing /send removed, the base for all other endpoints
+//  4. Without either, the path is joined to DefaultBaseURL
+func (c *Client) EndpointURL(name string) (string, error) {
+	endpointPath, overridden := c.Endpoints[name]
+	if overridden {
+		if u, err := url.Parse(endpointPath); err == nil && u.IsAbs() {
+			return endpointPath, nil
+		}
+	} else if defaultPath, ok := DefaultEndpoints[name]; ok {
+		endpointPath = defaultPath
+	} else {
+		endpointPath = name
+	}
+
+	base := c.BaseURL
+	if base == "" {
+		switch {
+		case c.APIURL == "":
+			base = DefaultBaseURL
+		case name == EndpointSend && !overridden:
+			return c.APIURL, nil
+		default:
+			base = legacyBaseURL(c.APIURL)
+		}
+	}
+
+	u, err := url.Parse(base)
+	if err != nil || !u.IsAbs() {
+		return "", fmt.Errorf("invalid base URL %q for the %s endpoint", base, name)
+	}
+	return u.JoinPath(endpointPath).String(), nil
+}
+
+// legacyBaseURL derives the base URL from an APIURL pointing at the send endpoint
+// If the URL does not end in /send it is used as the base unchanged
+func legacyBaseURL(apiURL string) string {
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return apiURL
+	}
+
+	trimmed := strings.TrimSuffix(u.Path, "/")
+	if path.Base(trimmed) != DefaultEndpoints[EndpointSend] {
+		return apiURL
+	}
+
+	u.Path = path.Dir(trimmed)
+	u.RawPath = ""
+	return u.String()
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndpointURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		apiURL    string
+		baseURL   string
+		endpoints map[string]string
+		wantSend  string
+		wantAI    string
+	}{
+		{
+			name:     "defaults",
+			apiURL:   DefaultAPIURL,
+			wantSend: "https://api.pincho.app/send",
+			wantAI:   "https://api.pincho.app/notifai",
+		},
+		{
+			name:     "neither base url nor api_url",
+			wantSend: "https://api.pincho.app/send",
+			wantAI:   "https://api.pincho.app/notifai",
+		},
+		{
+			name:     "legacy api_url with /send",
+			apiURL:   "https://proxy.example.com/pincho/send",
+			wantSend: "https://proxy.example.com/pincho/send",
+			wantAI:   "https://proxy.example.com/pincho/notifai",
+		},
+		{
+			name:     "legacy api_url containing send twice",
+			apiURL:   "https://send.example.com/send/send",
+			wantSend: "https://send.example.com/send/send",
+			wantAI:   "https://send.example.com/send/notifai",
+		},
+		{
+			name:     "legacy api_url without /send",
+			apiURL:   "https://proxy.example.com/notify",
+			wantSend: "https://proxy.example.com/notify",
+			wantAI:   "https://proxy.example.com/notify/notifai",
+		},
+		{
+			name:     "base url with trailing slash",
+			apiURL:   DefaultAPIURL,
+			baseURL:  "https://gateway.internal/pincho/v1/",
+			wantSend: "https://gateway.internal/pincho/v1/send",
+			wantAI:   "https://gateway.internal/pincho/v1/notifai",
+		},
+		{
+			name:      "base url with relative override",
+			baseURL:   "https://gateway.internal/pincho/v1",
+			endpoints: map[string]string{EndpointNotifAI: "ai/notifai"},
+			wantSend:  "https://gateway.internal/pincho/v1/send",
+			wantAI:    "https://gateway.internal/pincho/v1/ai/notifai",
+		},
+		{
+			name:      "absolute override",
+			apiURL:    DefaultAPIURL,
+			endpoints: map[string]string{EndpointNotifAI: "https://ai.internal/notifai"},
+			wantSend:  "https://api.pincho.app/send",
+			wantAI:    "https://ai.internal/notifai",
+		},
+		{
+			name:      "relative send override with legacy api_url",
+			apiURL:    "https://proxy.example.com/pincho/send",
+			endpoints: map[string]string{EndpointSend: "v2/send"},
+			wantSend:  "https://proxy.example.com/pincho/v2/send",
+			wantAI:    "https://proxy.example.com/pincho/notifai",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := New()
+			client.APIURL = tt.apiURL
+			client.SetBaseURL(tt.baseURL)
+			for name, pathOrURL := range tt.endpoints {
+				client.SetEndpoint(name, pathOrURL)
+			}
+
+			if got, err := client.EndpointURL(EndpointSend); err != nil || got != tt.wantSend {
+				t.Errorf("send URL = %q, %v; want %q", got, err, tt.wantSend)
+			}
+			if got, err := client.EndpointURL(EndpointNotifAI); err != nil || got != tt.wantAI {
+				t.Errorf("notifai URL = %q, %v; want %q", got, err, tt.wantAI)
+			}
+		})
+	}
+}
+
+func TestLegacyBaseURL_Default(t *testing.T) {
+	if got := legacyBaseURL(DefaultAPIURL); got != DefaultBaseURL {
+		t.Errorf("legacyBaseURL(%q) = %q, want DefaultBaseURL %q", DefaultAPIURL, got, DefaultBaseURL)
+	}
+}
+
+func TestEndpointURL_InvalidBase(t *testing.T) {
+	client := New()
+	client.SetBaseURL("not a url")
+
+	if _, err := client.EndpointURL(EndpointSend); err == nil {
+		t.Error("expected error for relative base URL")
+	}
+}
+
+func TestClient_NotifAI_UsesGatewayBaseURL(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := NewWithOptions(
+		WithBaseURL(server.URL+"/pincho/v1/"),
+		WithToken("test-token"),
+	)
+
+	if _, err := client.NotifAI(context.Background(), &NotifAIOptions{Text: "deploy finished"}); err != nil {
+		t.Fatalf("NotifAI failed: %v", err)
+	}
+	if gotPath != "/pincho/v1/notifai" {
+		t.Errorf("expected request to /pincho/v1/notifai, got %s", gotPath)
+	}
+
+	if _, err := client.Send(context.Background(), &SendOptions{Title: "Test"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotPath != "/pincho/v1/send" {
+		t.Errorf("expected request to /pincho/v1/send, got %s", gotPath)
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotencyFileName is the name of the state file recording sent idempotency keys
+	IdempotencyFileName = "idempotency.json"
+
+	// DefaultIdempotencyWindow is how long a sent idempotency key is refused locally
+	DefaultIdempotencyWindow = 24 * time.Hour
+)
+
+// IdempotencyStore records idempotency keys of successfully sent requests so
+// that a duplicate can be refused locally, even if the API ignores the header.
+// *state.KeyLog implements this interface.
+type IdempotencyStore interface {
+	// Lookup reports when key was last recorded, if that was within window
+	Lookup(key string, window time.Duration) (time.Time, bool, error)
+
+	// Record stores key as sent at the given time, keeping it for at least retention
+	Record(key string, at time.Time, retention time.Duration) error
+}
+
+// NewIdempotencyKey generates a random idempotency key
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms, fall back to a time based key
+		return "pincho-" + time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// resolveIdempotencyKey returns the key to send for a logical request and whether
+// the caller supplied it. Caller-supplied keys are checked against the local
+// store and refused if they were already sent within the idempotency window.
+func (c *Client) resolveIdempotencyKey(endpoint, key string) (string, bool, error) {
+	if key == "" {
+		return NewIdempotencyKey(), false, nil
+	}
+
+	if c.IdempotencyStore != nil {
+		sentAt, found, err := c.IdempotencyStore.Lookup(c.idempotencyStoreKey(endpoint, key), c.idempotencyWindow())
+		if err == nil && found {
+			return "", true, errors.NewDuplicateError(key, sentAt)
+		}
+	}
+
+	return key, true, nil
+}
+
+// recordIdempotencyKey stores a caller-supplied key after a successful request
+// Generated keys are never reused, so they are not recorded
+func (c *Client) recordIdempotencyKey(endpoint, key string, explicit bool) {
+	if !explicit || c.IdempotencyStore == nil {
+		return
+	}
+	// Recording is best effort and never fails a request that was delivered
+	_ = c.IdempotencyStore.Record(c.idempotencyStoreKey(endpoint, key), time.Now(), c.idempotencyWindow())
+}
+
+// idempotencyStoreKey scopes a caller-supplied key to the token and the
+// resolved endpoint URL, like the API does, so the same key sent with
+// another token or to a dev server is not refused
+func (c *Client) idempotencyStoreKey(endpoint, key string) string {
+	endpointURL, err := c.EndpointURL(endpoint)
+	if err != nil {
+		endpointURL = endpoint
+	}
+	return TokenFingerprint(c.Token) + " " + endpointURL + " " + key
+}
+
+// idempotencyWindow returns the configured window or the default
+func (c *Client) idempotencyWindow() time.Duration {
+	if c.IdempotencyWindow > 0 {
+		return c.IdempotencyWindow
+	}
+	return DefaultIdempotencyWindow
+}
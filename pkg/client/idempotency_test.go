@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+func TestNewIdempotencyKey(t *testing.T) {
+	a := NewIdempotencyKey()
+	b := NewIdempotencyKey()
+
+	if len(a) != 32 {
+		t.Errorf("expected 32 character key, got %d (%s)", len(a), a)
+	}
+	if a == b {
+		t.Error("expected generated keys to differ")
+	}
+}
+
+func TestClient_Send_IdempotencyKeyStableAcrossRetries(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		mu.Unlock()
+
+		// Fail the first attempt so the request is retried
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(503)
+			_, _ = w.Write([]byte(`{"status": "error"}`))
+			return
+		}
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	result, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(keys))
+	}
+	if keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected the same non-empty key on every attempt, got %q and %q", keys[0], keys[1])
+	}
+	if result.IdempotencyKey != keys[0] {
+		t.Errorf("expected result to report key %q, got %q", keys[0], result.IdempotencyKey)
+	}
+}
+
+func TestClient_Send_RefusesDuplicateIdempotencyKey(t *testing.T) {
+	var requests int32
+	var receivedKey atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		receivedKey.Store(r.Header.Get(IdempotencyKeyHeader))
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.IdempotencyStore = state.NewKeyLog(filepath.Join(t.TempDir(), IdempotencyFileName))
+
+	opts := func() *SendOptions {
+		return &SendOptions{Title: "Deploy", IdempotencyKey: "deploy-1.2.3"}
+	}
+
+	if _, err := client.Send(context.Background(), opts()); err != nil {
+		t.Fatalf("first send failed: %v", err)
+	}
+	if got := receivedKey.Load(); got != "deploy-1.2.3" {
+		t.Errorf("expected caller-supplied key to be sent, got %v", got)
+	}
+
+	_, err := client.Send(context.Background(), opts())
+	dupErr, ok := err.(*errors.DuplicateError)
+	if !ok {
+		t.Fatalf("expected *DuplicateError, got %T: %v", err, err)
+	}
+	if dupErr.Key != "deploy-1.2.3" || dupErr.SentAt.IsZero() {
+		t.Errorf("unexpected duplicate error: %+v", dupErr)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request to reach the API, got %d", got)
+	}
+
+	// Keys are scoped per endpoint
+	if _, err := client.NotifAI(context.Background(), &NotifAIOptions{Text: "deploy finished", IdempotencyKey: "deploy-1.2.3"}); err != nil {
+		t.Errorf("expected NotifAI with the same key to be allowed, got: %v", err)
+	}
+}
+
+func TestClient_Send_IdempotencyKeyScopedToTokenAndURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer other.Close()
+
+	store := state.NewKeyLog(filepath.Join(t.TempDir(), IdempotencyFileName))
+	send := func(baseURL, token string) error {
+		c := NewWithOptions(WithBaseURL(baseURL), WithToken(token), WithIdempotencyStore(store, time.Hour))
+		_, err := c.Send(context.Background(), &SendOptions{Title: "Deploy", IdempotencyKey: "deploy-1.2.3"})
+		return err
+	}
+
+	if err := send(server.URL, "token-a"); err != nil {
+		t.Fatalf("first send failed: %v", err)
+	}
+	if err := send(server.URL, "token-b"); err != nil {
+		t.Errorf("expected the same key with another token to be allowed, got: %v", err)
+	}
+	if err := send(other.URL, "token-a"); err != nil {
+		t.Errorf("expected the same key against another base URL to be allowed, got: %v", err)
+	}
+	if _, ok := send(server.URL, "token-a").(*errors.DuplicateError); !ok {
+		t.Error("expected the same key, token and URL to be refused")
+	}
+}
+
+func TestClient_Send_GeneratedKeysNotRecorded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), IdempotencyFileName)
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.IdempotencyStore = state.NewKeyLog(path)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Send(context.Background(), &SendOptions{Title: "Test"}); err != nil {
+			t.Fatalf("send %d failed: %v", i, err)
+		}
+	}
+
+	var recorded map[string]any
+	if found, _ := state.ReadJSON(path, &recorded); found && len(recorded) > 0 {
+		t.Errorf("expected generated keys not to be recorded, got %v", recorded)
+	}
+}
+
+func TestClient_Send_FailedSendNotRecorded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		_, _ = w.Write([]byte(`{"status": "error"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.IdempotencyStore = state.NewKeyLog(filepath.Join(t.TempDir(), IdempotencyFileName))
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Send(context.Background(), &SendOptions{Title: "Test", IdempotencyKey: "retry-me"})
+		if _, ok := err.(*errors.ValidationError); !ok {
+			t.Fatalf("send %d: expected *ValidationError, got %T: %v", i, err, err)
+		}
+	}
+}
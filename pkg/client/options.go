@@ -0,0 +1,176 @@
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+// Option configures a Client built by NewWithOptions or NewFromConfig
+type Option func(*Client)
+
+// WithAPIURL sets the API endpoint URL (ignored if empty)
+func WithAPIURL(apiURL string) Option {
+	return func(c *Client) {
+		if apiURL != "" {
+			c.APIURL = apiURL
+		}
+	}
+}
+
+// WithBaseURL sets the base URL that endpoint paths are resolved against (ignored if empty)
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if baseURL != "" {
+			c.SetBaseURL(baseURL)
+		}
+	}
+}
+
+// WithEndpoint overrides the path or absolute URL of a named endpoint
+func WithEndpoint(name, pathOrURL string) Option {
+	return func(c *Client) {
+		c.SetEndpoint(name, pathOrURL)
+	}
+}
+
+// WithToken sets the API token for authentication
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.SetToken(token)
+	}
+}
+
+// WithTimeout sets the HTTP timeout (ignored if not positive)
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.SetTimeout(timeout)
+	}
+}
+
+// WithRetries sets the retry configuration with the default retry policy
+// Unlike SetRetryConfig, a maxRetries of 0 disables retries
+func WithRetries(maxRetries int, initialBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.SetRetryConfig(maxRetries, initialBackoff)
+		if maxRetries >= 0 {
+			c.SetRetryPolicy(NewDefaultRetryPolicy(c.MaxRetries, c.InitialBackoff))
+		}
+	}
+}
+
+// WithRetryPolicy sets a custom retry policy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.SetRetryPolicy(policy)
+	}
+}
+
+// WithUserAgent sets the User-Agent header value (ignored if empty)
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		if userAgent != "" {
+			c.UserAgent = userAgent
+		}
+	}
+}
+
+// WithHTTPClient replaces the underlying HTTP client
+// The HTTP client's own timeout is kept unless WithTimeout is applied after it
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.HTTPClient = httpClient
+		}
+	}
+}
+
+// WithTransport sets the HTTP transport, e.g. for proxies or custom TLS settings
+// The HTTP client is copied, so one passed to WithHTTPClient is left unchanged.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		httpClient := *c.HTTPClient
+		httpClient.Transport = transport
+		c.HTTPClient = &httpClient
+	}
+}
+
+// WithQuotaStore enables rate limit quota persistence
+func WithQuotaStore(store QuotaStore) Option {
+	return func(c *Client) {
+		c.QuotaStore = store
+	}
+}
+
+// WithIdempotencyStore enables local refusal of resent idempotency keys
+// A window of zero uses DefaultIdempotencyWindow
+func WithIdempotencyStore(store IdempotencyStore, window time.Duration) Option {
+	return func(c *Client) {
+		c.IdempotencyStore = store
+		c.IdempotencyWindow = window
+	}
+}
+
+// WithCircuitBreaker enables the circuit breaker
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(c *Client) {
+		c.Breaker = breaker
+	}
+}
+
+// NewWithOptions creates a new Pincho client with default settings, then applies opts in order
+func NewWithOptions(opts ...Option) *Client {
+	c := New()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewFromConfig creates a client configured the same way as the CLI
+//
+// Settings left at their zero value in cfg keep the client defaults; a
+// MaxRetries of 0 disables retries only if MaxRetriesSet is true. Quota
+// tracking, idempotency key tracking and the circuit breaker persist their
+// state in the config directory (~/.pincho), shared with the CLI; each is
+// skipped if the directory cannot be resolved. A negative BreakerThreshold
+// disables the circuit breaker. opts are applied last and take precedence.
+func NewFromConfig(cfg *config.Config, opts ...Option) *Client {
+	c := New()
+
+	if cfg != nil {
+		WithAPIURL(cfg.APIURL)(c)
+		WithBaseURL(cfg.BaseURL)(c)
+		for name, pathOrURL := range cfg.Endpoints {
+			WithEndpoint(name, pathOrURL)(c)
+		}
+		WithToken(cfg.Token)(c)
+		WithTimeout(time.Duration(cfg.Timeout) * time.Second)(c)
+		if cfg.MaxRetriesSet || cfg.MaxRetries > 0 {
+			WithRetries(cfg.MaxRetries, DefaultInitialBackoff)(c)
+		}
+
+		if path, err := state.Path(QuotaFileName); err == nil {
+			c.QuotaStore = NewFileQuotaStore(path)
+		}
+		if path, err := state.Path(IdempotencyFileName); err == nil {
+			c.IdempotencyStore = state.NewKeyLog(path)
+		}
+		c.IdempotencyWindow = cfg.IdempotencyWindow
+
+		if cfg.BreakerThreshold >= 0 {
+			var store BreakerStore
+			if path, err := state.Path(BreakerFileName); err == nil {
+				store = NewFileBreakerStore(path)
+			}
+			c.Breaker = NewCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown, store)
+		}
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
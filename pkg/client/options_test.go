@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+)
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewWithOptions(t *testing.T) {
+	var viaTransport int32
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&viaTransport, 1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	var userAgent, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := NewWithOptions(
+		WithAPIURL(server.URL),
+		WithToken("test-token"),
+		WithTimeout(5*time.Second),
+		WithRetries(0, 0),
+		WithUserAgent("my-service/2.0"),
+		WithTransport(transport),
+	)
+
+	if client.Timeout != 5*time.Second || client.HTTPClient.Timeout != 5*time.Second {
+		t.Errorf("expected 5s timeout, got %v / %v", client.Timeout, client.HTTPClient.Timeout)
+	}
+	if client.MaxRetries != 0 || client.retryPolicy().ShouldRetry(RetryAttempt{Response: responseWithHeader(503, "")}) {
+		t.Error("expected WithRetries(0, ...) to disable retries")
+	}
+
+	if _, err := client.Send(context.Background(), &SendOptions{Title: "Test"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if userAgent != "my-service/2.0" {
+		t.Errorf("expected custom User-Agent, got %q", userAgent)
+	}
+	if auth != "Bearer test-token" {
+		t.Errorf("expected bearer token, got %q", auth)
+	}
+	if atomic.LoadInt32(&viaTransport) != 1 {
+		t.Errorf("expected request to go through the custom transport")
+	}
+}
+
+func TestWithTransport_CopiesHTTPClient(t *testing.T) {
+	shared := &http.Client{Timeout: 7 * time.Second}
+	transport := roundTripperFunc(http.DefaultTransport.RoundTrip)
+
+	client := NewWithOptions(WithHTTPClient(shared), WithTransport(transport))
+
+	if shared.Transport != nil {
+		t.Error("expected the HTTP client passed to WithHTTPClient to be left unchanged")
+	}
+	if client.HTTPClient == shared || client.HTTPClient.Transport == nil || client.HTTPClient.Timeout != 7*time.Second {
+		t.Errorf("expected a copy of the HTTP client with the transport, got %+v", client.HTTPClient)
+	}
+}
+
+func TestNewWithOptions_Defaults(t *testing.T) {
+	client := NewWithOptions()
+
+	if client.APIURL != DefaultAPIURL {
+		t.Errorf("expected default API URL, got %s", client.APIURL)
+	}
+	if client.Timeout != DefaultTimeout || client.MaxRetries != DefaultMaxRetries {
+		t.Errorf("expected defaults, got timeout %v, retries %d", client.Timeout, client.MaxRetries)
+	}
+	if client.RetryPolicy != nil || client.Breaker != nil || client.QuotaStore != nil {
+		t.Error("expected no policy, breaker or stores by default")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg := &config.Config{
+		Token:             "config-token",
+		APIURL:            "https://gateway.example.com/send",
+		Timeout:           10,
+		MaxRetries:        5,
+		IdempotencyWindow: time.Hour,
+		BreakerThreshold:  2,
+		BreakerCooldown:   time.Minute,
+	}
+
+	client := NewFromConfig(cfg, WithToken("override-token"))
+
+	if client.APIURL != cfg.APIURL {
+		t.Errorf("expected API URL %s, got %s", cfg.APIURL, client.APIURL)
+	}
+	if client.Token != "override-token" {
+		t.Errorf("expected options to take precedence, got token %q", client.Token)
+	}
+	if client.Timeout != 10*time.Second {
+		t.Errorf("expected 10s timeout, got %v", client.Timeout)
+	}
+	if client.MaxRetries != 5 {
+		t.Errorf("expected 5 retries, got %d", client.MaxRetries)
+	}
+	if client.IdempotencyWindow != time.Hour {
+		t.Errorf("expected 1h idempotency window, got %v", client.IdempotencyWindow)
+	}
+	if client.QuotaStore == nil || client.IdempotencyStore == nil {
+		t.Error("expected quota and idempotency stores to be configured")
+	}
+	if client.Breaker == nil || client.Breaker.Threshold != 2 || client.Breaker.Cooldown != time.Minute {
+		t.Fatalf("expected breaker with threshold 2 and 1m cooldown, got %+v", client.Breaker)
+	}
+
+	// Breaker state is shared with the CLI through the config directory
+	store, ok := client.Breaker.Store.(*FileBreakerStore)
+	if !ok {
+		t.Fatalf("expected *FileBreakerStore, got %T", client.Breaker.Store)
+	}
+	if want := filepath.Join(home, config.ConfigDirName, BreakerFileName); store.path != want {
+		t.Errorf("expected breaker state at %s, got %s", want, store.path)
+	}
+}
+
+func TestNewFromConfig_ZeroValues(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	client := NewFromConfig(&config.Config{BreakerThreshold: -1})
+
+	if client.APIURL != DefaultAPIURL || client.Timeout != DefaultTimeout || client.MaxRetries != DefaultMaxRetries {
+		t.Errorf("expected defaults for unset values, got %s, %v, %d", client.APIURL, client.Timeout, client.MaxRetries)
+	}
+	if client.Breaker != nil {
+		t.Error("expected a negative threshold to disable the breaker")
+	}
+
+	client = NewFromConfig(&config.Config{MaxRetries: 0, MaxRetriesSet: true})
+	if client.retryPolicy().ShouldRetry(RetryAttempt{Response: responseWithHeader(503, "")}) {
+		t.Error("expected max_retries 0 to disable retries")
+	}
+}
@@ -0,0 +1,291 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/validation"
+)
+
+// PreparedRequest is a validated API request ready to be sent, now or later.
+// The message is already encrypted and the request holds neither the
+// encryption password nor the API token, so it is safe to store on disk.
+type PreparedRequest struct {
+	Endpoint       string          `json:"endpoint"`              // EndpointSend or EndpointNotifAI
+	Body           json.RawMessage `json:"body"`                  // JSON request body
+	IdempotencyKey string          `json:"idempotencyKey"`        // Sent as Idempotency-Key header on every attempt
+	ExplicitKey    bool            `json:"explicitKey,omitempty"` // Key was chosen by the caller and is checked for duplicates
+}
+
+// PreparedResult is the successful outcome of sending a PreparedRequest
+type PreparedResult struct {
+	Endpoint       string
+	Body           json.RawMessage // Raw JSON success response
+	RateLimit      *RateLimitInfo
+	IdempotencyKey string
+}
+
+// SendResult decodes the response of a prepared send request
+func (r *PreparedResult) SendResult() (*SendResult, error) {
+	var apiResp SendResponse
+	if err := json.Unmarshal(r.Body, &apiResp); err != nil {
+		return nil, errors.NewNetworkError("failed to parse response", err)
+	}
+	return &SendResult{
+		Response:       &apiResp,
+		RateLimit:      r.RateLimit,
+		IdempotencyKey: r.IdempotencyKey,
+	}, nil
+}
+
+// NotifAIResult decodes the response of a prepared NotifAI request
+func (r *PreparedResult) NotifAIResult() (*NotifAIResult, error) {
+	var apiResp NotifAIResponse
+	if err := json.Unmarshal(r.Body, &apiResp); err != nil {
+		return nil, errors.NewNetworkError("failed to parse response", err)
+	}
+	return &NotifAIResult{
+		Response:       &apiResp,
+		RateLimit:      r.RateLimit,
+		IdempotencyKey: r.IdempotencyKey,
+	}, nil
+}
+
+// PrepareSend validates opts and builds the send request, encrypting the
+// message if an encryption password is set. A key is generated if
+// opts.IdempotencyKey is empty.
+func (c *Client) PrepareSend(opts *SendOptions) (*PreparedRequest, error) {
+	if opts.IdempotencyKey != "" {
+		return c.prepareSend(opts, opts.IdempotencyKey, true)
+	}
+	return c.prepareSend(opts, NewIdempotencyKey(), false)
+}
+
+// PrepareNotifAI validates opts and builds the NotifAI request
+// A key is generated if opts.IdempotencyKey is empty.
+func (c *Client) PrepareNotifAI(opts *NotifAIOptions) (*PreparedRequest, error) {
+	if opts.IdempotencyKey != "" {
+		return c.prepareNotifAI(opts, opts.IdempotencyKey, true)
+	}
+	return c.prepareNotifAI(opts, NewIdempotencyKey(), false)
+}
+
+// SendPrepared sends a prepared request with the client's token, retries,
+// quota tracking and circuit breaker. A caller-chosen key that was already
+// sent within the idempotency window is refused with a DuplicateError.
+func (c *Client) SendPrepared(ctx context.Context, p *PreparedRequest) (*PreparedResult, error) {
+	if c.Token == "" {
+		return nil, errors.NewAuthenticationError("token is required")
+	}
+
+	if p.ExplicitKey {
+		if _, _, err := c.resolveIdempotencyKey(p.Endpoint, p.IdempotencyKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.execute(ctx, p, nil)
+}
+
+// prepareSend validates opts and builds the send request body
+func (c *Client) prepareSend(opts *SendOptions, idempotencyKey string, explicitKey bool) (*PreparedRequest, error) {
+	// Validate required fields
+	if opts.Title == "" {
+		return nil, errors.NewValidationError("title is required")
+	}
+
+	// Normalize and validate tags
+	if len(opts.Tags) > 0 {
+		normalizedTags, err := validation.NormalizeAndValidateTags(opts.Tags)
+		if err != nil {
+			return nil, errors.NewValidationErrorWithDetails(fmt.Sprintf("tag validation failed: %v", err), "tags", "invalid_tags")
+		}
+		opts.Tags = normalizedTags
+	}
+
+	// Handle encryption if password provided
+	finalMessage := opts.Message
+	var ivHex string
+
+	if opts.EncryptionPassword != "" {
+		scheme, err := crypto.ParseScheme(string(opts.EncryptionScheme))
+		if err != nil {
+			return nil, errors.NewValidationErrorWithDetails(err.Error(), "encryptionScheme", "invalid_scheme")
+		}
+
+		// Only encrypt if message is not empty
+		if opts.Message != "" {
+			// Pad before encrypting so the ciphertext length hides the message length
+			padded, err := crypto.PadMessage(opts.Message, opts.PadTo)
+			if err != nil {
+				return nil, errors.NewValidationErrorWithDetails(err.Error(), "padTo", "invalid_padding")
+			}
+
+			ivBytes, ivHexStr, err := crypto.GenerateIV()
+			if err != nil {
+				return nil, errors.NewNetworkError("failed to generate IV", err)
+			}
+
+			encrypted, err := crypto.Encrypt(padded, opts.EncryptionPassword, scheme, ivBytes)
+			if err != nil {
+				return nil, errors.NewNetworkError("failed to encrypt message", err)
+			}
+
+			finalMessage = encrypted
+			ivHex = ivHexStr
+		}
+	}
+
+	// Build request with encrypted message if applicable
+	requestOpts := *opts
+	requestOpts.Message = finalMessage
+	requestOpts.IV = ivHex
+	requestOpts.EncryptionPassword = "" // Don't send password to API
+
+	// Build request body
+	jsonData, err := json.Marshal(requestOpts)
+	if err != nil {
+		return nil, errors.NewNetworkError("failed to marshal request", err)
+	}
+
+	return &PreparedRequest{
+		Endpoint:       EndpointSend,
+		Body:           jsonData,
+		IdempotencyKey: idempotencyKey,
+		ExplicitKey:    explicitKey,
+	}, nil
+}
+
+// prepareNotifAI validates opts and builds the NotifAI request body
+func (c *Client) prepareNotifAI(opts *NotifAIOptions, idempotencyKey string, explicitKey bool) (*PreparedRequest, error) {
+	// Validate text length
+	if opts.Text == "" {
+		return nil, errors.NewValidationError("text is required")
+	}
+	if len(opts.Text) < 5 {
+		return nil, errors.NewValidationError("text must be at least 5 characters long")
+	}
+	if len(opts.Text) > 2500 {
+		return nil, errors.NewValidationError("text must be at most 2500 characters long")
+	}
+
+	// Build request body
+	jsonData, err := json.Marshal(opts)
+	if err != nil {
+		return nil, errors.NewNetworkError("failed to marshal request", err)
+	}
+
+	return &PreparedRequest{
+		Endpoint:       EndpointNotifAI,
+		Body:           jsonData,
+		IdempotencyKey: idempotencyKey,
+		ExplicitKey:    explicitKey,
+	}, nil
+}
+
+// execute sends a prepared request and returns the raw success response
+// If gate is non-nil, retries and rate limit pauses are shared through it
+// A successful request with a caller-chosen key is recorded in the IdempotencyStore
+func (c *Client) execute(ctx context.Context, p *PreparedRequest, gate *retryGate) (*PreparedResult, error) {
+	endpointURL, err := c.EndpointURL(p.Endpoint)
+	if err != nil {
+		return nil, errors.NewValidationError(err.Error())
+	}
+
+	// Fail fast if the last known quota guarantees a 429
+	if err := c.checkQuota(p.Endpoint, endpointURL); err != nil {
+		return nil, err
+	}
+
+	// Create HTTP request with context
+	req, err := http.NewRequestWithContext(ctx, "POST", endpointURL, bytes.NewReader(p.Body))
+	if err != nil {
+		return nil, errors.NewNetworkError("failed to create request", err)
+	}
+
+	// Set GetBody for retry support
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(p.Body)), nil
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+	req.Header.Set("User-Agent", c.UserAgent)
+	req.Header.Set(IdempotencyKeyHeader, p.IdempotencyKey)
+
+	// Send request with retry logic
+	resp, err := c.doRequestWithBreaker(ctx, req, gate)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Extract and persist rate limit headers
+	rateLimit := c.trackRateLimit(endpointURL, resp)
+
+	// Read response body
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.NewNetworkError("failed to read response", err)
+	}
+
+	// Handle error status codes
+	if resp.StatusCode >= 400 {
+		return nil, apiError(resp, bodyBytes)
+	}
+
+	c.recordIdempotencyKey(p.Endpoint, p.IdempotencyKey, p.ExplicitKey)
+
+	return &PreparedResult{
+		Endpoint:       p.Endpoint,
+		Body:           bodyBytes,
+		RateLimit:      rateLimit,
+		IdempotencyKey: p.IdempotencyKey,
+	}, nil
+}
+
+// apiError converts an error response into a typed API error
+func apiError(resp *http.Response, bodyBytes []byte) error {
+	// Try to parse nested error response
+	var errorResp ErrorResponse
+	if err := json.Unmarshal(bodyBytes, &errorResp); err == nil && errorResp.Error.Message != "" {
+		// Return typed error based on status code
+		switch resp.StatusCode {
+		case 400, 404:
+			return errors.NewValidationErrorWithDetails(errorResp.Error.Message, errorResp.Error.Param, errorResp.Error.Code)
+		case 401, 403:
+			return errors.NewAuthenticationErrorWithStatus(errorResp.Error.Message, resp.StatusCode)
+		case 429:
+			retryAfter := retryAfterSeconds(resp.Header)
+			return errors.NewRateLimitErrorWithRetryAfter(errorResp.Error.Message, retryAfter)
+		default:
+			if resp.StatusCode >= 500 {
+				return errors.NewServerErrorWithStatus(errorResp.Error.Message, resp.StatusCode)
+			}
+			return errors.NewValidationErrorWithDetails(errorResp.Error.Message, errorResp.Error.Param, errorResp.Error.Code)
+		}
+	}
+
+	// Fallback to generic error message if parsing fails
+	errorMsg := string(bodyBytes)
+	switch resp.StatusCode {
+	case 400, 404:
+		return errors.NewValidationError(fmt.Sprintf("validation error: %s", errorMsg))
+	case 401, 403:
+		return errors.NewAuthenticationErrorWithStatus(fmt.Sprintf("authentication error: %s", errorMsg), resp.StatusCode)
+	case 429:
+		retryAfter := retryAfterSeconds(resp.Header)
+		return errors.NewRateLimitErrorWithRetryAfter(fmt.Sprintf("rate limit exceeded: %s", errorMsg), retryAfter)
+	default:
+		if resp.StatusCode >= 500 {
+			return errors.NewServerErrorWithStatus(fmt.Sprintf("server error: %s", errorMsg), resp.StatusCode)
+		}
+		return errors.NewValidationError(fmt.Sprintf("API error (%d): %s", resp.StatusCode, errorMsg))
+	}
+}
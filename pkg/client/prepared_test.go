@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Pincho-App/pincho-cli/pkg/crypto"
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+func TestPrepareSend_EncryptsWithoutPassword(t *testing.T) {
+	client := New()
+
+	prepared, err := client.PrepareSend(&SendOptions{
+		Title:              "Backup",
+		Message:            "plaintext message",
+		Tags:               []string{" Production "},
+		EncryptionPassword: "secret-password",
+	})
+	if err != nil {
+		t.Fatalf("PrepareSend failed: %v", err)
+	}
+
+	if prepared.Endpoint != EndpointSend || prepared.IdempotencyKey == "" || prepared.ExplicitKey {
+		t.Errorf("unexpected prepared request: %+v", prepared)
+	}
+
+	body := string(prepared.Body)
+	if strings.Contains(body, "secret-password") || strings.Contains(body, "plaintext message") {
+		t.Errorf("prepared body leaks secrets: %s", body)
+	}
+
+	var sent SendOptions
+	if err := json.Unmarshal(prepared.Body, &sent); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if sent.IV == "" || sent.Message == "" {
+		t.Errorf("expected encrypted message with IV, got %+v", sent)
+	}
+	if len(sent.Tags) != 1 || sent.Tags[0] != "production" {
+		t.Errorf("expected normalized tags, got %v", sent.Tags)
+	}
+}
+
+func TestPrepareSend_EncryptionScheme(t *testing.T) {
+	client := New()
+
+	prepared, err := client.PrepareSend(&SendOptions{
+		Title:              "Backup",
+		Message:            "plaintext message",
+		EncryptionPassword: "secret-password",
+		EncryptionScheme:   crypto.SchemeV2,
+	})
+	if err != nil {
+		t.Fatalf("PrepareSend failed: %v", err)
+	}
+
+	var sent SendOptions
+	if err := json.Unmarshal(prepared.Body, &sent); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	iv, err := crypto.ParseIV(sent.IV)
+	if err != nil {
+		t.Fatalf("invalid IV in body: %v", err)
+	}
+	if crypto.DetectScheme(sent.Message) != crypto.SchemeV2 {
+		t.Fatalf("expected a v2 message, got %q", sent.Message)
+	}
+	if plaintext, err := crypto.DecryptMessage(sent.Message, "secret-password", iv); err != nil || plaintext != "plaintext message" {
+		t.Errorf("DecryptMessage() = %q, %v", plaintext, err)
+	}
+
+	_, err = client.PrepareSend(&SendOptions{
+		Title:              "Backup",
+		Message:            "plaintext message",
+		EncryptionPassword: "secret-password",
+		EncryptionScheme:   "v9",
+	})
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("expected ValidationError for unknown scheme, got %v", err)
+	}
+}
+
+func TestPrepare_Validation(t *testing.T) {
+	client := New()
+
+	if _, err := client.PrepareSend(&SendOptions{}); err == nil {
+		t.Error("expected error for missing title")
+	}
+	if _, err := client.PrepareNotifAI(&NotifAIOptions{Text: "hi"}); err == nil {
+		t.Error("expected error for short text")
+	}
+
+	prepared, err := client.PrepareNotifAI(&NotifAIOptions{Text: "deploy finished", IdempotencyKey: "deploy-1"})
+	if err != nil {
+		t.Fatalf("PrepareNotifAI failed: %v", err)
+	}
+	if prepared.Endpoint != EndpointNotifAI || prepared.IdempotencyKey != "deploy-1" || !prepared.ExplicitKey {
+		t.Errorf("unexpected prepared request: %+v", prepared)
+	}
+}
+
+func TestClient_SendPrepared(t *testing.T) {
+	var requests int32
+	var receivedKey atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		receivedKey.Store(r.Header.Get(IdempotencyKeyHeader))
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success", "message": "sent"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.IdempotencyStore = state.NewKeyLog(filepath.Join(t.TempDir(), IdempotencyFileName))
+
+	prepared, err := client.PrepareSend(&SendOptions{Title: "Deploy", IdempotencyKey: "deploy-1.2.3"})
+	if err != nil {
+		t.Fatalf("PrepareSend failed: %v", err)
+	}
+
+	result, err := client.SendPrepared(context.Background(), prepared)
+	if err != nil {
+		t.Fatalf("SendPrepared failed: %v", err)
+	}
+	sendResult, err := result.SendResult()
+	if err != nil {
+		t.Fatalf("SendResult failed: %v", err)
+	}
+	if sendResult.Response.Status != "success" || sendResult.IdempotencyKey != "deploy-1.2.3" {
+		t.Errorf("unexpected result: %+v", sendResult)
+	}
+	if got := receivedKey.Load(); got != "deploy-1.2.3" {
+		t.Errorf("expected prepared key to be sent, got %v", got)
+	}
+
+	// A caller-chosen key is refused once it was delivered
+	if _, err := client.SendPrepared(context.Background(), prepared); err == nil {
+		t.Fatal("expected duplicate error")
+	} else if _, ok := err.(*errors.DuplicateError); !ok {
+		t.Fatalf("expected *DuplicateError, got %T: %v", err, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request to reach the API, got %d", got)
+	}
+}
+
+func TestClient_SendPrepared_NoToken(t *testing.T) {
+	client := New()
+	prepared, err := client.PrepareSend(&SendOptions{Title: "Deploy"})
+	if err != nil {
+		t.Fatalf("PrepareSend failed: %v", err)
+	}
+
+	if _, err := client.SendPrepared(context.Background(), prepared); err == nil {
+		t.Fatal("expected error for missing token")
+	} else if _, ok := err.(*errors.AuthenticationError); !ok {
+		t.Errorf("expected *AuthenticationError, got %T: %v", err, err)
+	}
+}
+
+func TestPrepareSend_PadTo(t *testing.T) {
+	client := New()
+
+	sizes := map[int]bool{}
+	for _, message := range []string{"OK", "Disk /var at 98% on db-1, replication lagging by 40 minutes"} {
+		prepared, err := client.PrepareSend(&SendOptions{
+			Title:              "Status",
+			Message:            message,
+			EncryptionPassword: "secret-password",
+			PadTo:              256,
+		})
+		if err != nil {
+			t.Fatalf("PrepareSend failed: %v", err)
+		}
+
+		var sent SendOptions
+		if err := json.Unmarshal(prepared.Body, &sent); err != nil {
+			t.Fatalf("failed to decode body: %v", err)
+		}
+		sizes[len(sent.Message)] = true
+
+		iv, _ := crypto.ParseIV(sent.IV)
+		plaintext, err := crypto.DecryptMessage(sent.Message, "secret-password", iv)
+		if err != nil || crypto.StripPadding(plaintext) != message {
+			t.Errorf("DecryptMessage() = %q, %v; want %q padded", plaintext, err, message)
+		}
+	}
+	if len(sizes) != 1 {
+		t.Errorf("expected padded messages of equal length, got lengths %v", sizes)
+	}
+
+	_, err := client.PrepareSend(&SendOptions{
+		Title:              "Status",
+		Message:            "OK",
+		EncryptionPassword: "secret-password",
+		PadTo:              -1,
+	})
+	if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("expected ValidationError for negative padding, got %v", err)
+	}
+}
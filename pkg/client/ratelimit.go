@@ -0,0 +1,230 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+// QuotaFileName is the name of the state file holding persisted rate limit quotas
+const QuotaFileName = "quota.json"
+
+// quotaRetention is how long a quota entry is kept after its window has reset,
+// or after it was last saved if its reset time is unknown
+const quotaRetention = 24 * time.Hour
+
+// RateLimitInfo contains rate limiting information from response headers
+type RateLimitInfo struct {
+	Limit     int       // Maximum number of requests in the current window
+	Remaining int       // Requests left in the current window
+	Reset     time.Time // When the current window resets (zero if unknown)
+}
+
+// Exhausted reports whether no requests remain in a window that has not reset by now
+// Returns false if the reset time is unknown, since the wait cannot be bounded
+func (r *RateLimitInfo) Exhausted(now time.Time) bool {
+	return r.Remaining <= 0 && !r.Reset.IsZero() && now.Before(r.Reset)
+}
+
+// parseRateLimit extracts the RateLimit-* headers from a response
+// Returns nil if the response carries no usable remaining count
+func parseRateLimit(header http.Header, now time.Time) *RateLimitInfo {
+	remaining, err := strconv.Atoi(strings.TrimSpace(header.Get("RateLimit-Remaining")))
+	if err != nil {
+		return nil
+	}
+
+	info := &RateLimitInfo{Remaining: remaining}
+	if limit, err := strconv.Atoi(strings.TrimSpace(header.Get("RateLimit-Limit"))); err == nil {
+		info.Limit = limit
+	}
+	if reset, ok := parseReset(strings.TrimSpace(header.Get("RateLimit-Reset")), now); ok {
+		info.Reset = reset
+	}
+
+	return info
+}
+
+// parseReset parses a RateLimit-Reset value given either as an RFC3339
+// timestamp or as delta-seconds until the window resets
+func parseReset(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if reset, err := time.Parse(time.RFC3339, value); err == nil {
+		return reset, true
+	}
+
+	return time.Time{}, false
+}
+
+// trackRateLimit parses the rate limit state of a response from endpointURL and
+// persists it to the client's QuotaStore. A 429 response always records an
+// exhausted quota, using Retry-After as the reset time when the
+// RateLimit-Reset header is absent.
+func (c *Client) trackRateLimit(endpointURL string, resp *http.Response) *RateLimitInfo {
+	now := time.Now()
+	info := parseRateLimit(resp.Header, now)
+
+	stored := info
+	if resp.StatusCode == 429 {
+		exhausted := RateLimitInfo{}
+		if info != nil {
+			exhausted = *info
+		}
+		exhausted.Remaining = 0
+		if exhausted.Reset.IsZero() {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok && retryAfter > 0 {
+				exhausted.Reset = now.Add(retryAfter)
+			}
+		}
+		stored = &exhausted
+	}
+
+	if c.QuotaStore != nil && stored != nil {
+		// Quota persistence is best effort and never fails the request
+		_ = c.QuotaStore.Save(c.Token, endpointURL, stored)
+	}
+
+	return info
+}
+
+// checkQuota returns a RateLimitError without contacting the API if the last
+// known quota for the endpoint, named name and resolved to endpointURL, is
+// exhausted and its window has not reset yet
+func (c *Client) checkQuota(name, endpointURL string) error {
+	if c.QuotaStore == nil {
+		return nil
+	}
+
+	info, err := c.QuotaStore.Load(c.Token, endpointURL)
+	if err != nil || info == nil {
+		return nil
+	}
+
+	if info.Exhausted(time.Now()) {
+		return errors.NewRateLimitErrorWithReset(
+			fmt.Sprintf("rate limit quota for the %s endpoint is exhausted, request not sent", name),
+			info.Reset,
+		)
+	}
+
+	return nil
+}
+
+// QuotaStore persists the last known rate limit quota per token and endpoint,
+// letting separate client instances and CLI invocations share it
+// The endpoint is the resolved endpoint URL, so quotas seen from a dev server
+// or staging base URL never mix with those of the production API.
+type QuotaStore interface {
+	// Load returns the last known quota, or nil if none is recorded
+	Load(token, endpoint string) (*RateLimitInfo, error)
+
+	// Save records the latest quota
+	Save(token, endpoint string, info *RateLimitInfo) error
+}
+
+// quotaEntry is a persisted quota and when it was last saved
+type quotaEntry struct {
+	RateLimitInfo
+	SavedAt time.Time
+}
+
+// expired reports whether the entry is past its retention at now
+func (q quotaEntry) expired(now time.Time) bool {
+	last := q.Reset
+	if last.IsZero() {
+		last = q.SavedAt
+	}
+	return last.Before(now.Add(-quotaRetention))
+}
+
+// FileQuotaStore is a QuotaStore backed by a JSON file
+// Tokens are never written to disk, entries are keyed by a hash of the token.
+// Saves lock the file, so concurrent CLI processes do not lose each other's
+// updates.
+type FileQuotaStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileQuotaStore creates a quota store persisting to the file at path
+func NewFileQuotaStore(path string) *FileQuotaStore {
+	return &FileQuotaStore{path: path}
+}
+
+// Load returns the last known quota for token and endpoint
+func (s *FileQuotaStore) Load(token, endpoint string) (*RateLimitInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quotas, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := quotas[quotaKey(token, endpoint)]
+	if !ok {
+		return nil, nil
+	}
+	return &entry.RateLimitInfo, nil
+}
+
+// Save records the latest quota for token and endpoint
+// Entries whose window reset more than a day ago, or that have no reset time
+// and were last saved more than a day ago, are pruned
+func (s *FileQuotaStore) Save(token, endpoint string, info *RateLimitInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return state.WithLock(s.path+".lock", func() error {
+		quotas, err := s.read()
+		if err != nil {
+			// Start over rather than failing forever on a corrupt file
+			quotas = make(map[string]quotaEntry)
+		}
+
+		now := time.Now()
+		for key, q := range quotas {
+			if q.expired(now) {
+				delete(quotas, key)
+			}
+		}
+
+		quotas[quotaKey(token, endpoint)] = quotaEntry{RateLimitInfo: *info, SavedAt: now.UTC()}
+		return state.WriteJSON(s.path, quotas)
+	})
+}
+
+// read loads all persisted quotas
+func (s *FileQuotaStore) read() (map[string]quotaEntry, error) {
+	quotas := make(map[string]quotaEntry)
+	if _, err := state.ReadJSON(s.path, &quotas); err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// quotaKey identifies a token and endpoint pair without revealing the token
+func quotaKey(token, endpoint string) string {
+	return TokenFingerprint(token) + "/" + endpoint
+}
+
+// TokenFingerprint identifies a token in local state files without revealing it
+func TokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,312 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		wantNil   bool
+		limit     int
+		remaining int
+		reset     time.Time
+	}{
+		{
+			name:    "no headers",
+			headers: map[string]string{},
+			wantNil: true,
+		},
+		{
+			name:      "delta-seconds reset",
+			headers:   map[string]string{"RateLimit-Limit": "30", "RateLimit-Remaining": "12", "RateLimit-Reset": "120"},
+			limit:     30,
+			remaining: 12,
+			reset:     now.Add(2 * time.Minute),
+		},
+		{
+			name:      "RFC3339 reset",
+			headers:   map[string]string{"RateLimit-Limit": "30", "RateLimit-Remaining": "0", "RateLimit-Reset": "2025-06-01T12:30:00Z"},
+			limit:     30,
+			remaining: 0,
+			reset:     time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "invalid reset is ignored",
+			headers:   map[string]string{"RateLimit-Limit": "50", "RateLimit-Remaining": "49", "RateLimit-Reset": "soon"},
+			limit:     50,
+			remaining: 49,
+		},
+		{
+			name:    "missing remaining",
+			headers: map[string]string{"RateLimit-Limit": "30", "RateLimit-Reset": "120"},
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+
+			info := parseRateLimit(header, now)
+			if tt.wantNil {
+				if info != nil {
+					t.Errorf("expected nil, got %+v", info)
+				}
+				return
+			}
+			if info == nil {
+				t.Fatal("expected rate limit info, got nil")
+			}
+			if info.Limit != tt.limit || info.Remaining != tt.remaining {
+				t.Errorf("expected %d/%d, got %d/%d", tt.remaining, tt.limit, info.Remaining, info.Limit)
+			}
+			if !info.Reset.Equal(tt.reset) {
+				t.Errorf("expected reset %v, got %v", tt.reset, info.Reset)
+			}
+		})
+	}
+}
+
+func TestRateLimitInfo_Exhausted(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		info RateLimitInfo
+		want bool
+	}{
+		{"remaining requests", RateLimitInfo{Limit: 30, Remaining: 5, Reset: now.Add(time.Minute)}, false},
+		{"exhausted before reset", RateLimitInfo{Limit: 30, Remaining: 0, Reset: now.Add(time.Minute)}, true},
+		{"exhausted after reset", RateLimitInfo{Limit: 30, Remaining: 0, Reset: now.Add(-time.Minute)}, false},
+		{"exhausted with unknown reset", RateLimitInfo{Limit: 30, Remaining: 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.Exhausted(now); got != tt.want {
+				t.Errorf("Exhausted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileQuotaStore(t *testing.T) {
+	store := NewFileQuotaStore(filepath.Join(t.TempDir(), QuotaFileName))
+
+	info, err := store.Load("token-a", EndpointSend)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if info != nil {
+		t.Fatalf("expected no quota for unknown token, got %+v", info)
+	}
+
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := store.Save("token-a", EndpointSend, &RateLimitInfo{Limit: 30, Remaining: 3, Reset: reset}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := store.Save("token-a", EndpointNotifAI, &RateLimitInfo{Limit: 50, Remaining: 40, Reset: reset}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	// A fresh store sees quotas persisted by another instance
+	other := NewFileQuotaStore(store.path)
+	info, err = other.Load("token-a", EndpointSend)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if info == nil || info.Remaining != 3 || info.Limit != 30 || !info.Reset.Equal(reset) {
+		t.Errorf("unexpected send quota: %+v", info)
+	}
+
+	info, _ = other.Load("token-a", EndpointNotifAI)
+	if info == nil || info.Remaining != 40 {
+		t.Errorf("unexpected notifai quota: %+v", info)
+	}
+
+	// Quotas are tracked per token
+	if info, _ := other.Load("token-b", EndpointSend); info != nil {
+		t.Errorf("expected no quota for another token, got %+v", info)
+	}
+}
+
+func TestFileQuotaStore_PrunesEntriesWithoutReset(t *testing.T) {
+	store := NewFileQuotaStore(filepath.Join(t.TempDir(), QuotaFileName))
+
+	// An entry without a reset time that was last saved two days ago
+	stale := map[string]quotaEntry{
+		quotaKey("old-token", EndpointSend): {
+			RateLimitInfo: RateLimitInfo{Limit: 30, Remaining: 10},
+			SavedAt:       time.Now().Add(-2 * quotaRetention),
+		},
+	}
+	if err := state.WriteJSON(store.path, stale); err != nil {
+		t.Fatalf("failed to write quota file: %v", err)
+	}
+
+	if err := store.Save("token-a", EndpointSend, &RateLimitInfo{Remaining: 5}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if info, _ := store.Load("old-token", EndpointSend); info != nil {
+		t.Errorf("expected the stale entry to be pruned, got %+v", info)
+	}
+	// A fresh entry without a reset time is kept
+	if info, _ := store.Load("token-a", EndpointSend); info == nil || info.Remaining != 5 {
+		t.Errorf("expected the new entry to be kept, got %+v", info)
+	}
+}
+
+func TestClient_Send_RecordsQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Limit", "30")
+		w.Header().Set("RateLimit-Remaining", "29")
+		w.Header().Set("RateLimit-Reset", "3600")
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	store := NewFileQuotaStore(filepath.Join(t.TempDir(), QuotaFileName))
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.QuotaStore = store
+
+	result, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if result.RateLimit == nil || result.RateLimit.Remaining != 29 || result.RateLimit.Limit != 30 {
+		t.Errorf("unexpected rate limit in result: %+v", result.RateLimit)
+	}
+
+	// Quotas are keyed by the resolved endpoint URL
+	endpointURL, _ := client.EndpointURL(EndpointSend)
+	info, err := store.Load("test-token", endpointURL)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if info == nil || info.Remaining != 29 {
+		t.Errorf("expected persisted quota with 29 remaining, got %+v", info)
+	}
+}
+
+func TestFileQuotaStore_ConcurrentSaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), QuotaFileName)
+
+	// Separate stores stand in for separate CLI processes
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store := NewFileQuotaStore(path)
+			if err := store.Save("token", fmt.Sprintf("endpoint-%d", i), &RateLimitInfo{Remaining: i}); err != nil {
+				t.Errorf("Save() failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	store := NewFileQuotaStore(path)
+	for i := 0; i < 20; i++ {
+		if info, _ := store.Load("token", fmt.Sprintf("endpoint-%d", i)); info == nil || info.Remaining != i {
+			t.Errorf("endpoint-%d: expected quota with %d remaining, got %+v", i, i, info)
+		}
+	}
+}
+
+func TestClient_Send_QuotaPerBaseURL(t *testing.T) {
+	exhausted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(429)
+		_, _ = w.Write([]byte(`{"status": "error", "error": {"type": "rate_limit_error", "code": "rate_limit_exceeded", "message": "Too many requests"}}`))
+	}))
+	defer exhausted.Close()
+
+	var requests int32
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer available.Close()
+
+	store := NewFileQuotaStore(filepath.Join(t.TempDir(), QuotaFileName))
+	newClient := func(baseURL string) *Client {
+		return NewWithOptions(WithBaseURL(baseURL), WithToken("test-token"), WithQuotaStore(store), WithRetries(0, time.Millisecond))
+	}
+
+	if _, err := newClient(exhausted.URL).Send(context.Background(), &SendOptions{Title: "Test"}); err == nil {
+		t.Fatal("expected rate limit error, got nil")
+	}
+
+	// The same token against another base URL is not refused locally
+	if _, err := newClient(available.URL).Send(context.Background(), &SendOptions{Title: "Test"}); err != nil {
+		t.Fatalf("expected no error for another base URL, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request to reach the other API, got %d", got)
+	}
+}
+
+func TestClient_Send_FailsFastOnExhaustedQuota(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(429)
+		_, _ = w.Write([]byte(`{"status": "error", "error": {"type": "rate_limit_error", "code": "rate_limit_exceeded", "message": "Too many requests"}}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.QuotaStore = NewFileQuotaStore(filepath.Join(t.TempDir(), QuotaFileName))
+	client.RetryPolicy = &DefaultRetryPolicy{MaxRetries: 0}
+
+	// The first request reaches the API and records the exhausted quota
+	if _, err := client.Send(context.Background(), &SendOptions{Title: "Test"}); err == nil {
+		t.Fatal("expected rate limit error, got nil")
+	}
+
+	// The second request is refused locally
+	_, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+	rateErr, ok := err.(*errors.RateLimitError)
+	if !ok {
+		t.Fatalf("expected *RateLimitError, got %T: %v", err, err)
+	}
+	if rateErr.ResetAt.IsZero() {
+		t.Error("expected the error to report when the window resets")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request to reach the API, got %d", got)
+	}
+
+	// Quotas are tracked per endpoint, so NotifAI is not blocked
+	info, _ := client.QuotaStore.Load("test-token", EndpointNotifAI)
+	if info != nil {
+		t.Errorf("expected no NotifAI quota, got %+v", info)
+	}
+}
@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	clierrors "github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+const (
+	// DefaultMaxBackoff caps the delay between two attempts
+	DefaultMaxBackoff = 30 * time.Second
+
+	// DefaultRateLimitBackoff is the initial backoff after a 429 without Retry-After
+	DefaultRateLimitBackoff = 5 * time.Second
+
+	// DefaultMaxElapsed caps the total time spent retrying a single request
+	DefaultMaxElapsed = 60 * time.Second
+)
+
+// RetryAttempt describes the outcome of a failed request attempt
+type RetryAttempt struct {
+	Attempt  int            // Zero-based number of the attempt that failed
+	Elapsed  time.Duration  // Time since the first attempt started
+	Response *http.Response // Response received, nil on transport errors
+	Err      error          // Transport error, nil if a response was received
+}
+
+// RetryPolicy decides whether a failed request is retried and how long to wait first.
+// Implementations must be safe for concurrent use.
+type RetryPolicy interface {
+	// ShouldRetry reports whether the request should be attempted again
+	ShouldRetry(a RetryAttempt) bool
+
+	// NextDelay returns how long to wait before the next attempt
+	NextDelay(a RetryAttempt) time.Duration
+}
+
+// DefaultRetryPolicy retries network errors, 5xx and 429 responses using
+// exponential backoff with full jitter, so that clients failing at the same
+// moment spread their retries out instead of colliding again.
+//
+// A Retry-After header (delta-seconds or HTTP-date) takes precedence over the
+// computed backoff. Zero durations use the package defaults.
+type DefaultRetryPolicy struct {
+	MaxRetries       int           // Maximum number of retries (zero disables retries)
+	InitialBackoff   time.Duration // Base backoff for network and server errors
+	RateLimitBackoff time.Duration // Base backoff for 429 responses without Retry-After
+	MaxBackoff       time.Duration // Cap on a single delay
+	MaxElapsed       time.Duration // Cap on the total time spent on one request
+}
+
+// NewDefaultRetryPolicy creates a DefaultRetryPolicy with the given retry count and
+// initial backoff, using the package defaults for everything else
+func NewDefaultRetryPolicy(maxRetries int, initialBackoff time.Duration) *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		MaxRetries:       maxRetries,
+		InitialBackoff:   initialBackoff,
+		RateLimitBackoff: DefaultRateLimitBackoff,
+		MaxBackoff:       DefaultMaxBackoff,
+		MaxElapsed:       DefaultMaxElapsed,
+	}
+}
+
+// ShouldRetry reports whether the failed attempt is retryable and the retry and
+// elapsed time budgets allow another attempt
+func (p *DefaultRetryPolicy) ShouldRetry(a RetryAttempt) bool {
+	if a.Attempt >= p.MaxRetries {
+		return false
+	}
+	if a.Elapsed >= orDefault(p.MaxElapsed, DefaultMaxElapsed) {
+		return false
+	}
+
+	if a.Err != nil {
+		return isRetryableError(a.Err)
+	}
+	if a.Response != nil {
+		return isRetryableStatus(a.Response.StatusCode)
+	}
+	return false
+}
+
+// NextDelay returns the Retry-After delay if the server sent one, otherwise a
+// random delay between zero and the exponential backoff for the attempt
+func (p *DefaultRetryPolicy) NextDelay(a RetryAttempt) time.Duration {
+	maxBackoff := orDefault(p.MaxBackoff, DefaultMaxBackoff)
+
+	// Respect remaining time budget so the last delay never overshoots it
+	if remaining := orDefault(p.MaxElapsed, DefaultMaxElapsed) - a.Elapsed; remaining < maxBackoff {
+		maxBackoff = max(remaining, 0)
+	}
+
+	base := orDefault(p.InitialBackoff, DefaultInitialBackoff)
+	if a.Response != nil {
+		if retryAfter, ok := parseRetryAfter(a.Response.Header.Get("Retry-After"), time.Now()); ok {
+			return min(retryAfter, maxBackoff)
+		}
+		if a.Response.StatusCode == http.StatusTooManyRequests {
+			base = orDefault(p.RateLimitBackoff, DefaultRateLimitBackoff)
+		}
+	}
+
+	// Exponential ceiling (base, 2*base, 4*base, ...) capped at maxBackoff
+	ceiling := maxBackoff
+	if a.Attempt < 32 {
+		if exp := base << uint(a.Attempt); exp > 0 && exp < ceiling {
+			ceiling = exp
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	// Full jitter: uniformly random in [0, ceiling]
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// orDefault returns d, or def if d is not positive
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// retryPolicy returns the client's RetryPolicy, or a DefaultRetryPolicy built
+// from MaxRetries and InitialBackoff if none is set
+func (c *Client) retryPolicy() RetryPolicy {
+	if c.RetryPolicy != nil {
+		return c.RetryPolicy
+	}
+
+	maxRetries := c.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	return NewDefaultRetryPolicy(maxRetries, c.InitialBackoff)
+}
+
+// isRetryableStatus reports whether an HTTP status code is worth retrying
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isRetryableError classifies transport errors returned by http.Client.Do
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// The caller gave up, retrying cannot help
+	// (an expired caller deadline is caught by the retry loop itself, while a
+	// per-attempt HTTP timeout is a retryable net.Error below)
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// Typed API errors know whether they are retryable
+	var apiErr clierrors.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.IsRetryable()
+	}
+
+	// Connection dropped mid-response
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// Connection refused, reset or aborted by the peer
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	// DNS failures are only retryable if the resolver says so
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	// Timeouts anywhere in the network stack
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// Any other failure while dialing, reading or writing a connection
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
+
+// parseRetryAfter parses a Retry-After header given as delta-seconds or as an HTTP-date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// retryAfterSeconds returns the Retry-After header of a response in whole seconds (0 if absent)
+func retryAfterSeconds(header http.Header) int {
+	d, ok := parseRetryAfter(header.Get("Retry-After"), time.Now())
+	if !ok {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
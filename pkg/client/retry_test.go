@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+// fastRetryPolicy retries like the default policy but with millisecond delays
+func fastRetryPolicy() *DefaultRetryPolicy {
+	return &DefaultRetryPolicy{
+		MaxRetries:       DefaultMaxRetries,
+		InitialBackoff:   time.Millisecond,
+		RateLimitBackoff: time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+	}
+}
+
+// responseWithHeader builds a response carrying a status code and optional Retry-After
+func responseWithHeader(statusCode int, retryAfter string) *http.Response {
+	header := http.Header{}
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return &http.Response{StatusCode: statusCode, Header: header}
+}
+
+func TestDefaultRetryPolicy_ShouldRetry(t *testing.T) {
+	policy := NewDefaultRetryPolicy(3, time.Second)
+
+	tests := []struct {
+		name    string
+		attempt RetryAttempt
+		want    bool
+	}{
+		{"500 response", RetryAttempt{Response: responseWithHeader(500, "")}, true},
+		{"503 response", RetryAttempt{Response: responseWithHeader(503, "")}, true},
+		{"429 response", RetryAttempt{Response: responseWithHeader(429, "")}, true},
+		{"400 response", RetryAttempt{Response: responseWithHeader(400, "")}, false},
+		{"401 response", RetryAttempt{Response: responseWithHeader(401, "")}, false},
+		{"501 response", RetryAttempt{Response: responseWithHeader(501, "")}, false},
+		{"connection refused", RetryAttempt{Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, true},
+		{"connection reset", RetryAttempt{Err: fmt.Errorf("read: %w", syscall.ECONNRESET)}, true},
+		{"unexpected EOF", RetryAttempt{Err: fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF)}, true},
+		{"dns not found", RetryAttempt{Err: &net.DNSError{Err: "no such host", Name: "api.invalid", IsNotFound: true}}, false},
+		{"dns timeout", RetryAttempt{Err: &net.DNSError{Err: "timeout", Name: "api.pincho.app", IsTimeout: true}}, true},
+		{"context cancelled", RetryAttempt{Err: context.Canceled}, false},
+		{"retryable API error", RetryAttempt{Err: errors.NewServerError("unavailable")}, true},
+		{"non-retryable API error", RetryAttempt{Err: errors.NewValidationError("bad")}, false},
+		{"error mentioning timeout is not matched by text", RetryAttempt{Err: fmt.Errorf("invalid timeout value")}, false},
+		{"retries exhausted", RetryAttempt{Attempt: 3, Response: responseWithHeader(500, "")}, false},
+		{"elapsed budget exhausted", RetryAttempt{Elapsed: DefaultMaxElapsed, Response: responseWithHeader(500, "")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.ShouldRetry(tt.attempt); got != tt.want {
+				t.Errorf("ShouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRetryPolicy_ZeroRetries(t *testing.T) {
+	policy := &DefaultRetryPolicy{}
+	if policy.ShouldRetry(RetryAttempt{Response: responseWithHeader(500, "")}) {
+		t.Error("expected a policy with MaxRetries 0 never to retry")
+	}
+}
+
+func TestDefaultRetryPolicy_NextDelay(t *testing.T) {
+	policy := NewDefaultRetryPolicy(5, time.Second)
+
+	tests := []struct {
+		name    string
+		attempt RetryAttempt
+		min     time.Duration
+		max     time.Duration
+	}{
+		{
+			name:    "rate limit with Retry-After seconds",
+			attempt: RetryAttempt{Response: responseWithHeader(429, "5")},
+			min:     5 * time.Second,
+			max:     5 * time.Second,
+		},
+		{
+			name:    "rate limit with Retry-After capped at max backoff",
+			attempt: RetryAttempt{Response: responseWithHeader(429, "60")},
+			min:     DefaultMaxBackoff,
+			max:     DefaultMaxBackoff,
+		},
+		{
+			name:    "rate limit with Retry-After HTTP-date",
+			attempt: RetryAttempt{Response: responseWithHeader(429, time.Now().Add(10*time.Second).UTC().Format(http.TimeFormat))},
+			min:     8 * time.Second,
+			max:     10 * time.Second,
+		},
+		{
+			name:    "rate limit without Retry-After uses rate limit backoff",
+			attempt: RetryAttempt{Response: responseWithHeader(429, "")},
+			min:     0,
+			max:     DefaultRateLimitBackoff,
+		},
+		{
+			name:    "invalid Retry-After falls back to backoff",
+			attempt: RetryAttempt{Response: responseWithHeader(429, "invalid")},
+			min:     0,
+			max:     DefaultRateLimitBackoff,
+		},
+		{
+			name:    "server error grows exponentially",
+			attempt: RetryAttempt{Attempt: 2, Response: responseWithHeader(500, "")},
+			min:     0,
+			max:     4 * time.Second,
+		},
+		{
+			name:    "backoff capped at max backoff",
+			attempt: RetryAttempt{Attempt: 10, Err: io.EOF},
+			min:     0,
+			max:     DefaultMaxBackoff,
+		},
+		{
+			name:    "delay never exceeds remaining elapsed budget",
+			attempt: RetryAttempt{Attempt: 4, Elapsed: DefaultMaxElapsed - time.Second, Response: responseWithHeader(429, "20")},
+			min:     time.Second,
+			max:     time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				delay := policy.NextDelay(tt.attempt)
+				if delay < tt.min || delay > tt.max {
+					t.Fatalf("NextDelay() = %v, want between %v and %v", delay, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultRetryPolicy_NextDelay_Jitter(t *testing.T) {
+	policy := NewDefaultRetryPolicy(3, time.Second)
+	attempt := RetryAttempt{Attempt: 3, Response: responseWithHeader(503, "")}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		seen[policy.NextDelay(attempt)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected jittered delays to differ between calls")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{"Sun, 01 Jun 2025 12:01:30 GMT", 90 * time.Second, true},
+		{"Sun, 01 Jun 2025 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestClient_Send_RetriesServerErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(503)
+			_, _ = w.Write([]byte(`{"status": "error", "error": {"type": "server_error", "code": "unavailable", "message": "Service unavailable"}}`))
+			return
+		}
+		w.WriteHeader(200)
+		_, _ = w.Write([]byte(`{"status": "success"}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	if _, err := client.Send(context.Background(), &SendOptions{Title: "Test"}); err != nil {
+		t.Fatalf("expected success after retries, got: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestClient_Send_ExhaustedRetriesReturnTypedError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(500)
+		_, _ = w.Write([]byte(`{"status": "error", "error": {"type": "server_error", "code": "internal", "message": "Internal failure"}}`))
+	}))
+	defer server.Close()
+
+	client := New()
+	client.APIURL = server.URL
+	client.SetToken("test-token")
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	_, err := client.Send(context.Background(), &SendOptions{Title: "Test"})
+	serverErr, ok := err.(*errors.ServerError)
+	if !ok {
+		t.Fatalf("expected *ServerError, got %T: %v", err, err)
+	}
+	if serverErr.Message != "Internal failure" {
+		t.Errorf("expected API error message to be preserved, got %q", serverErr.Message)
+	}
+	if got := atomic.LoadInt32(&requests); got != DefaultMaxRetries+1 {
+		t.Errorf("expected %d requests, got %d", DefaultMaxRetries+1, got)
+	}
+}
+
+func TestClient_Send_NetworkErrorIsTyped(t *testing.T) {
+	// Reserve a port and close it so connections are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := New()
+	client.APIURL = "http://" + addr
+	client.SetToken("test-token")
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	_, err = client.Send(context.Background(), &SendOptions{Title: "Test"})
+	if _, ok := err.(*errors.NetworkError); !ok {
+		t.Fatalf("expected *NetworkError, got %T: %v", err, err)
+	}
+}
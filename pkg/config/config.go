@@ -8,7 +8,15 @@
 // Configuration Priority (highest to lowest):
 //  1. Command-line flags (--token, --timeout, etc.)
 //  2. Environment variables (PINCHO_TOKEN, PINCHO_API_URL, etc.)
-//  3. Config file (~/.pincho/config.yaml)
+//  3. Active profile in the config file (profiles.<name>)
+//  4. Top-level values in the config file (~/.pincho/config.yaml)
+//
+// Profiles:
+//
+// The profiles section holds named sets of settings, selected with
+// SetProfile (--profile or PINCHO_PROFILE in the CLI). A profile can set any
+// supported key; keys it does not set are inherited from the top level.
+// Read settings through Key so the active profile is honored.
 //
 // Security:
 //   - Config directory created with 0700 permissions (owner-only access)
@@ -28,11 +36,25 @@
 //
 // Supported configuration keys:
 //   - token: Pincho API token
-//   - api_url: Custom API endpoint URL
+//   - token_file: File to read the token from, e.g. a Docker secret (string)
+//   - token_command: Shell command printing the token, e.g. a password manager (string)
+//   - api_url: Custom send endpoint URL (legacy, prefer base_url)
+//   - base_url: Base URL that endpoint paths are resolved against
+//   - endpoints: Per-endpoint path or absolute URL overrides (map of name to path)
 //   - timeout: HTTP request timeout in seconds (overrides default 30s)
 //   - max_retries: Maximum number of retry attempts (overrides default 3)
 //   - default_type: Default notification type (e.g., "alert", "deploy", "info")
 //   - default_tags: Default tags to include with all notifications (array of strings)
+//   - idempotency_window: How long a sent idempotency key is refused locally (e.g. "24h")
+//   - breaker_threshold: Consecutive failures before the circuit breaker opens (negative disables)
+//   - breaker_cooldown: How long an open circuit breaker fails fast (e.g. "60s")
+//   - dedup_window: How long an identical notification is suppressed (e.g. "10m")
+//   - templates: Named notification templates (map of name to fields, see pkg/templates)
+//   - outbox: Queue sends that fail with a transient error in ~/.pincho/outbox (bool)
+//   - encryption: Per-type encryption password sources (map of type to Encryption)
+//   - watch: Rule sets for pincho watch (map of name to list of rules, see pkg/watch)
+//   - github: Per-event mappings for pincho serve github (map of event to mapping, see pkg/github)
+//   - profiles: Named profiles overriding any of the keys above (map of name to settings)
 //
 // Example config file (~/.pincho/config.yaml):
 //
@@ -44,13 +66,27 @@
 //	default_tags:
 //	  - production
 //	  - automated
+//	profiles:
+//	  oncall:
+//	    token: team-token
+//	    default_type: alert
+//	  staging:
+//	    base_url: https://staging-api.example.com
+//	encryption:
+//	  secure:
+//	    password_env: PINCHO_SECURE_PASSWORD
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/Pincho-App/pincho-cli/pkg/secrets"
 	"github.com/spf13/viper"
 )
 
@@ -60,17 +96,55 @@ const (
 
 	// ConfigFileName is the name of the config file (without extension)
 	ConfigFileName = "config"
+
+	// ProfilesKey is the config section holding named profiles
+	ProfilesKey = "profiles"
+
+	// EncryptionKey is the config section mapping notification types to encryption passwords
+	EncryptionKey = "encryption"
 )
 
+// TokenKeys are the config keys a token can be read from, in order of precedence
+var TokenKeys = []string{"token", "token_file", "token_command"}
+
+// activeProfile is the profile selected with SetProfile ("" for none)
+var activeProfile string
+
 // Config represents the Pincho CLI configuration
 type Config struct {
-	Token       string   `mapstructure:"token"`
-	ID          string   `mapstructure:"id"`
-	APIURL      string   `mapstructure:"api_url"`
-	Timeout     int      `mapstructure:"timeout"`      // HTTP request timeout in seconds
-	MaxRetries  int      `mapstructure:"max_retries"`  // Maximum number of retry attempts
-	DefaultType string   `mapstructure:"default_type"` // Default notification type
-	DefaultTags []string `mapstructure:"default_tags"` // Default tags to include with all notifications
+	Token        string   `mapstructure:"token"`
+	TokenFile    string   `mapstructure:"token_file"`    // File to read the token from if Token is empty
+	TokenCommand string   `mapstructure:"token_command"` // Command printing the token if Token and TokenFile are empty
+	ID           string   `mapstructure:"id"`
+	APIURL       string   `mapstructure:"api_url"`      // Legacy send endpoint URL
+	BaseURL      string   `mapstructure:"base_url"`     // Base URL that endpoint paths are resolved against
+	Timeout      int      `mapstructure:"timeout"`      // HTTP request timeout in seconds
+	MaxRetries   int      `mapstructure:"max_retries"`  // Maximum number of retry attempts
+	DefaultType  string   `mapstructure:"default_type"` // Default notification type
+	DefaultTags  []string `mapstructure:"default_tags"` // Default tags to include with all notifications
+
+	MaxRetriesSet bool `mapstructure:"-"` // Whether MaxRetries was configured, so that 0 disables retries
+
+	IdempotencyWindow time.Duration `mapstructure:"idempotency_window"` // How long a sent idempotency key is refused locally
+	BreakerThreshold  int           `mapstructure:"breaker_threshold"`  // Consecutive failures before the circuit breaker opens
+	BreakerCooldown   time.Duration `mapstructure:"breaker_cooldown"`   // How long an open circuit breaker fails fast
+	DedupWindow       time.Duration `mapstructure:"dedup_window"`       // How long an identical notification is suppressed
+	Outbox            bool          `mapstructure:"outbox"`             // Queue sends that fail with a transient error
+
+	Endpoints  map[string]string     `mapstructure:"endpoints"`  // Per-endpoint path or absolute URL overrides
+	Encryption map[string]Encryption `mapstructure:"encryption"` // Encryption password sources by notification type
+}
+
+// Encryption is the entry of a notification type in the encryption section
+// Notifications of the type are encrypted with the password read from
+// PasswordEnv, PasswordFile or PasswordCommand, the first one set, so it
+// never has to be stored in the config file.
+type Encryption struct {
+	PasswordEnv     string `mapstructure:"password_env"`     // Environment variable holding the password
+	PasswordFile    string `mapstructure:"password_file"`    // File to read the password from
+	PasswordCommand string `mapstructure:"password_command"` // Command printing the password
+	Scheme          string `mapstructure:"scheme"`           // Encryption scheme (default v1)
+	PadTo           int    `mapstructure:"pad_to"`           // Pad messages to a multiple of this many bytes (0 disables)
 }
 
 // GetConfigDir returns the path to the config directory
@@ -134,7 +208,162 @@ func InitConfig() error {
 	return nil
 }
 
+// SetProfile selects the profile whose values override the top-level settings
+// An empty name selects no profile. Names are case-insensitive.
+func SetProfile(name string) {
+	activeProfile = strings.ToLower(name)
+}
+
+// ActiveProfile returns the name of the selected profile, or "" if none is selected
+func ActiveProfile() string {
+	return activeProfile
+}
+
+// ProfileKey returns the config key of a setting inside the named profile
+func ProfileKey(profile, key string) string {
+	return ProfilesKey + "." + strings.ToLower(profile) + "." + key
+}
+
+// Key returns the config key to read a setting from: the active profile's
+// key if the profile sets it, the top-level key otherwise
+// A setting given by its environment variable always reads the top-level key,
+// which Viper resolves from the environment, so env overrides the profile.
+func Key(key string) string {
+	if activeProfile != "" && !envSet(key) {
+		if profileKey := ProfileKey(activeProfile, key); viper.IsSet(profileKey) {
+			return profileKey
+		}
+	}
+	return key
+}
+
+// TokenKey returns the config key to read a token setting from
+// If the active profile sets any of TokenKeys, only the profile's keys are
+// used, so a token_command in a profile is not shadowed by a top-level token.
+// A token given by an environment variable reads the top-level keys.
+func TokenKey(key string) string {
+	if activeProfile == "" {
+		return key
+	}
+	for _, k := range TokenKeys {
+		if envSet(k) {
+			return key
+		}
+	}
+	if ProfileSetsToken(activeProfile) {
+		return ProfileKey(activeProfile, key)
+	}
+	return key
+}
+
+// ProfileSetsToken reports whether the named profile sets any of TokenKeys
+// Commands addressing a profile explicitly, rather than as the active profile,
+// read its token from these keys only, regardless of the environment.
+func ProfileSetsToken(profile string) bool {
+	for _, k := range TokenKeys {
+		if viper.IsSet(ProfileKey(profile, k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// envSet reports whether the environment variable of a top-level setting is set
+// Empty variables are ignored, as Viper does.
+func envSet(key string) bool {
+	return os.Getenv("PINCHO_"+strings.ToUpper(key)) != ""
+}
+
+// profileSettings returns the settings of the active profile, with the
+// environment value in place of any setting given by its environment variable
+func profileSettings() map[string]interface{} {
+	settings := viper.GetStringMap(ProfilesKey + "." + activeProfile)
+	for key := range settings {
+		if envSet(key) {
+			settings[key] = viper.Get(key)
+		}
+	}
+	return settings
+}
+
+// ProfileNames returns the sorted names of the profiles in the config file
+func ProfileNames() []string {
+	profiles := viper.GetStringMap(ProfilesKey)
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileExists reports whether the named profile is defined in the config file
+func ProfileExists(name string) bool {
+	_, ok := viper.GetStringMap(ProfilesKey)[strings.ToLower(name)]
+	return ok
+}
+
+// ResolveToken reads the token from TokenFile or TokenCommand if Token is empty
+func (c *Config) ResolveToken(ctx context.Context) error {
+	var err error
+	switch {
+	case c.Token != "":
+	case c.TokenFile != "":
+		c.Token, err = secrets.ReadFile(c.TokenFile)
+	case c.TokenCommand != "":
+		c.Token, err = secrets.RunCommand(ctx, c.TokenCommand)
+	}
+	return err
+}
+
+// EncryptionFor returns the encryption entry of a notification type, honoring the active profile
+// The boolean reports whether the type has an entry, that is whether its
+// notifications are meant to be encrypted.
+func EncryptionFor(notifType string) (Encryption, bool, error) {
+	if notifType == "" {
+		return Encryption{}, false, nil
+	}
+
+	var entries map[string]Encryption
+	if err := viper.UnmarshalKey(Key(EncryptionKey), &entries); err != nil {
+		return Encryption{}, false, fmt.Errorf("invalid encryption section in config: %w", err)
+	}
+
+	// Viper lowercases keys, so types are matched case-insensitively
+	entry, ok := entries[strings.ToLower(notifType)]
+	return entry, ok, nil
+}
+
+// ResolvePassword reads the password from the entry's source
+// An unset environment variable or an entry without a source yields an
+// empty password; an unreadable file or a failing command is an error.
+func (e Encryption) ResolvePassword(ctx context.Context) (string, error) {
+	switch {
+	case e.PasswordEnv != "":
+		return os.Getenv(e.PasswordEnv), nil
+	case e.PasswordFile != "":
+		return secrets.ReadFile(e.PasswordFile)
+	case e.PasswordCommand != "":
+		return secrets.RunCommand(ctx, e.PasswordCommand)
+	}
+	return "", nil
+}
+
+// Source describes where the entry's password is read from, for messages
+func (e Encryption) Source() string {
+	switch {
+	case e.PasswordEnv != "":
+		return "environment variable " + e.PasswordEnv
+	case e.PasswordFile != "":
+		return "file " + e.PasswordFile
+	case e.PasswordCommand != "":
+		return fmt.Sprintf("command %q", e.PasswordCommand)
+	}
+	return "no password source"
+}
+
 // Load loads the configuration from file and environment
+// Token files and commands are not read; call ResolveToken for that.
 func Load() (*Config, error) {
 	if err := InitConfig(); err != nil {
 		return nil, err
@@ -145,6 +374,29 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Values set in the active profile override the top-level values, but
+	// not the environment
+	if activeProfile != "" {
+		settings := profileSettings()
+		if TokenKey("token") == "token" {
+			for _, k := range TokenKeys {
+				delete(settings, k)
+			}
+		} else {
+			// The profile's token keys replace all top-level ones
+			cfg.Token, cfg.TokenFile, cfg.TokenCommand = "", "", ""
+		}
+
+		profile := viper.New()
+		if err := profile.MergeConfigMap(settings); err != nil {
+			return nil, fmt.Errorf("failed to read profile %s: %w", activeProfile, err)
+		}
+		if err := profile.Unmarshal(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal profile %s: %w", activeProfile, err)
+		}
+	}
+	cfg.MaxRetriesSet = viper.IsSet(Key("max_retries"))
+
 	return &cfg, nil
 }
 
@@ -178,21 +430,32 @@ func Set(key, value string) error {
 	return nil
 }
 
-// Get retrieves a configuration value
+// Get retrieves a configuration value, honoring the active profile
 func Get(key string) (string, error) {
 	if err := InitConfig(); err != nil {
 		return "", err
 	}
 
-	value := viper.GetString(key)
+	value := viper.GetString(Key(key))
 	return value, nil
 }
 
 // GetAll returns all configuration values
+// With a profile active, the profile's values are merged over the top-level
+// values and the profiles section is omitted.
 func GetAll() (map[string]interface{}, error) {
 	if err := InitConfig(); err != nil {
 		return nil, err
 	}
 
-	return viper.AllSettings(), nil
+	all := viper.AllSettings()
+	if activeProfile == "" {
+		return all, nil
+	}
+
+	delete(all, ProfilesKey)
+	for key, value := range profileSettings() {
+		all[key] = value
+	}
+	return all, nil
 }
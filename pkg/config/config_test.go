@@ -1,10 +1,12 @@
 package config
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -317,6 +319,54 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestLoadDuration(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if err := Set("idempotency_window", "72h"); err != nil {
+		t.Fatalf("Set(idempotency_window) failed: %v", err)
+	}
+
+	// Reset viper to force re-read
+	viper.Reset()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.IdempotencyWindow != 72*time.Hour {
+		t.Errorf("Load() idempotency_window = %v, want %v", cfg.IdempotencyWindow, 72*time.Hour)
+	}
+}
+
+func TestLoadMaxRetries(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.MaxRetries != 0 || cfg.MaxRetriesSet {
+		t.Errorf("Load() max_retries = %d (set %v), want 0 and unset", cfg.MaxRetries, cfg.MaxRetriesSet)
+	}
+
+	// Zero must be distinguishable from unset, it disables retries
+	if err := Set("max_retries", "0"); err != nil {
+		t.Fatalf("Set(max_retries) failed: %v", err)
+	}
+	viper.Reset()
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.MaxRetries != 0 || !cfg.MaxRetriesSet {
+		t.Errorf("Load() max_retries = %d (set %v), want 0 and set", cfg.MaxRetries, cfg.MaxRetriesSet)
+	}
+}
+
 func TestLoadWithoutConfigFile(t *testing.T) {
 	_, cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -373,3 +423,247 @@ func TestSetOverwritesExistingValue(t *testing.T) {
 		t.Errorf("Updated Get(token) = %q, want %q", value, "updated-token")
 	}
 }
+
+func TestProfiles(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer SetProfile("")
+
+	for key, value := range map[string]string{
+		"token":                              "top-token",
+		"timeout":                            "30",
+		"default_type":                       "info",
+		ProfileKey("OnCall", "token"):        "oncall-token",
+		ProfileKey("oncall", "default_type"): "alert",
+		ProfileKey("staging", "base_url"):    "https://staging.example.com",
+	} {
+		if err := Set(key, value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+	}
+	viper.Reset()
+	if err := InitConfig(); err != nil {
+		t.Fatalf("InitConfig() failed: %v", err)
+	}
+
+	if names := ProfileNames(); len(names) != 2 || names[0] != "oncall" || names[1] != "staging" {
+		t.Errorf("ProfileNames() = %v, want [oncall staging]", names)
+	}
+	if !ProfileExists("OnCall") || ProfileExists("missing") {
+		t.Error("ProfileExists() returned unexpected results")
+	}
+
+	// Without a profile, top-level values apply
+	if key := Key("token"); key != "token" {
+		t.Errorf("Key(token) = %q without a profile, want token", key)
+	}
+
+	SetProfile("OnCall")
+	if ActiveProfile() != "oncall" {
+		t.Errorf("ActiveProfile() = %q, want oncall", ActiveProfile())
+	}
+	if key := Key("token"); key != "profiles.oncall.token" {
+		t.Errorf("Key(token) = %q, want profiles.oncall.token", key)
+	}
+	if key := Key("timeout"); key != "timeout" {
+		t.Errorf("Key(timeout) = %q, want inherited top-level key", key)
+	}
+
+	value, err := Get("default_type")
+	if err != nil || value != "alert" {
+		t.Errorf("Get(default_type) = %q, %v; want alert", value, err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Token != "oncall-token" || cfg.DefaultType != "alert" || cfg.Timeout != 30 {
+		t.Errorf("Load() = token %q, type %q, timeout %d; want profile values over inherited ones",
+			cfg.Token, cfg.DefaultType, cfg.Timeout)
+	}
+
+	all, err := GetAll()
+	if err != nil {
+		t.Fatalf("GetAll() failed: %v", err)
+	}
+	if _, ok := all[ProfilesKey]; ok {
+		t.Error("GetAll() with a profile should omit the profiles section")
+	}
+	if all["token"] != "oncall-token" || all["timeout"] != "30" {
+		t.Errorf("GetAll() = %v, want merged profile and top-level values", all)
+	}
+}
+
+func TestProfileEnvPrecedence(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer SetProfile("")
+
+	for key, value := range map[string]string{
+		ProfileKey("oncall", "timeout"):      "5",
+		ProfileKey("oncall", "default_type"): "alert",
+	} {
+		if err := Set(key, value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+	}
+	viper.Reset()
+	t.Setenv("PINCHO_TIMEOUT", "99")
+	SetProfile("oncall")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Timeout != 99 || cfg.DefaultType != "alert" {
+		t.Errorf("Load() = timeout %d, type %q; want env timeout 99 over the profile and profile type alert",
+			cfg.Timeout, cfg.DefaultType)
+	}
+	if key := Key("timeout"); key != "timeout" {
+		t.Errorf("Key(timeout) = %q with PINCHO_TIMEOUT set, want top-level key", key)
+	}
+	if value, err := Get("timeout"); err != nil || value != "99" {
+		t.Errorf("Get(timeout) = %q, %v; want 99", value, err)
+	}
+}
+
+func TestProfileTokenKeys(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer SetProfile("")
+
+	path := filepath.Join(t.TempDir(), "pincho_token")
+	if err := os.WriteFile(path, []byte("profile-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	for key, value := range map[string]string{
+		"token":                           "toplevel",
+		ProfileKey("ci", "token_file"):    path,
+		ProfileKey("staging", "base_url"): "https://staging.example.com",
+	} {
+		if err := Set(key, value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", key, err)
+		}
+	}
+	viper.Reset()
+
+	// A profile setting any token key replaces the top-level token
+	SetProfile("ci")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if err := cfg.ResolveToken(context.Background()); err != nil || cfg.Token != "profile-token" {
+		t.Errorf("ResolveToken() = %q, %v; want token from the profile's token_file", cfg.Token, err)
+	}
+	if key := TokenKey("token"); key != ProfileKey("ci", "token") {
+		t.Errorf("TokenKey(token) = %q, want the profile's key", key)
+	}
+
+	// A token in the environment wins for the active profile, but the profile
+	// still owns its token keys for commands addressing it explicitly
+	t.Setenv("PINCHO_TOKEN", "env-token")
+	if key := TokenKey("token"); key != "token" {
+		t.Errorf("TokenKey(token) = %q with PINCHO_TOKEN set, want the top-level key", key)
+	}
+	if !ProfileSetsToken("ci") || ProfileSetsToken("staging") {
+		t.Error("ProfileSetsToken() should only depend on the profile's own keys")
+	}
+	os.Unsetenv("PINCHO_TOKEN")
+
+	// A profile without token keys inherits the top-level token
+	SetProfile("staging")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Token != "toplevel" || cfg.TokenFile != "" {
+		t.Errorf("Load() = token %q, token_file %q; want the top-level token", cfg.Token, cfg.TokenFile)
+	}
+}
+
+func TestResolveToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pincho_token")
+	if err := os.WriteFile(path, []byte("file-token\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	cfg := &Config{Token: "plain-token", TokenFile: path}
+	if err := cfg.ResolveToken(context.Background()); err != nil || cfg.Token != "plain-token" {
+		t.Errorf("ResolveToken() = %q, %v; want plain token kept", cfg.Token, err)
+	}
+
+	cfg = &Config{TokenFile: path, TokenCommand: "exit 1"}
+	if err := cfg.ResolveToken(context.Background()); err != nil || cfg.Token != "file-token" {
+		t.Errorf("ResolveToken() = %q, %v; want token from file", cfg.Token, err)
+	}
+
+	cfg = &Config{TokenFile: filepath.Join(t.TempDir(), "missing")}
+	if err := cfg.ResolveToken(context.Background()); err == nil {
+		t.Error("ResolveToken() expected error for missing token file")
+	}
+}
+
+func TestEncryptionFor(t *testing.T) {
+	_, cleanup := setupTestEnv(t)
+	defer cleanup()
+	defer SetProfile("")
+
+	viper.Set(EncryptionKey, map[string]any{
+		"secure": map[string]any{"password_env": "PINCHO_TEST_SECURE_PASSWORD", "scheme": "v2"},
+	})
+	viper.Set(ProfileKey("oncall", EncryptionKey), map[string]any{
+		"alert": map[string]any{"password_command": "echo oncall"},
+	})
+
+	entry, ok, err := EncryptionFor("Secure")
+	if err != nil || !ok || entry.PasswordEnv != "PINCHO_TEST_SECURE_PASSWORD" || entry.Scheme != "v2" {
+		t.Errorf("EncryptionFor(Secure) = %+v, %v, %v; want the secure entry", entry, ok, err)
+	}
+	if _, ok, _ := EncryptionFor("info"); ok {
+		t.Error("EncryptionFor(info) should report no entry")
+	}
+	if _, ok, _ := EncryptionFor(""); ok {
+		t.Error("EncryptionFor(\"\") should report no entry")
+	}
+
+	// A profile's encryption section replaces the top-level one
+	SetProfile("oncall")
+	if _, ok, _ := EncryptionFor("secure"); ok {
+		t.Error("EncryptionFor(secure) in profile oncall should report no entry")
+	}
+	if entry, ok, _ := EncryptionFor("alert"); !ok || entry.PasswordCommand != "echo oncall" {
+		t.Errorf("EncryptionFor(alert) = %+v, %v; want the profile's entry", entry, ok)
+	}
+}
+
+func TestEncryptionResolvePassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secure_password")
+	if err := os.WriteFile(path, []byte("file-password\n"), 0600); err != nil {
+		t.Fatalf("failed to write password file: %v", err)
+	}
+	t.Setenv("PINCHO_TEST_SECURE_PASSWORD", "env-password")
+
+	tests := []struct {
+		name  string
+		entry Encryption
+		want  string
+	}{
+		{"env", Encryption{PasswordEnv: "PINCHO_TEST_SECURE_PASSWORD", PasswordFile: path}, "env-password"},
+		{"unset env", Encryption{PasswordEnv: "PINCHO_TEST_UNSET_PASSWORD"}, ""},
+		{"file", Encryption{PasswordFile: path}, "file-password"},
+		{"no source", Encryption{Scheme: "v2"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := tt.entry.ResolvePassword(context.Background())
+		if err != nil || got != tt.want {
+			t.Errorf("%s: ResolvePassword() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	missing := Encryption{PasswordFile: filepath.Join(t.TempDir(), "missing")}
+	if _, err := missing.ResolvePassword(context.Background()); err == nil {
+		t.Error("ResolvePassword() expected error for missing password file")
+	}
+}
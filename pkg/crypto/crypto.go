@@ -20,9 +20,26 @@
 //	encrypted, err := crypto.EncryptMessage("sensitive data", "password", ivBytes)
 //	// Send encrypted message and ivHex to API
 //
+//	plaintext, err := crypto.DecryptMessage(encrypted, "password", ivBytes)
+//
 // Note: SHA1 is used for key derivation to maintain compatibility with the
 // existing Pincho app implementation. For new implementations, consider
 // using PBKDF2 or Argon2.
+//
+// Versioned scheme (opt-in):
+//
+// SchemeV2 derives a 256-bit key with salted PBKDF2-HMAC-SHA256 and encrypts
+// with AES-256-GCM. The message starts with a "v2:" marker and carries the
+// salt and iteration count, so DecryptMessage detects the scheme on its own:
+//
+//	encrypted, err := crypto.Encrypt("sensitive data", "password", crypto.SchemeV2, ivBytes)
+//	plaintext, err := crypto.DecryptMessage(encrypted, "password", ivBytes)
+//
+// Length hiding (opt-in):
+//
+// PadMessage pads the text with an invisible marker and trailing spaces to a
+// bucket size before encryption, so the encrypted length no longer reveals
+// the text length; StripPadding removes them after decryption.
 package crypto
 
 import (
@@ -50,6 +67,14 @@ func CustomBase64Encode(data []byte) string {
 	return custom
 }
 
+// CustomBase64Decode decodes a string produced by CustomBase64Encode.
+func CustomBase64Decode(encoded string) ([]byte, error) {
+	standard := strings.ReplaceAll(encoded, "-", "+")
+	standard = strings.ReplaceAll(standard, ".", "/")
+	standard = strings.ReplaceAll(standard, "_", "=")
+	return base64.StdEncoding.DecodeString(standard)
+}
+
 // DeriveEncryptionKey derives AES encryption key from password using SHA1.
 //
 // Key derivation process:
@@ -81,6 +106,24 @@ func pkcs7Pad(data []byte, blockSize int) []byte {
 	return append(data, padding...)
 }
 
+// pkcs7Unpad removes PKCS7 padding from data.
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("invalid padded length %d", len(data))
+	}
+
+	padLength := int(data[len(data)-1])
+	if padLength == 0 || padLength > blockSize {
+		return nil, fmt.Errorf("invalid padding")
+	}
+	for _, b := range data[len(data)-padLength:] {
+		if int(b) != padLength {
+			return nil, fmt.Errorf("invalid padding")
+		}
+	}
+	return data[:len(data)-padLength], nil
+}
+
 // EncryptMessage encrypts text using AES-128-CBC with custom Base64 encoding.
 //
 // Encryption process matching Pincho app:
@@ -115,6 +158,62 @@ func EncryptMessage(plaintext, password string, iv []byte) (string, error) {
 	return CustomBase64Encode(encrypted), nil
 }
 
+// DecryptMessage decrypts a message produced by EncryptMessage or EncryptMessageV2.
+//
+// The scheme is detected from the message. Returns an error if the ciphertext
+// is malformed or the padding is invalid, which usually means the password is
+// wrong. For v1 messages, a wrong password can still decrypt to garbage with
+// valid padding by chance (about 1 in 256); v2 messages are authenticated.
+func DecryptMessage(encrypted, password string, iv []byte) (string, error) {
+	if DetectScheme(encrypted) == SchemeV2 {
+		return decryptV2(encrypted, password, iv)
+	}
+
+	ciphertext, err := CustomBase64Decode(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode message: %w", err)
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", fmt.Errorf("invalid ciphertext length %d", len(ciphertext))
+	}
+	if len(iv) != aes.BlockSize {
+		return "", fmt.Errorf("invalid IV length %d (expected %d)", len(iv), aes.BlockSize)
+	}
+
+	key, err := DeriveEncryptionKey(password)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	decrypted := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, ciphertext)
+
+	plaintext, err := pkcs7Unpad(decrypted, aes.BlockSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt message (wrong password?): %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// ParseIV decodes a hex-encoded initialization vector as sent to the API.
+//
+// Returns an error unless ivHex encodes exactly 16 bytes.
+func ParseIV(ivHex string) ([]byte, error) {
+	iv, err := hex.DecodeString(strings.TrimSpace(ivHex))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IV: %w", err)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid IV length %d (expected %d bytes, %d hex characters)", len(iv), aes.BlockSize, 2*aes.BlockSize)
+	}
+	return iv, nil
+}
+
 // GenerateIV generates a random 16-byte initialization vector.
 //
 // Returns IV bytes and hexadecimal string representation (32 characters).
@@ -220,3 +220,95 @@ func TestEncryptMessage_InterSDKCompatibility(t *testing.T) {
 		t.Errorf("EncryptMessage() = %s, want %s (inter-SDK compatibility failed)", encrypted, expected)
 	}
 }
+
+func TestCustomBase64Decode(t *testing.T) {
+	data := []byte{0xfb, 0xff, 0xfe, 0x00, 0x01}
+	decoded, err := CustomBase64Decode(CustomBase64Encode(data))
+	if err != nil {
+		t.Fatalf("CustomBase64Decode() error = %v", err)
+	}
+	if string(decoded) != string(data) {
+		t.Errorf("CustomBase64Decode() = %v, want %v", decoded, data)
+	}
+
+	if _, err := CustomBase64Decode("not base64!"); err == nil {
+		t.Error("CustomBase64Decode() expected error for invalid input")
+	}
+}
+
+func TestDecryptMessage(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+
+	for _, plaintext := range []string{"", "Hello, World!", "exactly 16 bytes", "unicode ✓ message"} {
+		encrypted, err := EncryptMessage(plaintext, "test-password", iv)
+		if err != nil {
+			t.Fatalf("EncryptMessage() error = %v", err)
+		}
+
+		decrypted, err := DecryptMessage(encrypted, "test-password", iv)
+		if err != nil {
+			t.Fatalf("DecryptMessage(%q) error = %v", plaintext, err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("DecryptMessage() = %q, want %q", decrypted, plaintext)
+		}
+	}
+}
+
+func TestDecryptMessage_Errors(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+	encrypted, err := EncryptMessage("Hello, World!", "test-password", iv)
+	if err != nil {
+		t.Fatalf("EncryptMessage() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		encrypted string
+		password  string
+		iv        []byte
+	}{
+		{"invalid base64", "not base64!", "test-password", iv},
+		{"truncated ciphertext", CustomBase64Encode([]byte("short")), "test-password", iv},
+		{"short IV", encrypted, "test-password", iv[:8]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptMessage(tt.encrypted, tt.password, tt.iv); err == nil {
+				t.Error("DecryptMessage() expected error")
+			}
+		})
+	}
+}
+
+func TestDecryptMessage_InterSDKCompatibility(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+	encrypted := "y2fzGqnZSgdMqkwYhAUEZi30VFBYvwcCmrQ6BmSliPpPGHXMdMRsLCtG-cfwhhxN4HSIk5Y3UMjM6XoBWPqiHw__"
+
+	decrypted, err := DecryptMessage(encrypted, "test_password_123", iv)
+	if err != nil {
+		t.Fatalf("DecryptMessage() error = %v", err)
+	}
+
+	expected := "This is a secret message that needs to be encrypted securely."
+	if decrypted != expected {
+		t.Errorf("DecryptMessage() = %q, want %q (inter-SDK compatibility failed)", decrypted, expected)
+	}
+}
+
+func TestParseIV(t *testing.T) {
+	iv, err := ParseIV(" 0123456789abcdef0123456789ABCDEF\n")
+	if err != nil {
+		t.Fatalf("ParseIV() error = %v", err)
+	}
+	if hex.EncodeToString(iv) != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("ParseIV() = %x", iv)
+	}
+
+	for _, bad := range []string{"", "xyz", "0123456789abcdef"} {
+		if _, err := ParseIV(bad); err == nil {
+			t.Errorf("ParseIV(%q) expected error", bad)
+		}
+	}
+}
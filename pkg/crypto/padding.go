@@ -0,0 +1,47 @@
+package crypto
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxPadTo is the largest padding bucket accepted by PadMessage
+const MaxPadTo = 65536
+
+// paddingMarker separates a padded text from its padding
+// A zero width space is invisible in the app, like the spaces after it.
+const paddingMarker = "\u200b"
+
+// PadMessage pads text to a multiple of bucket bytes.
+//
+// The length of an encrypted message follows the length of its text, so
+// "OK" can be told apart from a long incident report without decrypting it.
+// Padded to a bucket, only the number of buckets the text spans shows. The
+// padding is an invisible marker followed by spaces, and the app displays
+// the decrypted text as is, so padded messages need no support from the app.
+// StripPadding removes the padding again, keeping any trailing whitespace of
+// the text itself. A bucket of 0 leaves text unchanged.
+func PadMessage(text string, bucket int) (string, error) {
+	if bucket < 0 || bucket > MaxPadTo {
+		return "", fmt.Errorf("invalid padding bucket %d (must be between 0 and %d)", bucket, MaxPadTo)
+	}
+	if bucket == 0 || (len(text)%bucket == 0 && StripPadding(text) == text) {
+		return text, nil
+	}
+
+	padded := text + paddingMarker
+	if remainder := len(padded) % bucket; remainder != 0 {
+		padded += strings.Repeat(" ", bucket-remainder)
+	}
+	return padded, nil
+}
+
+// StripPadding removes the padding added by PadMessage
+// Text without padding is returned unchanged.
+func StripPadding(text string) string {
+	trimmed := strings.TrimRight(text, " ")
+	if !strings.HasSuffix(trimmed, paddingMarker) {
+		return text
+	}
+	return strings.TrimSuffix(trimmed, paddingMarker)
+}
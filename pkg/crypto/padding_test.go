@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPadMessage(t *testing.T) {
+	tests := []struct {
+		text    string
+		bucket  int
+		wantLen int
+	}{
+		{"OK", 0, 2},
+		{"OK", 256, 256},
+		{strings.Repeat("a", 256), 256, 256},
+		{strings.Repeat("a", 257), 256, 512},
+		{"héllo", 16, 16}, // Counted in bytes, not characters
+		{"done  ", 16, 16},
+		{"tabs and newline\t\n", 16, 32},
+		{strings.Repeat("a", 255) + " ", 256, 256},
+		{"ends with a marker\u200b  ", 16, 32},
+	}
+	for _, tt := range tests {
+		padded, err := PadMessage(tt.text, tt.bucket)
+		if err != nil {
+			t.Fatalf("PadMessage(%q, %d) failed: %v", tt.text, tt.bucket, err)
+		}
+		if len(padded) != tt.wantLen {
+			t.Errorf("PadMessage(%q, %d) length = %d, want %d", tt.text, tt.bucket, len(padded), tt.wantLen)
+		}
+		if got := StripPadding(padded); got != tt.text {
+			t.Errorf("StripPadding(PadMessage(%q, %d)) = %q", tt.text, tt.bucket, got)
+		}
+	}
+
+	for _, bucket := range []int{-1, MaxPadTo + 1} {
+		if _, err := PadMessage("OK", bucket); err == nil {
+			t.Errorf("PadMessage(OK, %d) expected error", bucket)
+		}
+	}
+}
+
+func TestStripPadding_KeepsTrailingWhitespace(t *testing.T) {
+	for _, text := range []string{"OK", "OK  ", "OK\t", "line\n\n"} {
+		if got := StripPadding(text); got != text {
+			t.Errorf("StripPadding(%q) = %q, want it unchanged", text, got)
+		}
+	}
+}
+
+func TestPadMessage_HidesLength(t *testing.T) {
+	iv := make([]byte, 16)
+	short, _ := PadMessage("OK", 256)
+	long, _ := PadMessage(strings.Repeat("Incident report. ", 14), 256)
+
+	for _, scheme := range []Scheme{SchemeV1, SchemeV2} {
+		shortEnc, err := Encrypt(short, "password", scheme, iv)
+		if err != nil {
+			t.Fatalf("Encrypt(%s) failed: %v", scheme, err)
+		}
+		longEnc, err := Encrypt(long, "password", scheme, iv)
+		if err != nil {
+			t.Fatalf("Encrypt(%s) failed: %v", scheme, err)
+		}
+		if len(shortEnc) != len(longEnc) {
+			t.Errorf("%s: padded messages encrypt to %d and %d characters, want equal", scheme, len(shortEnc), len(longEnc))
+		}
+
+		// A decryptor unaware of the padding gets the text followed by invisible padding
+		decrypted, err := DecryptMessage(shortEnc, "password", iv)
+		if err != nil {
+			t.Fatalf("DecryptMessage(%s) failed: %v", scheme, err)
+		}
+		if !strings.HasPrefix(decrypted, "OK"+paddingMarker+" ") || StripPadding(decrypted) != "OK" {
+			t.Errorf("%s: decrypted padded message = %q, want OK followed by padding", scheme, decrypted)
+		}
+	}
+}
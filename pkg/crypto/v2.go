@@ -0,0 +1,167 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Scheme identifies the encryption scheme of a message.
+type Scheme string
+
+const (
+	// SchemeV1 is AES-128-CBC with an unsalted SHA1-derived key, as used by the
+	// Pincho app. It is the default for compatibility.
+	SchemeV1 Scheme = "v1"
+
+	// SchemeV2 is AES-256-GCM with a key derived by salted PBKDF2-HMAC-SHA256.
+	// Messages are authenticated, so a wrong password or a modified message is
+	// always detected. Opt-in: the recipient must support it.
+	SchemeV2 Scheme = "v2"
+)
+
+// v2 envelope parameters
+const (
+	// v2Prefix marks a v2 message. ':' is not in the custom Base64 alphabet,
+	// so a v1 message can never start with it.
+	v2Prefix = "v2:"
+
+	// V2Iterations is the PBKDF2 iteration count of new v2 messages
+	V2Iterations = 600000
+
+	// v2MaxIterations bounds the iteration count accepted when decrypting,
+	// so a crafted message cannot make decryption hang
+	v2MaxIterations = 10000000
+
+	v2SaltSize  = 16
+	v2NonceSize = 12 // Taken from the start of the 16-byte IV
+	v2KeySize   = 32
+)
+
+// ParseScheme parses a scheme name ("v1" or "v2"); an empty name is SchemeV1.
+func ParseScheme(name string) (Scheme, error) {
+	switch Scheme(strings.ToLower(name)) {
+	case "", SchemeV1:
+		return SchemeV1, nil
+	case SchemeV2:
+		return SchemeV2, nil
+	default:
+		return "", fmt.Errorf("unknown encryption scheme %q (supported: v1, v2)", name)
+	}
+}
+
+// DetectScheme returns the scheme an encrypted message was produced with.
+func DetectScheme(encrypted string) Scheme {
+	if strings.HasPrefix(encrypted, v2Prefix) {
+		return SchemeV2
+	}
+	return SchemeV1
+}
+
+// Encrypt encrypts text with the given scheme and a 16-byte IV from GenerateIV.
+func Encrypt(plaintext, password string, scheme Scheme, iv []byte) (string, error) {
+	switch scheme {
+	case "", SchemeV1:
+		return EncryptMessage(plaintext, password, iv)
+	case SchemeV2:
+		return EncryptMessageV2(plaintext, password, iv)
+	default:
+		return "", fmt.Errorf("unknown encryption scheme %q", scheme)
+	}
+}
+
+// EncryptMessageV2 encrypts text using the v2 envelope.
+//
+// Envelope format:
+//
+//	"v2:" + CustomBase64(iterations (uint32, big endian) || salt (16) || ciphertext || tag (16))
+//
+// The key is derived with PBKDF2-HMAC-SHA256 from the password and a random
+// salt, and the message is encrypted with AES-256-GCM using the first 12
+// bytes of the IV as nonce. The version marker and iteration count are
+// authenticated along with the message.
+func EncryptMessageV2(plaintext, password string, iv []byte) (string, error) {
+	salt := make([]byte, v2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return encryptV2(plaintext, password, iv, salt, V2Iterations)
+}
+
+// encryptV2 builds a v2 envelope with the given salt and iteration count
+func encryptV2(plaintext, password string, iv, salt []byte, iterations uint32) (string, error) {
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	if len(iv) != aes.BlockSize {
+		return "", fmt.Errorf("invalid IV length %d (expected %d)", len(iv), aes.BlockSize)
+	}
+
+	header := make([]byte, 4, 4+len(salt))
+	binary.BigEndian.PutUint32(header, iterations)
+	header = append(header, salt...)
+
+	gcm, err := newV2Cipher(password, salt, iterations)
+	if err != nil {
+		return "", err
+	}
+
+	envelope := gcm.Seal(header, iv[:v2NonceSize], []byte(plaintext), v2AdditionalData(header))
+	return v2Prefix + CustomBase64Encode(envelope), nil
+}
+
+// decryptV2 opens a v2 envelope
+func decryptV2(encrypted, password string, iv []byte) (string, error) {
+	envelope, err := CustomBase64Decode(strings.TrimPrefix(encrypted, v2Prefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode message: %w", err)
+	}
+	if len(envelope) < 4+v2SaltSize+16 {
+		return "", fmt.Errorf("invalid v2 message length %d", len(envelope))
+	}
+	if len(iv) != aes.BlockSize {
+		return "", fmt.Errorf("invalid IV length %d (expected %d)", len(iv), aes.BlockSize)
+	}
+
+	iterations := binary.BigEndian.Uint32(envelope)
+	if iterations == 0 || iterations > v2MaxIterations {
+		return "", fmt.Errorf("invalid v2 iteration count %d", iterations)
+	}
+	header, ciphertext := envelope[:4+v2SaltSize], envelope[4+v2SaltSize:]
+
+	gcm, err := newV2Cipher(password, header[4:], iterations)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := gcm.Open(nil, iv[:v2NonceSize], ciphertext, v2AdditionalData(header))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt message (wrong password or modified message)")
+	}
+	return string(plaintext), nil
+}
+
+// newV2Cipher derives the v2 key and returns the AES-256-GCM cipher
+func newV2Cipher(password string, salt []byte, iterations uint32) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(password), salt, int(iterations), v2KeySize, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return gcm, nil
+}
+
+// v2AdditionalData binds the version marker and envelope header to the ciphertext
+func v2AdditionalData(header []byte) []byte {
+	return append([]byte(v2Prefix), header...)
+}
@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// PBKDF2-HMAC-SHA256 test vectors (RFC 7914 section 11 and common references)
+	tests := []struct {
+		password, salt string
+		iterations     int
+		keyLen         int
+		want           string
+	}{
+		{"password", "salt", 1, 32, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 2, 32, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+		{"password", "salt", 4096, 32, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+	}
+
+	for _, tt := range tests {
+		got := hex.EncodeToString(pbkdf2.Key([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen, sha256.New))
+		if got != tt.want {
+			t.Errorf("pbkdf2.Key(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptMessageV2_RoundTrip(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+
+	for _, plaintext := range []string{"", "Hello, World!", "unicode ✓ message"} {
+		encrypted, err := EncryptMessageV2(plaintext, "test-password", iv)
+		if err != nil {
+			t.Fatalf("EncryptMessageV2() error = %v", err)
+		}
+		if !strings.HasPrefix(encrypted, "v2:") || DetectScheme(encrypted) != SchemeV2 {
+			t.Errorf("expected v2 marker, got %q", encrypted)
+		}
+
+		decrypted, err := DecryptMessage(encrypted, "test-password", iv)
+		if err != nil {
+			t.Fatalf("DecryptMessage(%q) error = %v", plaintext, err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("DecryptMessage() = %q, want %q", decrypted, plaintext)
+		}
+	}
+}
+
+func TestEncryptMessageV2_Salted(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+	a, _ := EncryptMessageV2("same message", "test-password", iv)
+	b, _ := EncryptMessageV2("same message", "test-password", iv)
+	if a == b {
+		t.Error("expected different output for different salts")
+	}
+}
+
+func TestDecryptMessageV2_Errors(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+	salt := make([]byte, v2SaltSize)
+	encrypted, err := encryptV2("Hello, World!", "test-password", iv, salt, 1000)
+	if err != nil {
+		t.Fatalf("encryptV2() error = %v", err)
+	}
+
+	// Flip one character in the ciphertext
+	body := []byte(encrypted)
+	body[len(body)-5] ^= 1
+	tampered := string(body)
+
+	otherIV, _ := hex.DecodeString("ffffffffffffffffffffffffffffffff")
+
+	tests := []struct {
+		name      string
+		encrypted string
+		password  string
+		iv        []byte
+	}{
+		{"wrong password", encrypted, "wrong-password", iv},
+		{"modified message", tampered, "test-password", iv},
+		{"wrong IV", encrypted, "test-password", otherIV},
+		{"truncated", "v2:" + CustomBase64Encode([]byte("short")), "test-password", iv},
+		{"invalid base64", "v2:not base64!", "test-password", iv},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecryptMessage(tt.encrypted, tt.password, tt.iv); err == nil {
+				t.Error("DecryptMessage() expected error")
+			}
+		})
+	}
+}
+
+func TestEncrypt_Schemes(t *testing.T) {
+	iv, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+
+	v1, err := Encrypt("message", "password", SchemeV1, iv)
+	if err != nil || DetectScheme(v1) != SchemeV1 {
+		t.Errorf("Encrypt(v1) = %q, %v", v1, err)
+	}
+	v2, err := Encrypt("message", "password", SchemeV2, iv)
+	if err != nil || DetectScheme(v2) != SchemeV2 {
+		t.Errorf("Encrypt(v2) = %q, %v", v2, err)
+	}
+	if _, err := Encrypt("message", "password", Scheme("v3"), iv); err == nil {
+		t.Error("Encrypt(v3) expected error")
+	}
+}
+
+func TestParseScheme(t *testing.T) {
+	for name, want := range map[string]Scheme{"": SchemeV1, "v1": SchemeV1, "V2": SchemeV2} {
+		if got, err := ParseScheme(name); err != nil || got != want {
+			t.Errorf("ParseScheme(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseScheme("aes"); err == nil {
+		t.Error("ParseScheme(aes) expected error")
+	}
+}
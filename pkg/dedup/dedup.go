@@ -0,0 +1,97 @@
+// Package dedup suppresses notifications identical to one sent recently.
+//
+// A notification is identified by a key derived from the endpoint, a
+// fingerprint of the token and either a hash of its content or a key chosen
+// by the caller, so different recipients never suppress each other and the
+// content itself is never stored. Keys of notifications sent successfully
+// are recorded in a state file (~/.pincho/dedup.json) with the window they
+// were sent with; each entry expires on its own, so a run with a short
+// window never forgets notifications recorded with a longer one.
+//
+// Example usage:
+//
+//	check, err := dedup.Open(dedup.Key(token, client.EndpointSend, "", dedup.SendParts(opts)...), 10*time.Minute)
+//	if sentAt, found, _ := check.Lookup(); found {
+//		// An identical notification was sent at sentAt
+//	}
+//	// Send the notification, then
+//	err = check.Record(time.Now())
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+	"github.com/Pincho-App/pincho-cli/pkg/validation"
+)
+
+// FileName is the state file recording recently sent notifications
+const FileName = "dedup.json"
+
+// Check is the duplicate check of one notification
+type Check struct {
+	Key    string
+	Window time.Duration
+	log    *state.KeyLog
+}
+
+// New creates the check of the notification identified by key, recorded in log
+func New(log *state.KeyLog, key string, window time.Duration) *Check {
+	return &Check{Key: key, Window: window, log: log}
+}
+
+// Open creates a check recorded in the state file in the config directory
+func Open(key string, window time.Duration) (*Check, error) {
+	path, err := state.Path(FileName)
+	if err != nil {
+		return nil, err
+	}
+	return New(state.NewKeyLog(path), key, window), nil
+}
+
+// Key identifies a notification sent to endpoint with token
+// An explicit dedupKey replaces the content parts.
+func Key(token, endpoint, dedupKey string, parts ...string) string {
+	key := endpoint + ":" + client.TokenFingerprint(token) + ":"
+	if dedupKey != "" {
+		return key + "key:" + dedupKey
+	}
+	return key + "content:" + ContentHash(parts...)
+}
+
+// ContentHash hashes notification fields; the content itself is never stored
+func ContentHash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SendParts returns the fields identifying a send notification
+// Tags are normalized and sorted so that equivalent tag lists match.
+func SendParts(opts *client.SendOptions) []string {
+	tags, err := validation.NormalizeAndValidateTags(opts.Tags)
+	if err != nil {
+		tags = opts.Tags
+	}
+	tags = append([]string(nil), tags...)
+	sort.Strings(tags)
+	return []string{opts.Title, opts.Message, opts.Type, strings.Join(tags, ",")}
+}
+
+// Lookup reports when the notification was last sent within the window
+func (c *Check) Lookup() (time.Time, bool, error) {
+	return c.log.Lookup(c.Key, c.Window)
+}
+
+// Record marks the notification as sent at the given time, for the window
+func (c *Check) Record(at time.Time) error {
+	return c.log.Record(c.Key, at, c.Window)
+}
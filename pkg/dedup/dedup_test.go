@@ -0,0 +1,140 @@
+package dedup
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+func TestKey(t *testing.T) {
+	base := Key("token-a", client.EndpointSend, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "alert", Tags: []string{"db", "prod"}})...)
+
+	tests := []struct {
+		name string
+		key  string
+		same bool
+	}{
+		{
+			name: "identical content",
+			key:  Key("token-a", client.EndpointSend, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "alert", Tags: []string{"db", "prod"}})...),
+			same: true,
+		},
+		{
+			name: "tags in another order and case",
+			key:  Key("token-a", client.EndpointSend, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "alert", Tags: []string{"PROD", " db"}})...),
+			same: true,
+		},
+		{
+			name: "different message",
+			key:  Key("token-a", client.EndpointSend, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "99%", Type: "alert", Tags: []string{"db", "prod"}})...),
+		},
+		{
+			name: "different type",
+			key:  Key("token-a", client.EndpointSend, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "info", Tags: []string{"db", "prod"}})...),
+		},
+		{
+			name: "another token",
+			key:  Key("token-b", client.EndpointSend, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "alert", Tags: []string{"db", "prod"}})...),
+		},
+		{
+			name: "another endpoint",
+			key:  Key("token-a", client.EndpointNotifAI, "", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "alert", Tags: []string{"db", "prod"}})...),
+		},
+		{
+			name: "explicit key",
+			key:  Key("token-a", client.EndpointSend, "disk", SendParts(&client.SendOptions{Title: "Disk full", Message: "98%", Type: "alert", Tags: []string{"db", "prod"}})...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key == base; got != tt.same {
+				t.Errorf("key %q == %q is %v, want %v", tt.key, base, got, tt.same)
+			}
+		})
+	}
+}
+
+func TestKey_ExplicitKeyReplacesContent(t *testing.T) {
+	a := Key("token", client.EndpointSend, "disk", "Disk full", "98%")
+	b := Key("token", client.EndpointSend, "disk", "Disk full", "99%")
+	if a != b {
+		t.Errorf("expected the same key for different content with one dedup key, got %q and %q", a, b)
+	}
+}
+
+func TestKey_StoresNoSecrets(t *testing.T) {
+	key := Key("secret-token", client.EndpointSend, "", "secret title", "secret message")
+	for _, secret := range []string{"secret-token", "secret title", "secret message"} {
+		if strings.Contains(key, secret) {
+			t.Errorf("key %q contains %q", key, secret)
+		}
+	}
+}
+
+func TestContentHash_FieldBoundaries(t *testing.T) {
+	if ContentHash("ab", "c") == ContentHash("a", "bc") {
+		t.Error("expected fields to be separated in the hash")
+	}
+}
+
+func TestCheck_Window(t *testing.T) {
+	log := state.NewKeyLog(filepath.Join(t.TempDir(), FileName))
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		sentAt time.Time
+		window time.Duration
+		found  bool
+	}{
+		{"never sent", time.Time{}, 10 * time.Minute, false},
+		{"sent within the window", now.Add(-5 * time.Minute), 10 * time.Minute, true},
+		{"sent before the window", now.Add(-15 * time.Minute), 10 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := New(log, Key("token", client.EndpointSend, "", tt.name), tt.window)
+			if !tt.sentAt.IsZero() {
+				if err := check.Record(tt.sentAt); err != nil {
+					t.Fatalf("Record() failed: %v", err)
+				}
+			}
+
+			sentAt, found, err := check.Lookup()
+			if err != nil {
+				t.Fatalf("Lookup() failed: %v", err)
+			}
+			if found != tt.found {
+				t.Fatalf("Lookup() found = %v, want %v", found, tt.found)
+			}
+			if found && !sentAt.Equal(tt.sentAt) {
+				t.Errorf("Lookup() sentAt = %v, want %v", sentAt, tt.sentAt)
+			}
+		})
+	}
+}
+
+func TestCheck_ShortWindowKeepsLongerEntries(t *testing.T) {
+	log := state.NewKeyLog(filepath.Join(t.TempDir(), FileName))
+
+	long := New(log, Key("token", client.EndpointSend, "", "backup failed"), time.Hour)
+	if err := long.Record(time.Now().Add(-10 * time.Minute)); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	// A run with a one minute window records another notification
+	short := New(log, Key("token", client.EndpointSend, "", "disk full"), time.Minute)
+	if err := short.Record(time.Now()); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	if _, found, _ := long.Lookup(); !found {
+		t.Error("expected the entry recorded with a one hour window to survive a one minute run")
+	}
+}
@@ -0,0 +1,332 @@
+// Package digest aggregates many notifications into one periodic summary.
+//
+// Noisy batch jobs can add an entry to a named digest for every event instead
+// of sending a notification each time, then flush the digest on a schedule to
+// send a single summary. This keeps them well within the API rate limits.
+//
+// Entries are stored one JSON file per entry in a directory per digest
+// (~/.pincho/digests/<name>), so concurrent processes can add entries safely.
+// A flush only removes the entries it summarized; entries added while it runs
+// are kept for the next flush. Flushes of the same digest are serialized, so
+// overlapping flushes (a cron job and a manual run) never send the same
+// entries twice.
+//
+// Example usage:
+//
+//	d, err := digest.Open("nightly")
+//	_, err = d.Add(digest.Entry{Title: "Backup failed", Type: "alert", Tags: []string{"db"}})
+//
+//	err = d.Flush(func(entries []digest.Entry) error {
+//		summary := digest.Summarize("nightly", entries, digest.DefaultTitles)
+//		// Send summary.Title, summary.Message, summary.Type and summary.Tags
+//		return d.Remove(entries)
+//	})
+package digest
+
+import (
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+	"github.com/Pincho-App/pincho-cli/pkg/validation"
+)
+
+const (
+	// DirName is the name of the digests directory inside the config directory
+	DirName = "digests"
+
+	// DefaultTitles is the default number of first and last entries listed in a summary
+	DefaultTitles = 3
+
+	// MaxListedMessage is the number of characters of an entry's message listed in a summary
+	MaxListedMessage = 80
+)
+
+// entryExt is the file extension of digest entries
+const entryExt = ".json"
+
+// flushLockName is the lock file serializing flushes, skipped as an entry
+const flushLockName = ".flush.lock"
+
+// nameRegex restricts digest names to safe directory names
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Entry is one event added to a digest
+type Entry struct {
+	ID      string    `json:"id"`
+	At      time.Time `json:"at"`
+	Title   string    `json:"title"`
+	Message string    `json:"message,omitempty"`
+	Type    string    `json:"type,omitempty"`
+	Tags    []string  `json:"tags,omitempty"`
+}
+
+// Digest is a named collection of pending entries
+type Digest struct {
+	Name string
+	dir  string
+}
+
+// ValidateName checks that name can be used as a digest name
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("invalid digest name %q (only letters, numbers, hyphens, and underscores allowed)", name)
+	}
+	return nil
+}
+
+// New returns the digest called name stored in dir
+func New(dir, name string) (*Digest, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	return &Digest{Name: name, dir: filepath.Join(dir, name)}, nil
+}
+
+// Open returns the digest called name in the config directory (~/.pincho/digests)
+func Open(name string) (*Digest, error) {
+	dir, err := state.Path(DirName)
+	if err != nil {
+		return nil, err
+	}
+	return New(dir, name)
+}
+
+// Add appends an entry to the digest
+// Tags are normalized and validated; ID and At are set if empty.
+func (d *Digest) Add(entry Entry) (*Entry, error) {
+	if entry.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+
+	tags, err := validation.NormalizeAndValidateTags(entry.Tags)
+	if err != nil {
+		return nil, err
+	}
+	entry.Tags = tags
+
+	if entry.At.IsZero() {
+		entry.At = time.Now().UTC()
+	}
+	if entry.ID == "" {
+		id, err := state.NewEntryID(entry.At)
+		if err != nil {
+			return nil, err
+		}
+		entry.ID = id
+	}
+
+	if err := state.WriteJSON(filepath.Join(d.dir, entry.ID+entryExt), entry); err != nil {
+		return nil, fmt.Errorf("failed to write digest entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Entries returns the pending entries, oldest first
+func (d *Digest) Entries() ([]Entry, error) {
+	names, err := state.EntryFiles(d.dir, entryExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read digest: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(names))
+	for _, name := range names {
+		var entry Entry
+		found, err := state.ReadJSON(filepath.Join(d.dir, name), &entry)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Remove deletes the given entries from the digest
+func (d *Digest) Remove(entries []Entry) error {
+	for _, entry := range entries {
+		err := os.Remove(filepath.Join(d.dir, entry.ID+entryExt))
+		if err != nil && !stderrors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove digest entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// Flush calls fn with the pending entries while holding the digest's flush lock
+// fn sends the summary and removes the entries it delivered with Remove. A
+// concurrent flush waits for the lock and then only sees the entries that are
+// still pending. The error of fn is returned as is.
+func (d *Digest) Flush(fn func(entries []Entry) error) error {
+	lock, err := state.Lock(filepath.Join(d.dir, flushLockName))
+	if err != nil {
+		return fmt.Errorf("failed to lock digest: %w", err)
+	}
+	defer lock.Unlock()
+
+	entries, err := d.Entries()
+	if err != nil {
+		return err
+	}
+	return fn(entries)
+}
+
+// Count is the number of entries with a type or tag
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Summary is the single notification a digest collapses into
+type Summary struct {
+	Title   string   `json:"title"`
+	Message string   `json:"message"`
+	Type    string   `json:"type,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+
+	Total      int      `json:"total"`
+	TypeCounts []Count  `json:"typeCounts,omitempty"`
+	TagCounts  []Count  `json:"tagCounts,omitempty"`
+	First      []string `json:"first"`          // First entries, as "title: message"
+	Last       []string `json:"last,omitempty"` // Last entries, as "title: message"
+}
+
+// Summarize collapses entries into one notification
+//
+// The summary counts entries per type and tag, lists the first and last
+// entries with their titles and messages (all of them if there are at most
+// 2*titles entries), and merges
+// the tags of all entries, most frequent first, capped to validation.MaxTags.
+// The type is kept if all entries share it.
+func Summarize(name string, entries []Entry, titles int) *Summary {
+	if titles <= 0 {
+		titles = DefaultTitles
+	}
+
+	s := &Summary{
+		Title: fmt.Sprintf("%s: %d %s", name, len(entries), templates.Plural(len(entries), "event", "events")),
+		Total: len(entries),
+	}
+	if len(entries) == 0 {
+		return s
+	}
+
+	s.TypeCounts = countBy(entries, func(e Entry) []string {
+		if e.Type == "" {
+			return nil
+		}
+		return []string{e.Type}
+	})
+	s.TagCounts = countBy(entries, func(e Entry) []string { return e.Tags })
+
+	if len(s.TypeCounts) == 1 && s.TypeCounts[0].Count == len(entries) {
+		s.Type = s.TypeCounts[0].Name
+	}
+	for _, c := range s.TagCounts {
+		if len(s.Tags) == validation.MaxTags {
+			break
+		}
+		s.Tags = append(s.Tags, c.Name)
+	}
+
+	if len(entries) <= 2*titles {
+		s.First = listEntries(entries)
+	} else {
+		s.First = listEntries(entries[:titles])
+		s.Last = listEntries(entries[len(entries)-titles:])
+	}
+
+	s.Message = s.message(entries)
+	return s
+}
+
+// message renders the summary body
+func (s *Summary) message(entries []Entry) string {
+	var b strings.Builder
+
+	first, last := entries[0].At.Local(), entries[len(entries)-1].At.Local()
+	fmt.Fprintf(&b, "%d %s from %s to %s\n", s.Total, templates.Plural(s.Total, "event", "events"),
+		first.Format("2006-01-02 15:04"), last.Format("2006-01-02 15:04"))
+
+	if len(s.TypeCounts) > 0 {
+		fmt.Fprintf(&b, "By type: %s\n", formatCounts(s.TypeCounts))
+	}
+	if len(s.TagCounts) > 0 {
+		fmt.Fprintf(&b, "By tag: %s\n", formatCounts(s.TagCounts))
+	}
+
+	if len(s.Last) == 0 {
+		b.WriteString("\n")
+		writeList(&b, s.First)
+	} else {
+		fmt.Fprintf(&b, "\nFirst %d:\n", len(s.First))
+		writeList(&b, s.First)
+		fmt.Fprintf(&b, "\nLast %d:\n", len(s.Last))
+		writeList(&b, s.Last)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// countBy counts entries per key, most frequent first (ties in order of first appearance)
+func countBy(entries []Entry, keys func(Entry) []string) []Count {
+	var counts []Count
+	index := make(map[string]int)
+	for _, e := range entries {
+		for _, key := range keys(e) {
+			i, ok := index[key]
+			if !ok {
+				i = len(counts)
+				index[key] = i
+				counts = append(counts, Count{Name: key})
+			}
+			counts[i].Count++
+		}
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	return counts
+}
+
+// formatCounts renders counts as "alert 8, info 4"
+func formatCounts(counts []Count) string {
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = fmt.Sprintf("%s %d", c.Name, c.Count)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// writeList writes items as a bulleted list
+func writeList(b *strings.Builder, items []string) {
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+// listEntries returns the entries as "title: message" lines
+// Messages are joined into one line and truncated to MaxListedMessage characters.
+func listEntries(entries []Entry) []string {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		message := strings.Join(strings.Fields(e.Message), " ")
+		if runes := []rune(message); len(runes) > MaxListedMessage {
+			message = string(runes[:MaxListedMessage-3]) + "..."
+		}
+
+		lines[i] = e.Title
+		if message != "" {
+			lines[i] += ": " + message
+		}
+	}
+	return lines
+}
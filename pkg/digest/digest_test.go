@@ -0,0 +1,207 @@
+package digest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/validation"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"nightly", "batch_jobs", "etl-2"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc", "a/b", "with space"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) expected error", name)
+		}
+	}
+}
+
+func TestAddEntriesRemove(t *testing.T) {
+	d, err := New(t.TempDir(), "nightly")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	for _, title := range []string{"first", "second", "third"} {
+		if _, err := d.Add(Entry{Title: title, Tags: []string{" DB "}}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	entries, err := d.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Title != "first" || entries[2].Title != "third" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if len(entries[0].Tags) != 1 || entries[0].Tags[0] != "db" {
+		t.Errorf("expected normalized tags, got %v", entries[0].Tags)
+	}
+
+	// An entry added after reading is kept by Remove
+	if _, err := d.Add(Entry{Title: "late"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := d.Remove(entries); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	entries, _ = d.Entries()
+	if len(entries) != 1 || entries[0].Title != "late" {
+		t.Errorf("expected only the late entry to remain, got %+v", entries)
+	}
+}
+
+func TestAdd_Validation(t *testing.T) {
+	d, _ := New(t.TempDir(), "nightly")
+	if _, err := d.Add(Entry{}); err == nil {
+		t.Error("expected error for missing title")
+	}
+	if _, err := d.Add(Entry{Title: "x", Tags: []string{"bad tag!"}}); err == nil {
+		t.Error("expected error for invalid tag")
+	}
+}
+
+func TestEntries_Missing(t *testing.T) {
+	d, _ := New(t.TempDir(), "empty")
+	entries, err := d.Entries()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Entries() = %v, %v; want empty, nil", entries, err)
+	}
+}
+
+func TestFlush_ConcurrentFlushesSendOnce(t *testing.T) {
+	dir := t.TempDir()
+	d, _ := New(dir, "nightly")
+	for i := 0; i < 5; i++ {
+		if _, err := d.Add(Entry{Title: fmt.Sprintf("event %d", i)}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	var sent []int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Separate Digest values, like separate processes
+			d, _ := New(dir, "nightly")
+			err := d.Flush(func(entries []Entry) error {
+				if len(entries) == 0 {
+					return nil
+				}
+				// Give other flushes time to read the same entries
+				time.Sleep(20 * time.Millisecond)
+				mu.Lock()
+				sent = append(sent, len(entries))
+				mu.Unlock()
+				return d.Remove(entries)
+			})
+			if err != nil {
+				t.Errorf("Flush failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(sent) != 1 || sent[0] != 5 {
+		t.Errorf("expected one summary of 5 entries, got %v", sent)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	var entries []Entry
+	for i := 0; i < 10; i++ {
+		notifType := "info"
+		tags := []string{"etl"}
+		if i%3 == 0 {
+			notifType = "alert"
+			tags = append(tags, "db")
+		}
+		entries = append(entries, Entry{
+			ID:    fmt.Sprint(i),
+			At:    start.Add(time.Duration(i) * time.Minute),
+			Title: fmt.Sprintf("event %d", i),
+			Type:  notifType,
+			Tags:  tags,
+		})
+	}
+
+	s := Summarize("nightly", entries, 2)
+
+	if s.Title != "nightly: 10 events" || s.Total != 10 {
+		t.Errorf("unexpected title %q / total %d", s.Title, s.Total)
+	}
+	if s.Type != "" {
+		t.Errorf("expected no type for mixed types, got %q", s.Type)
+	}
+	if len(s.TypeCounts) != 2 || s.TypeCounts[0] != (Count{"info", 6}) || s.TypeCounts[1] != (Count{"alert", 4}) {
+		t.Errorf("unexpected type counts: %+v", s.TypeCounts)
+	}
+	if len(s.Tags) != 2 || s.Tags[0] != "etl" || s.Tags[1] != "db" {
+		t.Errorf("unexpected tags: %v", s.Tags)
+	}
+	if strings.Join(s.First, ",") != "event 0,event 1" || strings.Join(s.Last, ",") != "event 8,event 9" {
+		t.Errorf("unexpected first/last titles: %v / %v", s.First, s.Last)
+	}
+	for _, want := range []string{"By type: info 6, alert 4", "By tag: etl 10, db 4", "First 2:", "- event 9"} {
+		if !strings.Contains(s.Message, want) {
+			t.Errorf("message missing %q:\n%s", want, s.Message)
+		}
+	}
+}
+
+func TestSummarize_FewEntries(t *testing.T) {
+	entries := []Entry{
+		{Title: "one", Type: "alert", Message: "orders.csv\nline 42"},
+		{Title: "two", Type: "alert", Message: strings.Repeat("x", 100)},
+	}
+
+	s := Summarize("jobs", entries, 3)
+
+	if s.Type != "alert" {
+		t.Errorf("expected shared type to be kept, got %q", s.Type)
+	}
+	if len(s.First) != 2 || len(s.Last) != 0 {
+		t.Errorf("expected all titles listed once, got %v / %v", s.First, s.Last)
+	}
+	if s.First[0] != "one: orders.csv line 42" {
+		t.Errorf("expected the message listed on one line, got %q", s.First[0])
+	}
+	if want := "two: " + strings.Repeat("x", MaxListedMessage-3) + "..."; s.First[1] != want {
+		t.Errorf("expected the message truncated, got %q", s.First[1])
+	}
+	if !strings.Contains(s.Message, "- one: orders.csv line 42") {
+		t.Errorf("message missing the entry message:\n%s", s.Message)
+	}
+	if strings.Contains(s.Message, "First") {
+		t.Errorf("expected a single title list:\n%s", s.Message)
+	}
+}
+
+func TestSummarize_TagsCapped(t *testing.T) {
+	var entries []Entry
+	for i := 0; i < validation.MaxTags+5; i++ {
+		entries = append(entries, Entry{Title: "x", Tags: []string{fmt.Sprintf("tag%d", i)}})
+	}
+
+	s := Summarize("many", entries, 3)
+
+	if len(s.Tags) != validation.MaxTags {
+		t.Errorf("expected %d tags, got %d", validation.MaxTags, len(s.Tags))
+	}
+	if _, err := validation.NormalizeAndValidateTags(s.Tags); err != nil {
+		t.Errorf("merged tags are not valid: %v", err)
+	}
+}
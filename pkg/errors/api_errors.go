@@ -3,6 +3,7 @@ package errors
 
 import (
 	"fmt"
+	"time"
 )
 
 // APIError is the base interface for all API-related errors
@@ -94,10 +95,14 @@ func NewAuthenticationErrorWithStatus(message string, statusCode int) *Authentic
 // These ARE retryable after waiting for the rate limit to reset
 type RateLimitError struct {
 	Message    string
-	RetryAfter int // Seconds to wait before retry (from Retry-After header)
+	RetryAfter int       // Seconds to wait before retry (from Retry-After header)
+	ResetAt    time.Time // When the rate limit window resets (zero if unknown)
 }
 
 func (e *RateLimitError) Error() string {
+	if !e.ResetAt.IsZero() {
+		return fmt.Sprintf("%s (window resets at %s)", e.Message, e.ResetAt.Format(time.RFC3339))
+	}
 	if e.RetryAfter > 0 {
 		return fmt.Sprintf("%s (retry after %d seconds)", e.Message, e.RetryAfter)
 	}
@@ -122,6 +127,15 @@ func NewRateLimitErrorWithRetryAfter(message string, retryAfter int) *RateLimitE
 	return &RateLimitError{Message: message, RetryAfter: retryAfter}
 }
 
+// NewRateLimitErrorWithReset creates a new rate limit error for a window resetting at resetAt
+func NewRateLimitErrorWithReset(message string, resetAt time.Time) *RateLimitError {
+	retryAfter := int(time.Until(resetAt).Round(time.Second).Seconds())
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	return &RateLimitError{Message: message, RetryAfter: retryAfter, ResetAt: resetAt}
+}
+
 // ServerError represents a server-side error (5xx)
 // These ARE retryable as the server may recover
 type ServerError struct {
@@ -185,6 +199,56 @@ func NewNetworkError(message string, cause error) *NetworkError {
 	return &NetworkError{Message: message, Cause: cause}
 }
 
+// DuplicateError represents a request refused locally because the same
+// idempotency key was already sent successfully within the configured window
+// These are NOT retryable as resending would notify twice
+type DuplicateError struct {
+	Key    string
+	SentAt time.Time
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("notification with idempotency key %q was already sent at %s", e.Key, e.SentAt.Format(time.RFC3339))
+}
+
+func (e *DuplicateError) IsRetryable() bool {
+	return false // Duplicates are never retryable
+}
+
+func (e *DuplicateError) StatusCode() int {
+	return 409
+}
+
+// NewDuplicateError creates a new duplicate error
+func NewDuplicateError(key string, sentAt time.Time) *DuplicateError {
+	return &DuplicateError{Key: key, SentAt: sentAt}
+}
+
+// CircuitOpenError represents a request refused locally because the circuit
+// breaker opened after sustained server or network failures
+// These are NOT retryable until the cooldown has elapsed
+type CircuitOpenError struct {
+	Failures  int
+	OpenUntil time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("API unavailable after %d consecutive failures, not sending until %s", e.Failures, e.OpenUntil.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) IsRetryable() bool {
+	return false // Retrying would defeat the breaker
+}
+
+func (e *CircuitOpenError) StatusCode() int {
+	return 503
+}
+
+// NewCircuitOpenError creates a new circuit open error
+func NewCircuitOpenError(failures int, openUntil time.Time) *CircuitOpenError {
+	return &CircuitOpenError{Failures: failures, OpenUntil: openUntil}
+}
+
 // IsRetryableError checks if any error implements the APIError interface and is retryable
 func IsRetryableError(err error) bool {
 	if apiErr, ok := err.(APIError); ok {
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestValidationError(t *testing.T) {
@@ -120,6 +121,13 @@ func TestRateLimitError(t *testing.T) {
 			expectedStatus: 429,
 			isRetryable:    true,
 		},
+		{
+			name:           "rate limit with reset time",
+			err:            &RateLimitError{Message: "quota exhausted", RetryAfter: 60, ResetAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)},
+			expectedMsg:    "quota exhausted (window resets at 2025-01-02T03:04:05Z)",
+			expectedStatus: 429,
+			isRetryable:    true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -137,6 +145,56 @@ func TestRateLimitError(t *testing.T) {
 	}
 }
 
+func TestNewRateLimitErrorWithReset(t *testing.T) {
+	resetAt := time.Now().Add(90 * time.Second)
+	err := NewRateLimitErrorWithReset("quota exhausted", resetAt)
+
+	if !err.ResetAt.Equal(resetAt) {
+		t.Errorf("expected ResetAt %v, got %v", resetAt, err.ResetAt)
+	}
+	if err.RetryAfter < 89 || err.RetryAfter > 90 {
+		t.Errorf("expected RetryAfter of about 90 seconds, got %d", err.RetryAfter)
+	}
+
+	// A reset time in the past never yields a negative wait
+	past := NewRateLimitErrorWithReset("quota exhausted", time.Now().Add(-time.Minute))
+	if past.RetryAfter != 0 {
+		t.Errorf("expected RetryAfter 0 for past reset, got %d", past.RetryAfter)
+	}
+}
+
+func TestDuplicateError(t *testing.T) {
+	sentAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := NewDuplicateError("deploy-42", sentAt)
+
+	expectedMsg := `notification with idempotency key "deploy-42" was already sent at 2025-01-02T03:04:05Z`
+	if err.Error() != expectedMsg {
+		t.Errorf("expected error message '%s', got '%s'", expectedMsg, err.Error())
+	}
+	if err.StatusCode() != 409 {
+		t.Errorf("expected status code 409, got %d", err.StatusCode())
+	}
+	if err.IsRetryable() {
+		t.Error("expected DuplicateError to not be retryable")
+	}
+}
+
+func TestCircuitOpenError(t *testing.T) {
+	openUntil := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := NewCircuitOpenError(5, openUntil)
+
+	expectedMsg := "API unavailable after 5 consecutive failures, not sending until 2025-01-02T03:04:05Z"
+	if err.Error() != expectedMsg {
+		t.Errorf("expected error message '%s', got '%s'", expectedMsg, err.Error())
+	}
+	if err.StatusCode() != 503 {
+		t.Errorf("expected status code 503, got %d", err.StatusCode())
+	}
+	if err.IsRetryable() {
+		t.Error("expected CircuitOpenError to not be retryable")
+	}
+}
+
 func TestServerError(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -344,6 +402,8 @@ func TestAPIErrorInterface(t *testing.T) {
 	var _ APIError = &RateLimitError{}
 	var _ APIError = &ServerError{}
 	var _ APIError = &NetworkError{}
+	var _ APIError = &DuplicateError{}
+	var _ APIError = &CircuitOpenError{}
 }
 
 func TestCLIErrorStillWorks(t *testing.T) {
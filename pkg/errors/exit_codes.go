@@ -73,6 +73,22 @@ func (e *CLIError) Unwrap() error {
 	return e.Cause
 }
 
+// ExitError makes the CLI exit with Code without printing an error
+// Used to pass on the exit code of a wrapped command.
+type ExitError struct {
+	Code int
+}
+
+// Error implements the error interface
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// NewExitError creates an error exiting silently with code
+func NewExitError(code int) *ExitError {
+	return &ExitError{Code: code}
+}
+
 // NewUsageError creates a user error (exit code 1)
 func NewUsageError(message string, cause error) *CLIError {
 	return &CLIError{
@@ -101,13 +117,18 @@ func NewSystemError(message string, cause error) *CLIError {
 }
 
 // HandleError prints the error and exits with the appropriate code
-// If the error is a CLIError, uses its exit code
+// If the error is a CLIError, uses its exit code; an ExitError exits with its
+// code without printing anything
 // Otherwise, uses ExitSystemError (3)
 func HandleError(err error) {
 	if err == nil {
 		return
 	}
 
+	if exitErr, ok := err.(*ExitError); ok {
+		os.Exit(exitErr.Code)
+	}
+
 	// Check if it's a CLIError with a specific exit code
 	if cliErr, ok := err.(*CLIError); ok {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", cliErr.Message)
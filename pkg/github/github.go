@@ -0,0 +1,506 @@
+// Package github turns GitHub webhook deliveries into notifications.
+//
+// VerifySignature checks the X-Hub-Signature-256 header of a delivery against
+// the webhook secret. Parse decodes workflow_run, release, pull_request and
+// push events into an Event with a default notification and the fields of
+// the event as template variables:
+//
+//	if err := github.VerifySignature(secret, body, r.Header.Get("X-Hub-Signature-256")); err != nil {
+//		// Reject the delivery
+//	}
+//	event, err := github.Parse(r.Header.Get("X-GitHub-Event"), body)
+//	opts, err := event.Notification(mappings[event.Name])
+//	if opts != nil {
+//		// Send opts
+//	}
+//
+// A Mapping, from the github section of the config file, overrides the
+// default notification of an event with templates (see pkg/templates) and
+// selects the actions to notify about:
+//
+//	github:
+//	  workflow_run:
+//	    title: "{{.Vars.workflow}}: {{.Vars.conclusion}}"
+//	    type: ci
+//	  pull_request:
+//	    actions: [opened, closed]
+//	  push:
+//	    disabled: true
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+	"github.com/Pincho-App/pincho-cli/pkg/validation"
+)
+
+const (
+	// SignatureHeader is the header carrying the HMAC-SHA256 signature of a delivery
+	SignatureHeader = "X-Hub-Signature-256"
+
+	// EventHeader is the header carrying the event name
+	EventHeader = "X-GitHub-Event"
+
+	// DeliveryHeader is the header carrying the unique ID of a delivery
+	DeliveryHeader = "X-GitHub-Delivery"
+
+	// maxCommits is the number of commits listed in a push notification
+	maxCommits = 5
+
+	// maxBodyLength is the length at which release notes are cut
+	maxBodyLength = 500
+)
+
+var (
+	// ErrInvalidSignature is returned by VerifySignature if a delivery is not signed with the secret
+	ErrInvalidSignature = stderrors.New("invalid webhook signature")
+
+	// ErrUnsupportedEvent is returned by Parse for events without a mapping
+	ErrUnsupportedEvent = stderrors.New("unsupported event")
+)
+
+// Events lists the supported events
+var Events = []string{"workflow_run", "release", "pull_request", "push"}
+
+// DefaultActions lists the actions notified about per event when a mapping does not set them
+// Push events get the action "pushed", or "deleted" for a deleted branch or tag.
+var DefaultActions = map[string][]string{
+	"workflow_run": {"completed"},
+	"release":      {"published"},
+	"pull_request": {"opened", "reopened", "closed", "ready_for_review"},
+	"push":         {"pushed"},
+}
+
+// invalidTagChars matches the characters not allowed in tags
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// VerifySignature checks signature, the X-Hub-Signature-256 header, against body and secret
+func VerifySignature(secret string, body []byte, signature string) error {
+	hexSig, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Mapping overrides how an event becomes a notification
+// Fields set in the template replace the default ones; tags replace the
+// default tags.
+type Mapping struct {
+	templates.Template `mapstructure:",squash"`
+
+	Actions  []string `mapstructure:"actions"`  // Actions to notify about, "*" for all (default: DefaultActions)
+	Disabled bool     `mapstructure:"disabled"` // Ignore the event
+}
+
+// Event is a parsed webhook delivery
+type Event struct {
+	Name   string            // Event name, e.g. "push"
+	Action string            // Activity type, e.g. "completed"
+	Vars   map[string]string // Fields of the event, available to mapping templates as .Vars
+
+	// Default is the notification sent without a mapping
+	Default templates.Rendered
+}
+
+// Notification returns the notification for the event with mapping m applied
+// Returns nil if the mapping disables the event or does not select its action.
+func (e *Event) Notification(m Mapping) (*client.SendOptions, error) {
+	if m.Disabled || !selected(e.Action, m.Actions, DefaultActions[e.Name]) {
+		return nil, nil
+	}
+
+	rendered, err := templates.Render(m.Template, e.Vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s mapping: %w", e.Name, err)
+	}
+
+	opts := &client.SendOptions{
+		Title:     pick(m.Title, rendered.Title, e.Default.Title),
+		Message:   pick(m.Message, rendered.Message, e.Default.Message),
+		Type:      pick(m.Type, rendered.Type, e.Default.Type),
+		Tags:      e.Default.Tags,
+		ImageURL:  pick(m.ImageURL, rendered.ImageURL, e.Default.ImageURL),
+		ActionURL: pick(m.ActionURL, rendered.ActionURL, e.Default.ActionURL),
+	}
+	if len(m.Tags) > 0 {
+		opts.Tags = rendered.Tags
+	}
+	return opts, nil
+}
+
+// Parse decodes the payload of the event called name
+// Returns ErrUnsupportedEvent for events other than those in Events.
+func Parse(name string, payload []byte) (*Event, error) {
+	var (
+		event *Event
+		err   error
+	)
+	switch name {
+	case "workflow_run":
+		event, err = parseWorkflowRun(payload)
+	case "release":
+		event, err = parseRelease(payload)
+	case "pull_request":
+		event, err = parsePullRequest(payload)
+	case "push":
+		event, err = parsePush(payload)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEvent, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", name, err)
+	}
+
+	event.Name = name
+	event.Vars["event"] = name
+	event.Vars["action"] = event.Action
+	event.Default.Tags = []string{tag(event.Vars["repo_name"]), name}
+	return event, nil
+}
+
+// repository is the repository of an event
+type repository struct {
+	FullName string `json:"full_name"`
+	Name     string `json:"name"`
+	HTMLURL  string `json:"html_url"`
+}
+
+// user is a GitHub account
+type user struct {
+	Login string `json:"login"`
+}
+
+// common holds the fields shared by all events
+type common struct {
+	Action     string     `json:"action"`
+	Repository repository `json:"repository"`
+	Sender     user       `json:"sender"`
+}
+
+// vars returns the template variables of the shared fields
+func (c common) vars() map[string]string {
+	return map[string]string{
+		"repo":      c.Repository.FullName,
+		"repo_name": c.Repository.Name,
+		"repo_url":  c.Repository.HTMLURL,
+		"sender":    c.Sender.Login,
+	}
+}
+
+func parseWorkflowRun(payload []byte) (*Event, error) {
+	var p struct {
+		common
+		WorkflowRun struct {
+			Name         string `json:"name"`
+			DisplayTitle string `json:"display_title"`
+			HeadBranch   string `json:"head_branch"`
+			HeadSHA      string `json:"head_sha"`
+			Status       string `json:"status"`
+			Conclusion   string `json:"conclusion"`
+			RunNumber    int    `json:"run_number"`
+			RunAttempt   int    `json:"run_attempt"`
+			Event        string `json:"event"`
+			HTMLURL      string `json:"html_url"`
+			Actor        user   `json:"actor"`
+		} `json:"workflow_run"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	run := p.WorkflowRun
+
+	vars := p.vars()
+	vars["workflow"] = run.Name
+	vars["run_title"] = run.DisplayTitle
+	vars["branch"] = run.HeadBranch
+	vars["sha"] = shortSHA(run.HeadSHA)
+	vars["status"] = run.Status
+	vars["conclusion"] = run.Conclusion
+	vars["run_number"] = strconv.Itoa(run.RunNumber)
+	vars["run_attempt"] = strconv.Itoa(run.RunAttempt)
+	vars["trigger"] = run.Event
+	vars["run_url"] = run.HTMLURL
+	vars["actor"] = run.Actor.Login
+
+	outcome := run.Conclusion
+	switch run.Conclusion {
+	case "success":
+		outcome = "succeeded"
+	case "failure":
+		outcome = "failed"
+	case "cancelled":
+		outcome = "was cancelled"
+	case "timed_out":
+		outcome = "timed out"
+	case "":
+		outcome = run.Status
+	}
+
+	var notifType string
+	switch run.Conclusion {
+	case "failure", "timed_out", "startup_failure":
+		notifType = "alert"
+	}
+
+	return &Event{
+		Action: p.Action,
+		Vars:   vars,
+		Default: templates.Rendered{
+			Title:     fmt.Sprintf("%s %s on %s", run.Name, outcome, run.HeadBranch),
+			Message:   fmt.Sprintf("%s: %s\nRun #%d by %s (%s)", p.Repository.FullName, run.DisplayTitle, run.RunNumber, run.Actor.Login, run.Event),
+			Type:      notifType,
+			ActionURL: run.HTMLURL,
+		},
+	}, nil
+}
+
+func parseRelease(payload []byte) (*Event, error) {
+	var p struct {
+		common
+		Release struct {
+			TagName    string `json:"tag_name"`
+			Name       string `json:"name"`
+			Body       string `json:"body"`
+			HTMLURL    string `json:"html_url"`
+			Prerelease bool   `json:"prerelease"`
+			Author     user   `json:"author"`
+		} `json:"release"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	release := p.Release
+
+	vars := p.vars()
+	vars["tag"] = release.TagName
+	vars["release_name"] = release.Name
+	vars["release_url"] = release.HTMLURL
+	vars["body"] = release.Body
+	vars["author"] = release.Author.Login
+	vars["prerelease"] = strconv.FormatBool(release.Prerelease)
+
+	kind := "released"
+	if release.Prerelease {
+		kind = "pre-released"
+	}
+	message := release.Name
+	if message == "" {
+		message = release.TagName
+	}
+	if body := strings.TrimSpace(release.Body); body != "" {
+		message += "\n\n" + cut(body, maxBodyLength)
+	}
+
+	return &Event{
+		Action: p.Action,
+		Vars:   vars,
+		Default: templates.Rendered{
+			Title:     fmt.Sprintf("%s %s %s", p.Repository.Name, release.TagName, kind),
+			Message:   message,
+			ActionURL: release.HTMLURL,
+		},
+	}, nil
+}
+
+func parsePullRequest(payload []byte) (*Event, error) {
+	var p struct {
+		common
+		Number      int `json:"number"`
+		PullRequest struct {
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+			Merged  bool   `json:"merged"`
+			Draft   bool   `json:"draft"`
+			User    user   `json:"user"`
+			Head    struct {
+				Ref string `json:"ref"`
+			} `json:"head"`
+			Base struct {
+				Ref string `json:"ref"`
+			} `json:"base"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	pr := p.PullRequest
+
+	vars := p.vars()
+	vars["number"] = strconv.Itoa(p.Number)
+	vars["pr_title"] = pr.Title
+	vars["pr_url"] = pr.HTMLURL
+	vars["author"] = pr.User.Login
+	vars["head"] = pr.Head.Ref
+	vars["base"] = pr.Base.Ref
+	vars["merged"] = strconv.FormatBool(pr.Merged)
+	vars["draft"] = strconv.FormatBool(pr.Draft)
+
+	verb := strings.ReplaceAll(p.Action, "_", " ")
+	if p.Action == "closed" && pr.Merged {
+		verb = "merged"
+	}
+
+	return &Event{
+		Action: p.Action,
+		Vars:   vars,
+		Default: templates.Rendered{
+			Title:     fmt.Sprintf("PR #%d %s: %s", p.Number, verb, pr.Title),
+			Message:   fmt.Sprintf("%s: %s → %s by %s", p.Repository.FullName, pr.Head.Ref, pr.Base.Ref, pr.User.Login),
+			ActionURL: pr.HTMLURL,
+		},
+	}, nil
+}
+
+func parsePush(payload []byte) (*Event, error) {
+	var p struct {
+		common
+		Ref     string `json:"ref"`
+		Created bool   `json:"created"`
+		Deleted bool   `json:"deleted"`
+		Forced  bool   `json:"forced"`
+		Compare string `json:"compare"`
+		Pusher  struct {
+			Name string `json:"name"`
+		} `json:"pusher"`
+		Commits []struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+		} `json:"commits"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+
+	action := "pushed"
+	if p.Deleted {
+		action = "deleted"
+	}
+
+	ref, isTag := strings.CutPrefix(p.Ref, "refs/tags/")
+	if !isTag {
+		ref = strings.TrimPrefix(p.Ref, "refs/heads/")
+	}
+
+	vars := p.vars()
+	vars["ref"] = p.Ref
+	vars["branch"] = ref
+	vars["pusher"] = p.Pusher.Name
+	vars["commits"] = strconv.Itoa(len(p.Commits))
+	vars["compare_url"] = p.Compare
+	vars["forced"] = strconv.FormatBool(p.Forced)
+	vars["head_message"] = ""
+	if len(p.Commits) > 0 {
+		vars["head_message"] = firstLine(p.Commits[len(p.Commits)-1].Message)
+	}
+
+	verb := "pushed"
+	if p.Forced {
+		verb = "force-pushed"
+	}
+	var title string
+	switch {
+	case p.Deleted:
+		title = fmt.Sprintf("%s deleted %s in %s", p.Pusher.Name, ref, p.Repository.Name)
+	case isTag:
+		title = fmt.Sprintf("%s pushed tag %s to %s", p.Pusher.Name, ref, p.Repository.Name)
+	default:
+		title = fmt.Sprintf("%s %s %d %s to %s/%s", p.Pusher.Name, verb, len(p.Commits), templates.Plural(len(p.Commits), "commit", "commits"), p.Repository.Name, ref)
+	}
+
+	var b strings.Builder
+	for i, commit := range p.Commits {
+		if i == maxCommits {
+			fmt.Fprintf(&b, "... and %d more\n", len(p.Commits)-maxCommits)
+			break
+		}
+		fmt.Fprintf(&b, "%s %s\n", shortSHA(commit.ID), firstLine(commit.Message))
+	}
+	message := strings.TrimSuffix(b.String(), "\n")
+	if message == "" {
+		message = p.Repository.FullName
+	}
+
+	return &Event{
+		Action: action,
+		Vars:   vars,
+		Default: templates.Rendered{
+			Title:     title,
+			Message:   message,
+			ActionURL: p.Compare,
+		},
+	}, nil
+}
+
+// selected reports whether action is in actions, or in defaults if actions is empty
+func selected(action string, actions, defaults []string) bool {
+	if len(actions) == 0 {
+		actions = defaults
+	}
+	for _, a := range actions {
+		if a == "*" || a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns rendered if the mapping set the field (src), or else the default
+func pick(src, rendered, def string) string {
+	if src != "" {
+		return rendered
+	}
+	return def
+}
+
+// tag turns a repository name into a valid tag
+func tag(name string) string {
+	t := strings.Trim(invalidTagChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(t) > validation.MaxTagLength {
+		t = t[:validation.MaxTagLength]
+	}
+	return t
+}
+
+// shortSHA returns the abbreviated form of a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// firstLine returns the first line of a commit message
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
+
+// cut shortens s to at most n runes, ending in "..." if shortened
+func cut(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
@@ -0,0 +1,205 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+)
+
+const repo = `"repository": {"full_name": "acme/Web.App", "name": "Web.App", "html_url": "https://github.com/acme/Web.App"}, "sender": {"login": "octocat"}`
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := `{"zen": "Keep it logically awesome."}`
+	if err := VerifySignature("s3cret", []byte(body), sign("s3cret", body)); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+
+	invalid := []string{
+		"",
+		sign("wrong", body),
+		sign("s3cret", body+" "),
+		strings.TrimPrefix(sign("s3cret", body), "sha256="),
+		"sha256=not-hex",
+	}
+	for _, signature := range invalid {
+		if err := VerifySignature("s3cret", []byte(body), signature); !stderrors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifySignature(%q) expected ErrInvalidSignature, got %v", signature, err)
+		}
+	}
+}
+
+func TestParse_WorkflowRun(t *testing.T) {
+	event, err := Parse("workflow_run", []byte(`{"action": "completed", "workflow_run": {
+		"name": "CI", "display_title": "Fix login", "head_branch": "main", "head_sha": "0123456789abcdef",
+		"status": "completed", "conclusion": "failure", "run_number": 42, "run_attempt": 1, "event": "push",
+		"html_url": "https://github.com/acme/Web.App/actions/runs/1", "actor": {"login": "octocat"}}, `+repo+`}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	opts, err := event.Notification(Mapping{})
+	if err != nil {
+		t.Fatalf("Notification failed: %v", err)
+	}
+	if opts.Title != "CI failed on main" || opts.Type != "alert" || opts.ActionURL != "https://github.com/acme/Web.App/actions/runs/1" {
+		t.Errorf("unexpected notification: %+v", opts)
+	}
+	if opts.Message != "acme/Web.App: Fix login\nRun #42 by octocat (push)" {
+		t.Errorf("unexpected message: %q", opts.Message)
+	}
+	if len(opts.Tags) != 2 || opts.Tags[0] != "web-app" || opts.Tags[1] != "workflow_run" {
+		t.Errorf("expected repo and event tags, got %v", opts.Tags)
+	}
+	if event.Vars["sha"] != "0123456" || event.Vars["action"] != "completed" {
+		t.Errorf("unexpected vars: %v", event.Vars)
+	}
+}
+
+func TestParse_Release(t *testing.T) {
+	event, err := Parse("release", []byte(`{"action": "published", "release": {"tag_name": "v1.2.0", "name": "Spring release",
+		"body": "`+strings.Repeat("x", 600)+`", "html_url": "https://github.com/acme/Web.App/releases/v1.2.0", "author": {"login": "octocat"}}, `+repo+`}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	opts, _ := event.Notification(Mapping{})
+	if opts.Title != "Web.App v1.2.0 released" || !strings.HasPrefix(opts.Message, "Spring release\n\nxxx") {
+		t.Errorf("unexpected notification: %+v", opts)
+	}
+	if len([]rune(opts.Message)) > len("Spring release\n\n")+maxBodyLength || !strings.HasSuffix(opts.Message, "...") {
+		t.Errorf("expected the release notes to be cut, got %d characters", len(opts.Message))
+	}
+}
+
+func TestParse_PullRequest(t *testing.T) {
+	payload := func(action string, merged bool) []byte {
+		m := "false"
+		if merged {
+			m = "true"
+		}
+		return []byte(`{"action": "` + action + `", "number": 7, "pull_request": {"title": "Add dark mode",
+			"html_url": "https://github.com/acme/Web.App/pull/7", "merged": ` + m + `, "user": {"login": "octocat"},
+			"head": {"ref": "dark-mode"}, "base": {"ref": "main"}}, ` + repo + `}`)
+	}
+
+	tests := []struct {
+		action string
+		merged bool
+		title  string
+	}{
+		{"opened", false, "PR #7 opened: Add dark mode"},
+		{"closed", true, "PR #7 merged: Add dark mode"},
+		{"closed", false, "PR #7 closed: Add dark mode"},
+		{"ready_for_review", false, "PR #7 ready for review: Add dark mode"},
+		{"synchronize", false, ""},
+	}
+	for _, tt := range tests {
+		event, err := Parse("pull_request", payload(tt.action, tt.merged))
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		opts, _ := event.Notification(Mapping{})
+		if tt.title == "" {
+			if opts != nil {
+				t.Errorf("%s: expected no notification, got %+v", tt.action, opts)
+			}
+			continue
+		}
+		if opts == nil || opts.Title != tt.title {
+			t.Errorf("%s: expected title %q, got %+v", tt.action, tt.title, opts)
+			continue
+		}
+		if opts.Message != "acme/Web.App: dark-mode → main by octocat" {
+			t.Errorf("unexpected message: %q", opts.Message)
+		}
+	}
+}
+
+func TestParse_Push(t *testing.T) {
+	var commits []string
+	for i := 0; i < 7; i++ {
+		commits = append(commits, `{"id": "abcdef0123456789", "message": "Commit subject\n\nBody"}`)
+	}
+	event, err := Parse("push", []byte(`{"ref": "refs/heads/main", "forced": true, "compare": "https://github.com/acme/Web.App/compare/a...b",
+		"pusher": {"name": "octocat"}, "commits": [`+strings.Join(commits, ",")+`], `+repo+`}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	opts, _ := event.Notification(Mapping{})
+	if opts.Title != "octocat force-pushed 7 commits to Web.App/main" {
+		t.Errorf("unexpected title: %q", opts.Title)
+	}
+	lines := strings.Split(opts.Message, "\n")
+	if len(lines) != maxCommits+1 || lines[0] != "abcdef0 Commit subject" || lines[maxCommits] != "... and 2 more" {
+		t.Errorf("unexpected message: %q", opts.Message)
+	}
+
+	// A deleted branch is not notified by default
+	event, err = Parse("push", []byte(`{"ref": "refs/tags/v1", "deleted": true, "pusher": {"name": "octocat"}, `+repo+`}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if opts, _ := event.Notification(Mapping{}); opts != nil {
+		t.Errorf("expected no notification for a deleted tag, got %+v", opts)
+	}
+	opts, _ = event.Notification(Mapping{Actions: []string{"*"}})
+	if opts == nil || opts.Title != "octocat deleted v1 in Web.App" {
+		t.Errorf("unexpected notification with all actions: %+v", opts)
+	}
+}
+
+func TestParse_Unsupported(t *testing.T) {
+	if _, err := Parse("issues", []byte(`{}`)); !stderrors.Is(err, ErrUnsupportedEvent) {
+		t.Errorf("expected ErrUnsupportedEvent, got %v", err)
+	}
+	if _, err := Parse("push", []byte(`not json`)); err == nil {
+		t.Error("expected an error for an invalid payload")
+	}
+}
+
+func TestNotification_Mapping(t *testing.T) {
+	event, err := Parse("release", []byte(`{"action": "published", "release": {"tag_name": "v2.0.0", "html_url": "https://example.com/r"}, `+repo+`}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	opts, err := event.Notification(Mapping{Template: templates.Template{
+		Title: "{{.Vars.repo}} {{.Vars.tag | upper}}",
+		Type:  "release",
+		Tags:  []string{"releases", "{{.Vars.event}}"},
+	}})
+	if err != nil {
+		t.Fatalf("Notification failed: %v", err)
+	}
+	if opts.Title != "acme/Web.App V2.0.0" || opts.Type != "release" {
+		t.Errorf("expected overridden fields, got %+v", opts)
+	}
+	if opts.Message != "v2.0.0" || opts.ActionURL != "https://example.com/r" {
+		t.Errorf("expected default message and action URL, got %+v", opts)
+	}
+	if len(opts.Tags) != 2 || opts.Tags[0] != "releases" || opts.Tags[1] != "release" {
+		t.Errorf("expected the mapping tags, got %v", opts.Tags)
+	}
+
+	if opts, _ := event.Notification(Mapping{Disabled: true}); opts != nil {
+		t.Errorf("expected a disabled mapping to ignore the event, got %+v", opts)
+	}
+	if opts, _ := event.Notification(Mapping{Actions: []string{"created"}}); opts != nil {
+		t.Errorf("expected an unselected action to be ignored, got %+v", opts)
+	}
+	if _, err := event.Notification(Mapping{Template: templates.Template{Title: "{{.Vars.missing}}"}}); err == nil {
+		t.Error("expected an error for an unknown variable")
+	}
+}
@@ -0,0 +1,315 @@
+// Package jobs keeps the state of scheduled jobs run by pincho cron.
+//
+// A job notifies only when its outcome changes: on the first failed run, on
+// the first successful run after failures, and every few runs while it keeps
+// failing. To tell these apart, each job has a state file
+// (~/.pincho/jobs/<name>.json) with its failure streak and its recent runs,
+// which also give the usual duration of a run. A transition stays pending in
+// the state until its notification was delivered (Notified), so a failed
+// send is retried on the next run instead of being lost.
+//
+// A run holds the job's lock (<name>.lock) while the command runs, so an
+// overlapping run started while the previous one is still going can be
+// detected and skipped. Locks are released by the operating system when the
+// process ends, even if it is killed, so they never go stale.
+//
+// Example usage:
+//
+//	job, err := jobs.Open("backup")
+//	lock, err := job.Lock()
+//	if stderrors.Is(err, jobs.ErrLocked) {
+//		// The previous run is still running
+//	}
+//	defer lock.Unlock()
+//
+//	var pending *jobs.Pending
+//	err = job.Update(func(s *jobs.State) error {
+//		s.Record(run, jobs.DefaultRemindEvery)
+//		pending = s.Pending
+//		return nil
+//	})
+//	// Notify about pending, then
+//	err = job.Update(func(s *jobs.State) error {
+//		s.Notified()
+//		return nil
+//	})
+package jobs
+
+import (
+	stderrors "errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+const (
+	// DirName is the name of the jobs directory inside the config directory
+	DirName = "jobs"
+
+	// DefaultRemindEvery is the default number of failed runs between reminders
+	DefaultRemindEvery = 10
+
+	// MaxHistory is the number of recent runs kept per job
+	MaxHistory = 20
+)
+
+// ErrLocked is returned by Lock when another run of the job holds the lock
+var ErrLocked = stderrors.New("job is already running")
+
+// nameRegex restricts job names to safe file names
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Transition is a change in a job's outcome worth a notification
+type Transition int
+
+const (
+	// NoChange means the run turned out like the previous one
+	NoChange Transition = iota
+
+	// Failed is the first failed run after a success, or the first run of a job
+	Failed
+
+	// StillFailing is a reminder that the job keeps failing
+	StillFailing
+
+	// Recovered is the first successful run after failures
+	Recovered
+)
+
+// String returns the name of the transition
+func (t Transition) String() string {
+	switch t {
+	case Failed:
+		return "failed"
+	case StillFailing:
+		return "still_failing"
+	case Recovered:
+		return "recovered"
+	default:
+		return "no_change"
+	}
+}
+
+// Run is one run of a job
+type Run struct {
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	ExitCode  int           `json:"exitCode"`
+	PID       int           `json:"pid,omitempty"`
+}
+
+// Success reports whether the run exited with code 0
+func (r Run) Success() bool {
+	return r.ExitCode == 0
+}
+
+// Pending is a transition whose notification was not delivered yet
+type Pending struct {
+	Transition Transition `json:"transition"`
+
+	// Failures and FailingSince describe the failure streak the notification
+	// reports; for Recovered, the streak that ended
+	Failures     int       `json:"failures,omitempty"`
+	FailingSince time.Time `json:"failingSince,omitempty"`
+}
+
+// State is the persisted state of a job
+type State struct {
+	// Running is the run holding the lock, if any
+	Running *Run `json:"running,omitempty"`
+
+	// Runs is the total number of finished runs
+	Runs int `json:"runs"`
+
+	// Failures is the number of consecutive failed runs, 0 after a success
+	Failures int `json:"failures"`
+
+	// FailingSince is when the first of the consecutive failed runs started
+	FailingSince time.Time `json:"failingSince,omitempty"`
+
+	// Overlaps is the number of consecutive runs skipped because the job was still running
+	Overlaps int `json:"overlaps,omitempty"`
+
+	// History holds the most recent finished runs, oldest first
+	History []Run `json:"history,omitempty"`
+
+	// Pending is the transition still to be notified, if any
+	Pending *Pending `json:"pending,omitempty"`
+}
+
+// Record adds a finished run and returns the transition it makes
+//
+// While the job keeps failing, every remindEvery-th consecutive failure is a
+// StillFailing reminder; 0 disables reminders. A successful first run is not
+// a transition: there is nothing to recover from.
+//
+// A transition becomes Pending until Notified is called. A run that changes
+// nothing keeps an earlier pending transition, brought up to date: while the
+// job keeps failing, an undelivered Failed or StillFailing notification
+// becomes a StillFailing one for the current streak.
+func (s *State) Record(run Run, remindEvery int) Transition {
+	s.Running = nil
+	s.Overlaps = 0
+	s.Runs++
+	s.History = append(s.History, run)
+	if len(s.History) > MaxHistory {
+		s.History = s.History[len(s.History)-MaxHistory:]
+	}
+
+	if run.Success() {
+		failures, failingSince := s.Failures, s.FailingSince
+		s.Failures = 0
+		s.FailingSince = time.Time{}
+		if failures > 0 {
+			s.Pending = &Pending{Transition: Recovered, Failures: failures, FailingSince: failingSince}
+			return Recovered
+		}
+		return NoChange
+	}
+
+	s.Failures++
+	if s.Failures == 1 {
+		s.FailingSince = run.StartedAt
+		s.Pending = &Pending{Transition: Failed, Failures: 1, FailingSince: run.StartedAt}
+		return Failed
+	}
+	if remindEvery > 0 && (s.Failures-1)%remindEvery == 0 {
+		s.Pending = &Pending{Transition: StillFailing, Failures: s.Failures, FailingSince: s.FailingSince}
+		return StillFailing
+	}
+	if s.Pending != nil {
+		s.Pending = &Pending{Transition: StillFailing, Failures: s.Failures, FailingSince: s.FailingSince}
+	}
+	return NoChange
+}
+
+// Notified clears the pending transition once its notification was delivered
+func (s *State) Notified() {
+	s.Pending = nil
+}
+
+// RecordOverlap counts a run skipped because the job was still running
+// Reports whether to notify about it: the first skipped run, and every
+// remindEvery-th one after it (0 disables reminders).
+func (s *State) RecordOverlap(remindEvery int) bool {
+	s.Overlaps++
+	return s.Overlaps == 1 || (remindEvery > 0 && (s.Overlaps-1)%remindEvery == 0)
+}
+
+// MedianDuration returns the median duration of the successful runs in the history
+// The count is 0 if there are none.
+func (s *State) MedianDuration() (time.Duration, int) {
+	var durations []time.Duration
+	for _, run := range s.History {
+		if run.Success() {
+			durations = append(durations, run.Duration)
+		}
+	}
+	if len(durations) == 0 {
+		return 0, 0
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	mid := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[mid-1] + durations[mid]) / 2, len(durations)
+	}
+	return durations[mid], len(durations)
+}
+
+// Job is a named scheduled job
+type Job struct {
+	Name string
+	dir  string
+}
+
+// ValidateName checks that name can be used as a job name
+func ValidateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return fmt.Errorf("invalid job name %q (only letters, numbers, hyphens, and underscores allowed)", name)
+	}
+	return nil
+}
+
+// New returns the job called name with its files in dir
+func New(dir, name string) (*Job, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	return &Job{Name: name, dir: dir}, nil
+}
+
+// Open returns the job called name in the config directory (~/.pincho/jobs)
+func Open(name string) (*Job, error) {
+	dir, err := state.Path(DirName)
+	if err != nil {
+		return nil, err
+	}
+	return New(dir, name)
+}
+
+// Lock is a held job lock
+type Lock struct {
+	lock *state.FileLock
+}
+
+// Lock takes the job's run lock without waiting
+// Returns ErrLocked if another run holds it.
+func (j *Job) Lock() (*Lock, error) {
+	lock, err := state.TryLock(j.path(".lock"))
+	if stderrors.Is(err, state.ErrLocked) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Lock{lock: lock}, nil
+}
+
+// Unlock releases the lock
+func (l *Lock) Unlock() error {
+	return l.lock.Unlock()
+}
+
+// State returns the job's state, empty if it never ran
+func (j *Job) State() (*State, error) {
+	var s State
+	if _, err := state.ReadJSON(j.path(".json"), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Update changes the job's state with fn and saves it
+// Updates are serialized between processes, so a run skipped because of an
+// overlap can safely update the state of the job that is still running.
+func (j *Job) Update(fn func(*State) error) error {
+	lock, err := state.Lock(j.path(".state.lock"))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	s, err := j.State()
+	if err != nil {
+		// Start over rather than failing forever on a corrupt file
+		s = &State{}
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+
+	if err := state.WriteJSON(j.path(".json"), s); err != nil {
+		return fmt.Errorf("failed to write job state: %w", err)
+	}
+	return nil
+}
+
+// path returns the path of one of the job's files
+func (j *Job) path(ext string) string {
+	return filepath.Join(j.dir, j.Name+ext)
+}
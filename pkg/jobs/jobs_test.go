@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	stderrors "errors"
+	"testing"
+	"time"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"backup", "db_dump", "sync-2"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc", "a/b", "with space"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) expected error", name)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	run := func(i, exitCode int) Run {
+		return Run{StartedAt: start.Add(time.Duration(i) * 5 * time.Minute), Duration: time.Minute, ExitCode: exitCode}
+	}
+
+	var s State
+	exitCodes := []int{0, 0, 1, 2, 1, 1, 0, 0, 1}
+	want := []Transition{NoChange, NoChange, Failed, NoChange, StillFailing, NoChange, Recovered, NoChange, Failed}
+	for i, code := range exitCodes {
+		if got := s.Record(run(i, code), 2); got != want[i] {
+			t.Errorf("run %d (exit code %d): expected %s, got %s", i, code, want[i], got)
+		}
+		if i == 4 && (s.Failures != 3 || !s.FailingSince.Equal(run(2, 1).StartedAt)) {
+			t.Errorf("expected 3 failures since run 2, got %d since %v", s.Failures, s.FailingSince)
+		}
+	}
+	if s.Runs != len(exitCodes) {
+		t.Errorf("expected %d runs, got %d", len(exitCodes), s.Runs)
+	}
+}
+
+func TestRecord_FirstRunFails(t *testing.T) {
+	var s State
+	if got := s.Record(Run{ExitCode: 1}, DefaultRemindEvery); got != Failed {
+		t.Errorf("expected a failed first run to be %s, got %s", Failed, got)
+	}
+}
+
+func TestRecord_NoReminders(t *testing.T) {
+	var s State
+	for i := 0; i < 5; i++ {
+		if got := s.Record(Run{ExitCode: 1}, 0); i > 0 && got != NoChange {
+			t.Errorf("run %d: expected no reminder, got %s", i, got)
+		}
+	}
+}
+
+func TestRecord_Pending(t *testing.T) {
+	start := time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)
+	run := func(i, exitCode int) Run {
+		return Run{StartedAt: start.Add(time.Duration(i) * 5 * time.Minute), ExitCode: exitCode}
+	}
+
+	tests := []struct {
+		name     string
+		exitCode int
+		notified bool
+		want     *Pending
+	}{
+		{"first failure", 1, false, &Pending{Transition: Failed, Failures: 1, FailingSince: run(0, 1).StartedAt}},
+		{"undelivered failure is kept up to date", 1, false, &Pending{Transition: StillFailing, Failures: 2, FailingSince: run(0, 1).StartedAt}},
+		{"delivered", 1, true, nil},
+		{"no change after delivery", 1, false, nil},
+		{"recovery", 0, false, &Pending{Transition: Recovered, Failures: 4, FailingSince: run(0, 1).StartedAt}},
+		{"undelivered recovery is kept", 0, false, &Pending{Transition: Recovered, Failures: 4, FailingSince: run(0, 1).StartedAt}},
+		{"failure replaces undelivered recovery", 1, false, &Pending{Transition: Failed, Failures: 1, FailingSince: run(6, 1).StartedAt}},
+	}
+
+	var s State
+	for i, tt := range tests {
+		s.Record(run(i, tt.exitCode), 0)
+		if tt.notified {
+			s.Notified()
+		}
+
+		switch {
+		case tt.want == nil && s.Pending != nil:
+			t.Errorf("%s: expected nothing pending, got %+v", tt.name, *s.Pending)
+		case tt.want != nil && (s.Pending == nil || *s.Pending != *tt.want):
+			t.Errorf("%s: expected %+v pending, got %+v", tt.name, *tt.want, s.Pending)
+		}
+	}
+}
+
+func TestRecord_History(t *testing.T) {
+	var s State
+	for i := 0; i < MaxHistory+5; i++ {
+		s.Record(Run{Duration: time.Duration(i) * time.Second}, 0)
+	}
+	if len(s.History) != MaxHistory {
+		t.Fatalf("expected %d runs in history, got %d", MaxHistory, len(s.History))
+	}
+	if s.History[0].Duration != 5*time.Second {
+		t.Errorf("expected the oldest runs to be dropped, first is %v", s.History[0].Duration)
+	}
+}
+
+func TestRecordOverlap(t *testing.T) {
+	var s State
+	var notified []int
+	for i := 1; i <= 7; i++ {
+		if s.RecordOverlap(3) {
+			notified = append(notified, i)
+		}
+	}
+	if len(notified) != 3 || notified[0] != 1 || notified[1] != 4 || notified[2] != 7 {
+		t.Errorf("expected notifications for overlaps 1, 4 and 7, got %v", notified)
+	}
+
+	s.Record(Run{}, 3)
+	if s.Overlaps != 0 {
+		t.Errorf("expected a finished run to reset overlaps, got %d", s.Overlaps)
+	}
+}
+
+func TestMedianDuration(t *testing.T) {
+	var s State
+	if _, n := s.MedianDuration(); n != 0 {
+		t.Errorf("expected no median without runs, got %d runs", n)
+	}
+
+	for _, d := range []time.Duration{3, 1, 100, 2} {
+		s.Record(Run{Duration: d * time.Second}, 0)
+	}
+	// Failed runs are not counted
+	s.Record(Run{Duration: time.Hour, ExitCode: 1}, 0)
+
+	median, n := s.MedianDuration()
+	if n != 4 || median != 2500*time.Millisecond {
+		t.Errorf("expected median 2.5s of 4 runs, got %v of %d", median, n)
+	}
+}
+
+func TestLock(t *testing.T) {
+	job, err := New(t.TempDir(), "backup")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	lock, err := job.Lock()
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if _, err := job.Lock(); !stderrors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked while locked, got %v", err)
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	lock, err = job.Lock()
+	if err != nil {
+		t.Fatalf("Lock after Unlock failed: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestUpdate(t *testing.T) {
+	job, err := New(t.TempDir(), "backup")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	s, err := job.State()
+	if err != nil {
+		t.Fatalf("State failed: %v", err)
+	}
+	if s.Runs != 0 || s.Running != nil {
+		t.Errorf("expected empty state, got %+v", s)
+	}
+
+	for i := 0; i < 2; i++ {
+		err := job.Update(func(s *State) error {
+			s.Record(Run{ExitCode: 1, Duration: time.Second}, 0)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	s, _ = job.State()
+	if s.Runs != 2 || s.Failures != 2 || len(s.History) != 2 || s.History[1].Duration != time.Second {
+		t.Errorf("unexpected state: %+v", s)
+	}
+
+	// An error from fn leaves the state unchanged
+	wantErr := stderrors.New("boom")
+	err = job.Update(func(s *State) error {
+		s.Runs = 100
+		return wantErr
+	})
+	if !stderrors.Is(err, wantErr) {
+		t.Errorf("expected fn's error, got %v", err)
+	}
+	if s, _ = job.State(); s.Runs != 2 {
+		t.Errorf("expected state to be unchanged, got %d runs", s.Runs)
+	}
+}
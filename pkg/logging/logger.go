@@ -24,13 +24,20 @@
 //	    "api_url", "https://api.pincho.app/send",
 //	    "timeout", "30s",
 //	    "max_retries", 3)
+//
+// Values of keys containing "password" or "secret" are printed as [REDACTED],
+// so verbose output can be shared in bug reports.
 package logging
 
 import (
 	"log/slog"
 	"os"
+	"strings"
 )
 
+// redacted replaces the value of secret attributes
+const redacted = "[REDACTED]"
+
 var (
 	// logger is the global logger instance
 	logger *slog.Logger
@@ -52,32 +59,44 @@ func updateLogger() {
 	}
 
 	opts := &slog.HandlerOptions{
-		Level: level,
-		// Custom replacement to format output for CLI use
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			// Remove time for cleaner output
-			if a.Key == slog.TimeKey {
-				return slog.Attr{}
-			}
-			// Simplify level names
-			if a.Key == slog.LevelKey {
-				level := a.Value.Any().(slog.Level)
-				switch level {
-				case slog.LevelDebug:
-					a.Value = slog.StringValue("VERBOSE")
-				case slog.LevelInfo:
-					a.Value = slog.StringValue("INFO")
-				case slog.LevelError:
-					a.Value = slog.StringValue("ERROR")
-				}
-			}
-			return a
-		},
+		Level:       level,
+		ReplaceAttr: replaceAttr,
 	}
 
 	logger = slog.New(slog.NewTextHandler(os.Stderr, opts))
 }
 
+// replaceAttr formats attributes for CLI use and redacts secrets
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	// Remove time for cleaner output
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	// Simplify level names
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		level := a.Value.Any().(slog.Level)
+		switch level {
+		case slog.LevelDebug:
+			a.Value = slog.StringValue("VERBOSE")
+		case slog.LevelInfo:
+			a.Value = slog.StringValue("INFO")
+		case slog.LevelError:
+			a.Value = slog.StringValue("ERROR")
+		}
+	}
+	// Never print secrets, even in verbose mode
+	if isSecretKey(a.Key) {
+		a.Value = slog.StringValue(redacted)
+	}
+	return a
+}
+
+// isSecretKey reports whether an attribute key names a secret
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return strings.Contains(key, "password") || strings.Contains(key, "secret")
+}
+
 // SetVerbose enables or disables verbose logging
 func SetVerbose(enabled bool) {
 	verboseEnabled = enabled
@@ -194,3 +194,27 @@ func TestIsVerboseFunction(t *testing.T) {
 		t.Error("expected IsVerbose() to return true")
 	}
 }
+
+func TestRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: replaceAttr}
+	testLogger := slog.New(slog.NewTextHandler(&buf, opts))
+
+	oldLogger := logger
+	logger = testLogger
+	defer func() { logger = oldLogger }()
+
+	Debug("options", "encryption_password", "hunter2", "webhook_secret", "s3cr3t",
+		slog.Group("request", "Password", "hunter2"), "type", "secure")
+
+	output := buf.String()
+	if strings.Contains(output, "hunter2") || strings.Contains(output, "s3cr3t") {
+		t.Errorf("expected secrets to be redacted, got: %s", output)
+	}
+	if !strings.Contains(output, "encryption_password="+redacted) || !strings.Contains(output, "type=secure") {
+		t.Errorf("expected redacted secret and other attributes, got: %s", output)
+	}
+	if strings.Contains(output, "time=") || !strings.Contains(output, "level=VERBOSE") {
+		t.Errorf("expected CLI formatting, got: %s", output)
+	}
+}
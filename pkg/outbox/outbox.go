@@ -0,0 +1,299 @@
+// Package outbox provides a durable on-disk queue for notifications that
+// could not be delivered.
+//
+// When a send ultimately fails with a transient error (network failure,
+// server error, rate limit or open circuit breaker), the fully prepared
+// request is spooled to the outbox directory (~/.pincho/outbox) and delivered
+// later by Flush. Entries hold the request body exactly as it will be sent:
+// messages are already encrypted, and neither the encryption password nor the
+// API token is stored. Each entry records a fingerprint of the token it was
+// queued for, so a flush never delivers it with a different token.
+//
+// Every entry keeps the idempotency key of the original request, so a
+// request that reached the API before the connection failed is not delivered
+// twice.
+//
+// Entries are stored one JSON file per entry and are flushed oldest first.
+// A flush stops at the first failure other than a rejection, so later
+// entries never overtake earlier ones. Flushes hold an exclusive lock on the
+// outbox, so concurrent processes never deliver the same entry twice.
+//
+// Example usage:
+//
+//	box, err := outbox.Default()
+//
+//	prepared, err := c.PrepareSend(opts)
+//	if _, err := c.SendPrepared(ctx, prepared); outbox.ShouldQueue(err) {
+//		entry, err := box.Add(client.TokenFingerprint(c.Token), prepared, err)
+//	}
+//
+//	result, err := box.Flush(ctx, c)
+package outbox
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/state"
+)
+
+// DirName is the name of the outbox directory inside the config directory
+const DirName = "outbox"
+
+// entryExt is the file extension of outbox entries
+const entryExt = ".json"
+
+// flushLockName is the lock file serializing flushes, skipped as an entry
+const flushLockName = ".flush.lock"
+
+// Entry is a queued notification
+type Entry struct {
+	ID          string                 `json:"id"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	TokenID     string                 `json:"tokenId"` // client.TokenFingerprint of the token to send with
+	Request     client.PreparedRequest `json:"request"`
+	Attempts    int                    `json:"attempts"`
+	LastAttempt time.Time              `json:"lastAttempt"`
+	LastError   string                 `json:"lastError,omitempty"`
+	Failed      bool                   `json:"failed,omitempty"` // Rejected by the API, never retried
+}
+
+// Outbox is a directory of queued notifications
+type Outbox struct {
+	dir string
+}
+
+// New creates an outbox stored in dir
+func New(dir string) *Outbox {
+	return &Outbox{dir: dir}
+}
+
+// Default returns the outbox in the config directory (~/.pincho/outbox)
+func Default() (*Outbox, error) {
+	dir, err := state.Path(DirName)
+	if err != nil {
+		return nil, err
+	}
+	return New(dir), nil
+}
+
+// Dir returns the directory the outbox is stored in
+func (o *Outbox) Dir() string {
+	return o.dir
+}
+
+// ShouldQueue reports whether a failed request is worth queueing
+// Only transient failures qualify; requests the API rejected would be
+// rejected again.
+func ShouldQueue(err error) bool {
+	var networkErr *errors.NetworkError
+	var serverErr *errors.ServerError
+	var rateLimitErr *errors.RateLimitError
+	var circuitErr *errors.CircuitOpenError
+	return stderrors.As(err, &networkErr) ||
+		stderrors.As(err, &serverErr) ||
+		stderrors.As(err, &rateLimitErr) ||
+		stderrors.As(err, &circuitErr)
+}
+
+// Add queues a prepared request for the token identified by tokenID
+// cause is the error of the failed attempt, if any.
+func (o *Outbox) Add(tokenID string, req *client.PreparedRequest, cause error) (*Entry, error) {
+	id, err := state.NewEntryID(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		ID:        id,
+		CreatedAt: time.Now().UTC(),
+		TokenID:   tokenID,
+		Request:   *req,
+	}
+	if cause != nil {
+		entry.Attempts = 1
+		entry.LastAttempt = entry.CreatedAt
+		entry.LastError = cause.Error()
+	}
+
+	if err := o.save(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// List returns all queued entries, oldest first
+func (o *Outbox) List() ([]*Entry, error) {
+	names, err := o.entryFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(names))
+	for _, name := range names {
+		var entry Entry
+		found, err := state.ReadJSON(filepath.Join(o.dir, name), &entry)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			entries = append(entries, &entry)
+		}
+	}
+	return entries, nil
+}
+
+// Remove deletes a queued entry
+func (o *Outbox) Remove(id string) error {
+	if err := os.Remove(o.entryPath(id)); err != nil && !stderrors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove outbox entry: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes queued entries and returns how many were deleted
+// If failedOnly is set, only entries rejected by the API are deleted.
+func (o *Outbox) Purge(failedOnly bool) (int, error) {
+	entries, err := o.List()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, entry := range entries {
+		if failedOnly && !entry.Failed {
+			continue
+		}
+		if err := o.Remove(entry.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// FlushResult summarizes a flush
+type FlushResult struct {
+	Sent     []*Entry // Delivered and removed from the outbox
+	Rejected []*Entry // Rejected by the API in this flush, kept and marked failed
+	Skipped  int      // Entries for another token or marked failed earlier
+	Pending  int      // Entries not attempted because the flush stopped
+	StopErr  error    // Error that stopped the flush, if any; see Transient
+}
+
+// Transient reports whether the flush stopped on a transient error
+// Only then is it worth queueing new notifications behind the pending ones;
+// other errors, such as an authentication failure, would fail them as well.
+func (r *FlushResult) Transient() bool {
+	return r.StopErr != nil && ShouldQueue(r.StopErr)
+}
+
+// Flush delivers queued entries for the client's token, oldest first
+//
+// Delivered entries are removed. An entry rejected by the API (validation
+// error) is marked failed and skipped by later flushes. Any other error
+// stops the flush so that ordering is preserved; the entry stays queued and
+// the error is returned in FlushResult.StopErr. That includes transient
+// errors such as a network failure, rate limit or open circuit breaker, and
+// errors affecting every entry for the token, such as an authentication
+// failure. The client's quota tracking makes a flush stop before sending
+// when the rate limit is known to be exhausted.
+//
+// The outbox is locked for the whole flush; a concurrent flush waits for it
+// and then sees the entries this one delivered as removed.
+//
+// The returned error is only set if the outbox itself cannot be read or written.
+func (o *Outbox) Flush(ctx context.Context, c *client.Client) (*FlushResult, error) {
+	lock, err := state.Lock(filepath.Join(o.dir, flushLockName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	defer lock.Unlock()
+
+	entries, err := o.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FlushResult{}
+	tokenID := client.TokenFingerprint(c.Token)
+
+	for i, entry := range entries {
+		if entry.Failed || entry.TokenID != tokenID {
+			result.Skipped++
+			continue
+		}
+
+		_, sendErr := c.SendPrepared(ctx, &entry.Request)
+
+		var duplicateErr *errors.DuplicateError
+		var validationErr *errors.ValidationError
+		switch {
+		case sendErr == nil, stderrors.As(sendErr, &duplicateErr):
+			// A duplicate key means an earlier attempt was delivered
+			if err := o.Remove(entry.ID); err != nil {
+				return result, err
+			}
+			result.Sent = append(result.Sent, entry)
+			continue
+		case stderrors.As(sendErr, &validationErr):
+			entry.Failed = true
+			result.Rejected = append(result.Rejected, entry)
+		default:
+			result.StopErr = sendErr
+			result.Pending = countPending(entries[i+1:], tokenID)
+		}
+
+		entry.Attempts++
+		entry.LastAttempt = time.Now().UTC()
+		entry.LastError = sendErr.Error()
+		if err := o.save(entry); err != nil {
+			return result, err
+		}
+
+		if result.StopErr != nil {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// countPending counts the entries a flush would still have attempted
+func countPending(entries []*Entry, tokenID string) int {
+	pending := 0
+	for _, entry := range entries {
+		if !entry.Failed && entry.TokenID == tokenID {
+			pending++
+		}
+	}
+	return pending
+}
+
+// save writes an entry to its file
+func (o *Outbox) save(entry *Entry) error {
+	if err := state.WriteJSON(o.entryPath(entry.ID), entry); err != nil {
+		return fmt.Errorf("failed to write outbox entry: %w", err)
+	}
+	return nil
+}
+
+// entryPath returns the file path of an entry
+func (o *Outbox) entryPath(id string) string {
+	return filepath.Join(o.dir, id+entryExt)
+}
+
+// entryFiles returns the names of all entry files, oldest first
+func (o *Outbox) entryFiles() ([]string, error) {
+	names, err := state.EntryFiles(o.dir, entryExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	return names, nil
+}
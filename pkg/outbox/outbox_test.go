@@ -0,0 +1,370 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+	"github.com/Pincho-App/pincho-cli/pkg/pinchotest"
+)
+
+// newTestClient builds a client for srv without retries
+func newTestClient(srv *pinchotest.Server, token string) *client.Client {
+	return client.NewWithOptions(
+		client.WithBaseURL(srv.URL),
+		client.WithToken(token),
+		client.WithRetries(0, time.Millisecond),
+	)
+}
+
+// queue prepares a send request and adds it to box
+func queue(t *testing.T, box *Outbox, c *client.Client, opts *client.SendOptions) *Entry {
+	t.Helper()
+	prepared, err := c.PrepareSend(opts)
+	if err != nil {
+		t.Fatalf("PrepareSend failed: %v", err)
+	}
+	entry, err := box.Add(client.TokenFingerprint(c.Token), prepared, nil)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	return entry
+}
+
+func TestAddAndList_Order(t *testing.T) {
+	box := New(t.TempDir())
+	c := client.NewWithOptions(client.WithToken("token"))
+
+	for _, title := range []string{"first", "second", "third"} {
+		queue(t, box, c, &client.SendOptions{Title: title})
+	}
+
+	entries, err := box.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for i, title := range []string{"first", "second", "third"} {
+		var body client.SendOptions
+		if err := json.Unmarshal(entries[i].Request.Body, &body); err != nil || body.Title != title {
+			t.Errorf("entry %d: expected title %q, got body %s", i, title, entries[i].Request.Body)
+		}
+	}
+}
+
+func TestList_MissingDir(t *testing.T) {
+	box := New(filepath.Join(t.TempDir(), "missing"))
+	entries, err := box.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+}
+
+func TestAdd_StoresNoSecrets(t *testing.T) {
+	dir := t.TempDir()
+	box := New(dir)
+	c := client.NewWithOptions(client.WithToken("secret-token"))
+
+	entry := queue(t, box, c, &client.SendOptions{
+		Title:              "Backup",
+		Message:            "plaintext message",
+		EncryptionPassword: "secret-password",
+	})
+
+	data, err := os.ReadFile(filepath.Join(dir, entry.ID+entryExt))
+	if err != nil {
+		t.Fatalf("failed to read entry file: %v", err)
+	}
+	for _, secret := range []string{"secret-token", "secret-password", "plaintext message"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("entry file contains %q: %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"iv"`) {
+		t.Errorf("expected encrypted message with IV, got %s", data)
+	}
+}
+
+func TestFlush_DeliversInOrder(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	box := New(t.TempDir())
+	c := newTestClient(srv, "token")
+	for _, title := range []string{"first", "second"} {
+		queue(t, box, c, &client.SendOptions{Title: title})
+	}
+
+	result, err := box.Flush(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(result.Sent) != 2 || result.StopErr != nil {
+		t.Fatalf("expected 2 sent and no stop error, got %+v", result)
+	}
+
+	got := srv.Notifications()
+	if len(got) != 2 || got[0].Title != "first" || got[1].Title != "second" {
+		t.Errorf("unexpected delivery order: %+v", got)
+	}
+
+	entries, _ := box.List()
+	if len(entries) != 0 {
+		t.Errorf("expected outbox to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestFlush_StopsOnTransientError(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	box := New(t.TempDir())
+	c := newTestClient(srv, "token")
+	for _, title := range []string{"first", "second", "third"} {
+		queue(t, box, c, &client.SendOptions{Title: title})
+	}
+
+	srv.InjectFaults(pinchotest.Fault{Status: http.StatusServiceUnavailable})
+
+	result, err := box.Flush(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, ok := result.StopErr.(*errors.ServerError); !ok {
+		t.Fatalf("expected ServerError stop error, got %T: %v", result.StopErr, result.StopErr)
+	}
+	if !result.Transient() {
+		t.Error("expected a server error to be transient")
+	}
+	if len(result.Sent) != 0 || result.Pending != 2 {
+		t.Errorf("expected 0 sent and 2 pending, got %+v", result)
+	}
+	if srv.Requests() != 1 {
+		t.Errorf("expected later entries not to be attempted, got %d requests", srv.Requests())
+	}
+
+	entries, _ := box.List()
+	if len(entries) != 3 || entries[0].Attempts != 1 || entries[0].LastError == "" {
+		t.Fatalf("expected first entry to record the attempt, got %+v", entries[0])
+	}
+
+	// The next flush delivers everything in the original order
+	result, err = box.Flush(context.Background(), c)
+	if err != nil || len(result.Sent) != 3 {
+		t.Fatalf("expected 3 sent on second flush, got %+v (err %v)", result, err)
+	}
+	got := srv.Notifications()
+	if len(got) != 3 || got[0].Title != "first" || got[2].Title != "third" {
+		t.Errorf("unexpected delivery order: %+v", got)
+	}
+}
+
+func TestFlush_StopsOnAuthError(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	box := New(t.TempDir())
+	c := newTestClient(srv, "token")
+	for _, title := range []string{"first", "second"} {
+		queue(t, box, c, &client.SendOptions{Title: title})
+	}
+
+	srv.InjectFaults(pinchotest.Fault{Status: http.StatusUnauthorized})
+
+	result, err := box.Flush(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, ok := result.StopErr.(*errors.AuthenticationError); !ok {
+		t.Fatalf("expected AuthenticationError stop error, got %T: %v", result.StopErr, result.StopErr)
+	}
+	if result.Transient() {
+		t.Error("expected an authentication error not to be transient")
+	}
+	if len(result.Rejected) != 0 || result.Pending != 1 {
+		t.Errorf("expected 0 rejected and 1 pending, got %+v", result)
+	}
+
+	// The entries stay queued for a flush with a working token
+	entries, _ := box.List()
+	if len(entries) != 2 || entries[0].Failed {
+		t.Errorf("expected both entries queued and not failed, got %+v", entries)
+	}
+}
+
+func TestFlush_ConcurrentFlushesDeliverOnce(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := newTestClient(srv, "token")
+	for i := 0; i < 10; i++ {
+		queue(t, New(dir), c, &client.SendOptions{Title: "queued"})
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := New(dir).Flush(context.Background(), newTestClient(srv, "token")); err != nil {
+				t.Errorf("Flush failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each entry is attempted once; duplicates would be refused by idempotency key
+	if got := srv.Requests(); got != 10 {
+		t.Errorf("expected 10 requests, got %d", got)
+	}
+	if entries, _ := New(dir).List(); len(entries) != 0 {
+		t.Errorf("expected an empty outbox, got %d entries", len(entries))
+	}
+}
+
+func TestFlush_StopsOnRateLimit(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+	srv.SetRateLimit(client.EndpointSend, 1, time.Hour)
+
+	box := New(t.TempDir())
+	c := newTestClient(srv, "token")
+	for _, title := range []string{"first", "second", "third"} {
+		queue(t, box, c, &client.SendOptions{Title: title})
+	}
+
+	result, err := box.Flush(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, ok := result.StopErr.(*errors.RateLimitError); !ok {
+		t.Fatalf("expected RateLimitError stop error, got %T: %v", result.StopErr, result.StopErr)
+	}
+	if len(result.Sent) != 1 || result.Pending != 1 {
+		t.Errorf("expected 1 sent and 1 pending, got %+v", result)
+	}
+}
+
+func TestFlush_RejectedEntriesAreMarkedFailed(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	box := New(t.TempDir())
+	c := newTestClient(srv, "token")
+
+	// A request the API rejects, followed by a valid one
+	invalid := &client.PreparedRequest{Endpoint: client.EndpointSend, Body: []byte(`{}`), IdempotencyKey: client.NewIdempotencyKey()}
+	if _, err := box.Add(client.TokenFingerprint("token"), invalid, nil); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	queue(t, box, c, &client.SendOptions{Title: "valid"})
+
+	result, err := box.Flush(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if len(result.Rejected) != 1 || len(result.Sent) != 1 || result.StopErr != nil {
+		t.Fatalf("expected 1 rejected and 1 sent, got %+v", result)
+	}
+
+	entries, _ := box.List()
+	if len(entries) != 1 || !entries[0].Failed {
+		t.Fatalf("expected the rejected entry to be kept and marked failed, got %+v", entries)
+	}
+
+	// Failed entries are skipped by later flushes
+	result, _ = box.Flush(context.Background(), c)
+	if result.Skipped != 1 || srv.Requests() != 2 {
+		t.Errorf("expected failed entry to be skipped, got %+v after %d requests", result, srv.Requests())
+	}
+
+	purged, err := box.Purge(true)
+	if err != nil || purged != 1 {
+		t.Errorf("Purge(true) = %d, %v; want 1, nil", purged, err)
+	}
+}
+
+func TestFlush_SkipsOtherTokens(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	box := New(t.TempDir())
+	queue(t, box, newTestClient(srv, "other-token"), &client.SendOptions{Title: "other"})
+
+	result, err := box.Flush(context.Background(), newTestClient(srv, "token"))
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if result.Skipped != 1 || srv.Requests() != 0 {
+		t.Errorf("expected entry for another token to be skipped, got %+v", result)
+	}
+}
+
+func TestFlush_ReusesIdempotencyKey(t *testing.T) {
+	srv := pinchotest.NewServer()
+	defer srv.Close()
+
+	box := New(t.TempDir())
+	c := newTestClient(srv, "token")
+	entry := queue(t, box, c, &client.SendOptions{Title: "Deploy"})
+
+	if _, err := box.Flush(context.Background(), c); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	got := srv.Notifications()
+	if len(got) != 1 || got[0].IdempotencyKey != entry.Request.IdempotencyKey {
+		t.Errorf("expected idempotency key %q to be sent, got %+v", entry.Request.IdempotencyKey, got)
+	}
+}
+
+func TestPurge_All(t *testing.T) {
+	box := New(t.TempDir())
+	c := client.NewWithOptions(client.WithToken("token"))
+	queue(t, box, c, &client.SendOptions{Title: "one"})
+	queue(t, box, c, &client.SendOptions{Title: "two"})
+
+	purged, err := box.Purge(false)
+	if err != nil || purged != 2 {
+		t.Fatalf("Purge(false) = %d, %v; want 2, nil", purged, err)
+	}
+	entries, _ := box.List()
+	if len(entries) != 0 {
+		t.Errorf("expected empty outbox, got %d entries", len(entries))
+	}
+}
+
+func TestShouldQueue(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.NewNetworkError("connection refused", nil), true},
+		{errors.NewServerErrorWithStatus("unavailable", 503), true},
+		{errors.NewRateLimitError("slow down"), true},
+		{&errors.CircuitOpenError{Failures: 5, OpenUntil: time.Now()}, true},
+		{errors.NewValidationError("title is required"), false},
+		{errors.NewAuthenticationError("invalid token"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := ShouldQueue(tt.err); got != tt.want {
+			t.Errorf("ShouldQueue(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
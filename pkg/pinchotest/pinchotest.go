@@ -0,0 +1,494 @@
+// Package pinchotest provides an in-process fake of the Pincho API for tests.
+//
+// The fake implements the /send and /notifai endpoints with the same request
+// and response shapes as the real API (client.SendResponse,
+// client.NotifAIResponse and the nested client.ErrorResponse), so code built
+// on pkg/client can be tested end to end without network access.
+//
+// Features:
+//   - Personal and team token responses
+//   - RateLimit-* headers and 429 responses with Retry-After
+//   - Scripted failures: error status codes, slow responses, connection resets
+//   - Idempotency-Key handling: a repeated key replays the first response
+//   - Every accepted notification is recorded for assertions
+//
+// Basic usage:
+//
+//	srv := pinchotest.NewServer()
+//	defer srv.Close()
+//
+//	c := client.NewWithOptions(client.WithBaseURL(srv.URL), client.WithToken("test-token"))
+//	_, err := c.Send(ctx, &client.SendOptions{Title: "Deploy"})
+//
+//	if got := srv.Notifications(); len(got) != 1 || got[0].Title != "Deploy" {
+//	    t.Errorf("unexpected notifications: %+v", got)
+//	}
+//
+// Endpoints are matched by the last path segment, so the fake also works
+// behind a base path such as /pincho/v1/.
+package pinchotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+)
+
+// notificationTTL is how long the fake reports notifications are kept
+const notificationTTL = 30 * 24 * time.Hour
+
+// Notification is a notification accepted by the fake API
+type Notification struct {
+	Endpoint       string // client.EndpointSend or client.EndpointNotifAI
+	Token          string
+	IdempotencyKey string
+	ReceivedAt     time.Time
+
+	// Fields sent to /send
+	Title     string
+	Message   string
+	Type      string
+	Tags      []string
+	ImageURL  string
+	ActionURL string
+	IV        string // Hex IV if Message is encrypted
+
+	// Fields sent to /notifai
+	Text string
+}
+
+// Fault is a scripted failure consumed by one request
+type Fault struct {
+	Status     int           // Respond with this status code instead of handling the request (0 handles it normally)
+	RetryAfter time.Duration // Retry-After header sent with Status, rounded up to whole seconds
+	Delay      time.Duration // Wait before responding or resetting the connection
+	Reset      bool          // Close the connection without sending a response
+}
+
+// requestBody is the union of the /send and /notifai request bodies
+type requestBody struct {
+	Title     string   `json:"title"`
+	Message   string   `json:"message"`
+	Type      string   `json:"type"`
+	Tags      []string `json:"tags"`
+	ImageURL  string   `json:"imageURL"`
+	ActionURL string   `json:"actionURL"`
+	IV        string   `json:"iv"`
+	Text      string   `json:"text"`
+}
+
+// team describes the team a team token sends to
+type team struct {
+	id      string
+	name    string
+	members int
+}
+
+// rateLimit tracks a fixed request window for one endpoint
+type rateLimit struct {
+	limit   int
+	window  time.Duration
+	used    int
+	resetAt time.Time
+}
+
+// replay is a response stored for an idempotency key
+type replay struct {
+	status int
+	body   []byte
+}
+
+// Handler is an http.Handler implementing the fake Pincho API
+// It is safe for concurrent use.
+type Handler struct {
+	// OnNotification is called for every accepted notification
+	// Set it before the first request is served
+	OnNotification func(Notification)
+
+	mu            sync.Mutex
+	personal      map[string]bool
+	teams         map[string]team
+	limits        map[string]*rateLimit
+	faults        []Fault
+	notifications []Notification
+	replays       map[string]replay
+	requests      int
+	nextID        int
+}
+
+// NewHandler creates a fake API handler
+// Until a token is registered, any non-empty token is accepted as a personal token
+func NewHandler() *Handler {
+	return &Handler{
+		personal: make(map[string]bool),
+		teams:    make(map[string]team),
+		limits:   make(map[string]*rateLimit),
+		replays:  make(map[string]replay),
+	}
+}
+
+// AddPersonalToken registers a token that sends to a single user
+func (h *Handler) AddPersonalToken(token string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.personal[token] = true
+}
+
+// AddTeamToken registers a token that sends to every member of a team
+func (h *Handler) AddTeamToken(token, teamID, teamName string, members int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.teams[token] = team{id: teamID, name: teamName, members: members}
+}
+
+// SetRateLimit allows limit requests per window on an endpoint (client.EndpointSend or client.EndpointNotifAI)
+// Requests over the limit get a 429 with Retry-After until the window resets
+func (h *Handler) SetRateLimit(endpoint string, limit int, window time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.limits[endpoint] = &rateLimit{limit: limit, window: window}
+}
+
+// InjectFaults queues scripted failures, each consumed by the next request in order
+func (h *Handler) InjectFaults(faults ...Fault) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = append(h.faults, faults...)
+}
+
+// Notifications returns a copy of the notifications accepted so far
+func (h *Handler) Notifications() []Notification {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Notification(nil), h.notifications...)
+}
+
+// Requests returns the number of requests received, including failed ones
+func (h *Handler) Requests() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.requests
+}
+
+// Clear forgets recorded notifications, idempotency keys and pending faults
+// Registered tokens and rate limits are kept
+func (h *Handler) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.notifications = nil
+	h.replays = make(map[string]replay)
+	h.faults = nil
+	h.requests = 0
+}
+
+// ServeHTTP handles a request to the fake API
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.requests++
+	var fault *Fault
+	if len(h.faults) > 0 {
+		fault = &h.faults[0]
+		h.faults = h.faults[1:]
+	}
+	h.mu.Unlock()
+
+	if fault != nil {
+		if fault.Delay > 0 {
+			select {
+			case <-time.After(fault.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if fault.Reset {
+			// Aborts the connection without a response and without logging a stack trace
+			panic(http.ErrAbortHandler)
+		}
+		if fault.Status != 0 {
+			if fault.RetryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(fault.RetryAfter)))
+			}
+			writeError(w, fault.Status, errorTypeForStatus(fault.Status), "injected_fault", fmt.Sprintf("injected fault (%d)", fault.Status), "")
+			return
+		}
+	}
+
+	var endpoint string
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/"+client.EndpointSend):
+		endpoint = client.EndpointSend
+	case strings.HasSuffix(r.URL.Path, "/"+client.EndpointNotifAI):
+		endpoint = client.EndpointNotifAI
+	default:
+		writeError(w, http.StatusNotFound, "invalid_request_error", "not_found", fmt.Sprintf("unknown endpoint %s", r.URL.Path), "")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method_not_allowed", "only POST is supported", "")
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		writeError(w, http.StatusUnauthorized, "authentication_error", "missing_token", "token is required", "")
+		return
+	}
+
+	h.mu.Lock()
+	tokenTeam, isTeam := h.teams[token]
+	known := isTeam || h.personal[token] || (len(h.personal) == 0 && len(h.teams) == 0)
+	h.mu.Unlock()
+	if !known {
+		writeError(w, http.StatusUnauthorized, "authentication_error", "invalid_token", "invalid token", "")
+		return
+	}
+
+	if !h.takeRateLimit(w, endpoint) {
+		writeError(w, http.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded", fmt.Sprintf("rate limit exceeded for the %s endpoint", endpoint), "")
+		return
+	}
+
+	var body requestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid_json", "request body must be valid JSON", "")
+		return
+	}
+
+	if param, message := validate(endpoint, &body); message != "" {
+		writeError(w, http.StatusBadRequest, "validation_error", "invalid_parameter", message, param)
+		return
+	}
+
+	idempotencyKey := r.Header.Get(client.IdempotencyKeyHeader)
+	replayKey := endpoint + ":" + token + ":" + idempotencyKey
+
+	h.mu.Lock()
+	if stored, ok := h.replays[replayKey]; ok && idempotencyKey != "" {
+		h.mu.Unlock()
+		writeJSON(w, stored.status, json.RawMessage(stored.body))
+		return
+	}
+
+	now := time.Now()
+	notification := Notification{
+		Endpoint:       endpoint,
+		Token:          token,
+		IdempotencyKey: idempotencyKey,
+		ReceivedAt:     now,
+		Title:          body.Title,
+		Message:        body.Message,
+		Type:           body.Type,
+		Tags:           body.Tags,
+		ImageURL:       body.ImageURL,
+		ActionURL:      body.ActionURL,
+		IV:             body.IV,
+		Text:           body.Text,
+	}
+	h.notifications = append(h.notifications, notification)
+	h.nextID++
+	response := buildResponse(notification, h.nextID, tokenTeam, isTeam)
+	responseBody, _ := json.Marshal(response)
+	if idempotencyKey != "" {
+		h.replays[replayKey] = replay{status: http.StatusOK, body: responseBody}
+	}
+	hook := h.OnNotification
+	h.mu.Unlock()
+
+	if hook != nil {
+		hook(notification)
+	}
+
+	writeJSON(w, http.StatusOK, json.RawMessage(responseBody))
+}
+
+// takeRateLimit counts a request against the endpoint's limit and sets the RateLimit-* headers
+// Returns false, with Retry-After set, if the limit is exhausted
+func (h *Handler) takeRateLimit(w http.ResponseWriter, endpoint string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	limit, ok := h.limits[endpoint]
+	if !ok {
+		return true
+	}
+
+	now := time.Now()
+	if !now.Before(limit.resetAt) {
+		limit.used = 0
+		limit.resetAt = now.Add(limit.window)
+	}
+
+	resetIn := ceilSeconds(limit.resetAt.Sub(now))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.limit))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(resetIn))
+
+	if limit.used >= limit.limit {
+		w.Header().Set("RateLimit-Remaining", "0")
+		w.Header().Set("Retry-After", strconv.Itoa(resetIn))
+		return false
+	}
+
+	limit.used++
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(limit.limit-limit.used))
+	return true
+}
+
+// validate applies the API's required field checks
+// Returns the offending parameter and an error message, or an empty message if valid
+func validate(endpoint string, body *requestBody) (string, string) {
+	switch endpoint {
+	case client.EndpointSend:
+		if body.Title == "" {
+			return "title", "title is required"
+		}
+	case client.EndpointNotifAI:
+		if len(body.Text) < 5 {
+			return "text", "text must be at least 5 characters long"
+		}
+		if len(body.Text) > 2500 {
+			return "text", "text must be at most 2500 characters long"
+		}
+	}
+	return "", ""
+}
+
+// buildResponse builds the success response for an accepted notification
+func buildResponse(n Notification, id int, t team, isTeam bool) any {
+	// NotifAI delivers the generated summary, to every member of a team too
+	var summary *client.NotifAISummary
+	if n.Endpoint == client.EndpointNotifAI {
+		summary = &client.NotifAISummary{Title: summaryTitle(n.Text), Message: n.Text}
+		n.Title, n.Message = summary.Title, summary.Message
+	}
+
+	details := func(userID string) client.NotificationDetails {
+		return client.NotificationDetails{
+			NotificationID: fmt.Sprintf("notif_%d", id),
+			UserID:         userID,
+			Title:          n.Title,
+			Body:           n.Message,
+			Type:           n.Type,
+			ImageURL:       n.ImageURL,
+			ActionURL:      n.ActionURL,
+			Timestamp:      n.ReceivedAt.UTC().Format(time.RFC3339),
+			Tags:           n.Tags,
+			TeamID:         t.id,
+			TeamName:       t.name,
+			Endpoint:       n.Endpoint,
+			IV:             n.IV,
+			ExpiresAt:      client.FirestoreTimestamp{Seconds: n.ReceivedAt.Add(notificationTTL).Unix()},
+		}
+	}
+
+	var received *client.NotificationDetails
+	var members []client.NotificationDetails
+	if isTeam {
+		for i := 1; i <= t.members; i++ {
+			members = append(members, details(fmt.Sprintf("member_%d", i)))
+		}
+	}
+
+	if summary != nil {
+		if !isTeam {
+			d := details("user_1")
+			received = &d
+		}
+		return client.NotifAIResponse{
+			Status:               "success",
+			Message:              "AI notification generated and sent",
+			ReceivedNotification: received,
+			TeamID:               t.id,
+			MemberCount:          len(members),
+			Notifications:        members,
+			Summary:              summary,
+		}
+	}
+
+	if !isTeam {
+		d := details("user_1")
+		received = &d
+	}
+	return client.SendResponse{
+		Status:               "success",
+		Message:              "Notification sent successfully",
+		ReceivedNotification: received,
+		TeamID:               t.id,
+		MemberCount:          len(members),
+		Notifications:        members,
+	}
+}
+
+// summaryTitle derives a short title from NotifAI input text
+func summaryTitle(text string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(title); len(runes) > 50 {
+		title = string(runes[:50])
+	}
+	return title
+}
+
+// errorTypeForStatus returns the API error type for a status code
+func errorTypeForStatus(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "authentication_error"
+	case status == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case status >= 500:
+		return "server_error"
+	default:
+		return "validation_error"
+	}
+}
+
+// writeError writes an error response with the API's nested error shape
+func writeError(w http.ResponseWriter, status int, errType, code, message, param string) {
+	writeJSON(w, status, client.ErrorResponse{
+		Status: "error",
+		Error: client.ErrorDetails{
+			Type:    errType,
+			Code:    code,
+			Message: message,
+			Param:   param,
+		},
+	})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// ceilSeconds rounds a duration up to whole seconds, never below zero
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// Server is a running fake API on a local httptest server
+// The API base URL is Server.URL; pass it to client.WithBaseURL.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts a fake API server; call Close when done
+func NewServer() *Server {
+	h := NewHandler()
+	return &Server{
+		Server:  httptest.NewServer(h),
+		Handler: h,
+	}
+}
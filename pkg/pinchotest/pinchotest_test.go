@@ -0,0 +1,250 @@
+package pinchotest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/client"
+	"github.com/Pincho-App/pincho-cli/pkg/errors"
+)
+
+// newTestClient builds a client for srv with millisecond retry delays
+func newTestClient(srv *Server, token string) *client.Client {
+	return client.NewWithOptions(
+		client.WithBaseURL(srv.URL),
+		client.WithToken(token),
+		client.WithRetryPolicy(&client.DefaultRetryPolicy{
+			MaxRetries:       client.DefaultMaxRetries,
+			InitialBackoff:   time.Millisecond,
+			RateLimitBackoff: time.Millisecond,
+			MaxBackoff:       5 * time.Millisecond,
+		}),
+	)
+}
+
+func TestServer_SendPersonalToken(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	var hooked []Notification
+	srv.OnNotification = func(n Notification) { hooked = append(hooked, n) }
+
+	c := newTestClient(srv, "personal-token")
+	result, err := c.Send(context.Background(), &client.SendOptions{
+		Title:   "Deploy",
+		Message: "v1.2.3 deployed",
+		Type:    "deploy",
+		Tags:    []string{"production"},
+	})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	resp := result.Response
+	if resp.Status != "success" || resp.ReceivedNotification == nil {
+		t.Fatalf("expected personal success response, got %+v", resp)
+	}
+	if resp.ReceivedNotification.Title != "Deploy" || resp.ReceivedNotification.Body != "v1.2.3 deployed" {
+		t.Errorf("unexpected notification details: %+v", resp.ReceivedNotification)
+	}
+
+	got := srv.Notifications()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 recorded notification, got %d", len(got))
+	}
+	n := got[0]
+	if n.Endpoint != client.EndpointSend || n.Token != "personal-token" || n.Type != "deploy" || len(n.Tags) != 1 {
+		t.Errorf("unexpected recorded notification: %+v", n)
+	}
+	if n.IdempotencyKey != result.IdempotencyKey {
+		t.Errorf("expected recorded key %q, got %q", result.IdempotencyKey, n.IdempotencyKey)
+	}
+	if len(hooked) != 1 {
+		t.Errorf("expected OnNotification to be called once, got %d", len(hooked))
+	}
+}
+
+func TestServer_TeamToken(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddTeamToken("team-token", "team_1", "Ops", 3)
+
+	result, err := newTestClient(srv, "team-token").Send(context.Background(), &client.SendOptions{Title: "Alert"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	resp := result.Response
+	if resp.TeamID != "team_1" || resp.MemberCount != 3 || len(resp.Notifications) != 3 {
+		t.Errorf("expected team response for 3 members, got %+v", resp)
+	}
+	if resp.ReceivedNotification != nil {
+		t.Error("expected no receivedNotification for a team token")
+	}
+
+	// Once tokens are registered, unknown tokens are rejected
+	_, err = newTestClient(srv, "unknown").Send(context.Background(), &client.SendOptions{Title: "Alert"})
+	if _, ok := err.(*errors.AuthenticationError); !ok {
+		t.Errorf("expected *AuthenticationError, got %T: %v", err, err)
+	}
+}
+
+func TestServer_NotifAI(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	result, err := newTestClient(srv, "token").NotifAI(context.Background(), &client.NotifAIOptions{Text: "deployment finished\nall checks green"})
+	if err != nil {
+		t.Fatalf("NotifAI failed: %v", err)
+	}
+	if result.Response.Summary == nil || result.Response.Summary.Title != "deployment finished" {
+		t.Errorf("unexpected summary: %+v", result.Response.Summary)
+	}
+
+	got := srv.Notifications()
+	if len(got) != 1 || got[0].Endpoint != client.EndpointNotifAI || got[0].Text == "" {
+		t.Errorf("unexpected recorded notifications: %+v", got)
+	}
+}
+
+func TestServer_NotifAITeamToken(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddTeamToken("team-token", "team_1", "Ops", 2)
+
+	result, err := newTestClient(srv, "team-token").NotifAI(context.Background(), &client.NotifAIOptions{Text: "backup failed\ndisk full"})
+	if err != nil {
+		t.Fatalf("NotifAI failed: %v", err)
+	}
+
+	resp := result.Response
+	if resp.MemberCount != 2 || len(resp.Notifications) != 2 {
+		t.Fatalf("expected team response for 2 members, got %+v", resp)
+	}
+	for _, member := range resp.Notifications {
+		if member.Title != "backup failed" || member.Body != "backup failed\ndisk full" {
+			t.Errorf("expected %s to receive the summary, got title %q and body %q", member.UserID, member.Title, member.Body)
+		}
+	}
+}
+
+func TestServer_ValidationError(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/send", http.NoBody)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty body, got %d", resp.StatusCode)
+	}
+	if len(srv.Notifications()) != 0 {
+		t.Error("expected invalid request not to be recorded")
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.SetRateLimit(client.EndpointSend, 2, time.Hour)
+
+	c := newTestClient(srv, "token")
+	c.SetRetryPolicy(&client.DefaultRetryPolicy{MaxRetries: 0})
+
+	result, err := c.Send(context.Background(), &client.SendOptions{Title: "1"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if rl := result.RateLimit; rl == nil || rl.Limit != 2 || rl.Remaining != 1 || rl.Reset.IsZero() {
+		t.Errorf("unexpected rate limit info: %+v", result.RateLimit)
+	}
+
+	if _, err := c.Send(context.Background(), &client.SendOptions{Title: "2"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	_, err = c.Send(context.Background(), &client.SendOptions{Title: "3"})
+	rlErr, ok := err.(*errors.RateLimitError)
+	if !ok {
+		t.Fatalf("expected *RateLimitError, got %T: %v", err, err)
+	}
+	if rlErr.RetryAfter < 3500 {
+		t.Errorf("expected Retry-After close to the window, got %d", rlErr.RetryAfter)
+	}
+
+	// Other endpoints are not limited
+	if _, err := c.NotifAI(context.Background(), &client.NotifAIOptions{Text: "still works"}); err != nil {
+		t.Errorf("expected NotifAI to be unaffected, got: %v", err)
+	}
+}
+
+func TestServer_InjectFaults(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.InjectFaults(
+		Fault{Status: http.StatusServiceUnavailable},
+		Fault{Reset: true},
+		Fault{Status: http.StatusTooManyRequests, RetryAfter: time.Millisecond},
+		Fault{Delay: 20 * time.Millisecond},
+	)
+
+	start := time.Now()
+	result, err := newTestClient(srv, "token").Send(context.Background(), &client.SendOptions{Title: "Eventually"})
+	if err != nil {
+		t.Fatalf("expected retries to get through the faults, got: %v", err)
+	}
+	if result.Response.Status != "success" {
+		t.Errorf("unexpected response: %+v", result.Response)
+	}
+	if srv.Requests() != 4 {
+		t.Errorf("expected 4 requests, got %d", srv.Requests())
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Error("expected the delayed response to be slow")
+	}
+	if len(srv.Notifications()) != 1 {
+		t.Errorf("expected exactly 1 recorded notification, got %d", len(srv.Notifications()))
+	}
+}
+
+func TestServer_ServerErrorIsTyped(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.InjectFaults(Fault{Status: http.StatusInternalServerError})
+
+	c := newTestClient(srv, "token")
+	c.SetRetryPolicy(&client.DefaultRetryPolicy{MaxRetries: 0})
+
+	_, err := c.Send(context.Background(), &client.SendOptions{Title: "Test"})
+	if serverErr, ok := err.(*errors.ServerError); !ok || serverErr.StatusCode() != 500 {
+		t.Errorf("expected *ServerError with status 500, got %T: %v", err, err)
+	}
+}
+
+func TestServer_IdempotencyKeyReplays(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	c := newTestClient(srv, "token")
+	for i := 0; i < 2; i++ {
+		if _, err := c.Send(context.Background(), &client.SendOptions{Title: "Once", IdempotencyKey: "build-42"}); err != nil {
+			t.Fatalf("send %d failed: %v", i, err)
+		}
+	}
+
+	if got := len(srv.Notifications()); got != 1 {
+		t.Errorf("expected a repeated key to be recorded once, got %d", got)
+	}
+
+	srv.Clear()
+	if srv.Requests() != 0 || len(srv.Notifications()) != 0 {
+		t.Error("expected Clear to reset recorded state")
+	}
+}
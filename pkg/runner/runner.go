@@ -0,0 +1,145 @@
+// Package runner runs a command on behalf of the CLI and reports how it went.
+//
+// The command's stdout and stderr are passed through unchanged while the
+// last lines of its combined output are kept, so a notification can show why
+// a job failed. The result records the exit code, wall time, CPU time and,
+// where the platform reports it, peak memory use:
+//
+//	result := runner.Run(ctx, []string{"./backup.sh", "--full"}, runner.Options{TailLines: 10})
+//	if !result.Success() {
+//		fmt.Printf("exit code %d after %s\n%s", result.ExitCode, result.Duration,
+//			strings.Join(result.Output, "\n"))
+//	}
+//
+// Interrupt and terminate signals received while the command runs are
+// forwarded to it, so Ctrl-C stops the command and the result is still
+// reported. A Ctrl-C typed on the terminal already reaches the command and
+// is not forwarded a second time.
+package runner
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Exit codes used by shells for commands that could not be run
+const (
+	// ExitNotFound is the exit code of a command that does not exist
+	ExitNotFound = 127
+
+	// ExitCannotRun is the exit code of a command that exists but could not be started
+	ExitCannotRun = 126
+)
+
+// Options configures Run
+type Options struct {
+	Stdin     io.Reader // Defaults to os.Stdin
+	Stdout    io.Writer // Defaults to os.Stdout
+	Stderr    io.Writer // Defaults to os.Stderr
+	TailLines int       // Number of last output lines to keep (0 keeps none)
+}
+
+// Result describes a finished command
+type Result struct {
+	Command    []string
+	StartedAt  time.Time
+	Duration   time.Duration // Wall time
+	ExitCode   int           // 128+N if killed by signal N, ExitNotFound or ExitCannotRun if not started
+	Signal     string        // Name of the signal that killed the command, if any
+	StartErr   error         // Why the command could not be started, if it was not
+	UserTime   time.Duration // CPU time spent in user mode
+	SystemTime time.Duration // CPU time spent in the kernel
+	PeakRSS    uint64        // Peak resident set size in bytes (0 if not reported by the platform)
+	Output     []string      // Last Options.TailLines lines of combined stdout and stderr
+}
+
+// Success reports whether the command ran and exited with code 0
+func (r *Result) Success() bool {
+	return r.ExitCode == 0
+}
+
+// Run runs command (program and arguments) until it exits or ctx is done
+func Run(ctx context.Context, command []string, opts Options) *Result {
+	result := &Result{Command: command, StartedAt: time.Now()}
+	if len(command) == 0 {
+		result.ExitCode, result.StartErr = ExitNotFound, stderrors.New("no command given")
+		return result
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = opts.Stdin, opts.Stdout, opts.Stderr
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	// Keep the output tail; without one, the command writes to the
+	// terminal directly and can still detect it
+	var tail *Tail
+	if opts.TailLines > 0 {
+		tail = NewTail(opts.TailLines)
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, tail)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, tail)
+	}
+
+	if err := cmd.Start(); err != nil {
+		result.Duration = time.Since(result.StartedAt)
+		result.StartErr = err
+		result.ExitCode = ExitCannotRun
+		if stderrors.Is(err, exec.ErrNotFound) || stderrors.Is(err, fs.ErrNotExist) {
+			result.ExitCode = ExitNotFound
+		}
+		return result
+	}
+
+	// Forward signals sent to this process only, such as from a supervisor;
+	// the command gets Ctrl-C from the terminal itself, and a second
+	// interrupt would make many programs abort their cleanup
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if !reachedCommand(sig) {
+					_ = cmd.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	_ = cmd.Wait()
+	signal.Stop(signals)
+	close(done)
+
+	result.Duration = time.Since(result.StartedAt)
+	if tail != nil {
+		result.Output = tail.Lines()
+	}
+
+	state := cmd.ProcessState
+	result.ExitCode = state.ExitCode()
+	result.UserTime = state.UserTime()
+	result.SystemTime = state.SystemTime()
+	result.PeakRSS = peakRSS(state)
+	if name, number, ok := killSignal(state); ok {
+		result.Signal = name
+		result.ExitCode = 128 + number
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	var stdout, stderr bytes.Buffer
+	result := Run(context.Background(), []string{"sh", "-c", "echo one; echo two >&2; echo three; exit 3"}, Options{
+		Stdin:     strings.NewReader(""),
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+		TailLines: 2,
+	})
+
+	if result.ExitCode != 3 || result.Success() || result.StartErr != nil {
+		t.Errorf("Run() exit code = %d, start error = %v; want 3", result.ExitCode, result.StartErr)
+	}
+	if stdout.String() != "one\nthree\n" || stderr.String() != "two\n" {
+		t.Errorf("output not passed through: stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+	// stdout and stderr are read concurrently, so only their own order is kept
+	if len(result.Output) != 2 || !strings.Contains(strings.Join(result.Output, "|"), "three") {
+		t.Errorf("Output = %q, want the last 2 lines including three", result.Output)
+	}
+	if result.Duration <= 0 || result.StartedAt.IsZero() {
+		t.Errorf("Duration = %v, StartedAt = %v; want them set", result.Duration, result.StartedAt)
+	}
+	if runtime.GOOS == "linux" && result.PeakRSS == 0 {
+		t.Error("PeakRSS = 0, want the peak memory use on linux")
+	}
+}
+
+func TestRun_Success(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	var stdout bytes.Buffer
+	result := Run(context.Background(), []string{"true"}, Options{Stdout: &stdout})
+	if !result.Success() || result.Output != nil {
+		t.Errorf("Run(true) = exit code %d, output %q; want success without output", result.ExitCode, result.Output)
+	}
+}
+
+func TestRun_Signal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	result := Run(context.Background(), []string{"sh", "-c", "kill -TERM $$"}, Options{})
+	if result.Signal == "" || result.ExitCode != 128+15 {
+		t.Errorf("Run() = signal %q, exit code %d; want terminated, 143", result.Signal, result.ExitCode)
+	}
+}
+
+func TestRun_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if result := Run(ctx, []string{"sleep", "5"}, Options{}); result.Success() {
+		t.Error("expected a killed command to fail")
+	}
+}
+
+func TestRun_NotStarted(t *testing.T) {
+	result := Run(context.Background(), []string{"pincho-no-such-command"}, Options{})
+	if result.ExitCode != ExitNotFound || result.StartErr == nil {
+		t.Errorf("Run() = exit code %d, start error %v; want %d", result.ExitCode, result.StartErr, ExitNotFound)
+	}
+
+	if result := Run(context.Background(), nil, Options{}); result.StartErr == nil {
+		t.Error("expected an error for an empty command")
+	}
+}
+
+func TestTail(t *testing.T) {
+	tail := NewTail(3)
+	for _, chunk := range []string{"one\ntw", "o\r\nthree\n", "four\nfi", "ve"} {
+		if _, err := tail.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	want := []string{"three", "four", "five"}
+	if got := tail.Lines(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestTail_LongLine(t *testing.T) {
+	tail := NewTail(2)
+	tail.Write([]byte(strings.Repeat("x", MaxLineLength+100)))
+	tail.Write([]byte(strings.Repeat("y", 10) + "\nlast\n"))
+
+	lines := tail.Lines()
+	if len(lines) != 2 || len(lines[0]) != MaxLineLength || lines[1] != "last" {
+		t.Errorf("Lines() = %d lines, first %d bytes; want a cut line and last", len(lines), len(lines[0]))
+	}
+}
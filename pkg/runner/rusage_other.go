@@ -0,0 +1,15 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package runner
+
+import "os"
+
+// peakRSS is not reported on this platform
+func peakRSS(state *os.ProcessState) uint64 {
+	return 0
+}
+
+// killSignal is not reported on this platform: processes exit with a code
+func killSignal(state *os.ProcessState) (string, int, bool) {
+	return "", 0, false
+}
@@ -0,0 +1,32 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package runner
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// peakRSS returns the peak resident set size of the finished process in bytes
+func peakRSS(state *os.ProcessState) uint64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage.Maxrss <= 0 {
+		return 0
+	}
+	// Darwin reports bytes, the other systems kilobytes
+	if runtime.GOOS == "darwin" {
+		return uint64(usage.Maxrss)
+	}
+	return uint64(usage.Maxrss) * 1024
+}
+
+// killSignal returns the signal that killed the process, if any
+func killSignal(state *os.ProcessState) (string, int, bool) {
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return "", 0, false
+	}
+	sig := status.Signal()
+	return sig.String(), int(sig), true
+}
@@ -0,0 +1,11 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package runner
+
+import "os"
+
+// reachedCommand reports whether sig was also delivered to the command
+// A console interrupt (Ctrl-C) reaches every process attached to the console.
+func reachedCommand(sig os.Signal) bool {
+	return sig == os.Interrupt
+}
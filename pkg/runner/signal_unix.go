@@ -0,0 +1,27 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package runner
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reachedCommand reports whether sig was also delivered to the command
+// The terminal sends an interrupt (Ctrl-C) to its whole foreground process
+// group, which the command shares while this process is in the foreground.
+func reachedCommand(sig os.Signal) bool {
+	if sig != os.Interrupt {
+		return false
+	}
+
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false // No controlling terminal, the signal was sent to this process
+	}
+	defer tty.Close()
+
+	pgrp, err := unix.IoctlGetInt(int(tty.Fd()), unix.TIOCGPGRP)
+	return err == nil && pgrp == unix.Getpgrp()
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"bytes"
+	"sync"
+)
+
+// MaxLineLength is the length at which a kept output line is cut
+// Output without newlines, such as a progress bar, cannot grow without bound.
+const MaxLineLength = 4096
+
+// Tail is an io.Writer keeping the last lines written to it
+// It is safe for concurrent use, so stdout and stderr can share one.
+type Tail struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+// NewTail returns a Tail keeping the last n lines
+func NewTail(n int) *Tail {
+	return &Tail{max: n}
+}
+
+// Write implements io.Writer
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data := p
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			t.appendPartial(data)
+			break
+		}
+		t.appendPartial(data[:i])
+		t.push(string(bytes.TrimSuffix(t.partial, []byte("\r"))))
+		t.partial = t.partial[:0]
+		data = data[i+1:]
+	}
+	return len(p), nil
+}
+
+// Lines returns the kept lines, oldest first, including an unterminated last line
+func (t *Tail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string{}, t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, string(t.partial))
+		if len(lines) > t.max {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// appendPartial adds data to the current line, up to MaxLineLength
+func (t *Tail) appendPartial(data []byte) {
+	if room := MaxLineLength - len(t.partial); room < len(data) {
+		data = data[:max(room, 0)]
+	}
+	t.partial = append(t.partial, data...)
+}
+
+// push adds a complete line, dropping the oldest beyond the limit
+func (t *Tail) push(line string) {
+	t.lines = append(t.lines, line)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+}
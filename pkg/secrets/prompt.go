@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// IsTerminal reports whether f is an interactive terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// PromptPassword prints prompt to stderr and reads a line from the terminal f
+// without echoing it. An empty answer is an error.
+func PromptPassword(f *os.File, prompt string) (string, error) {
+	if !IsTerminal(f) {
+		return "", fmt.Errorf("cannot prompt for a password: %s is not a terminal", f.Name())
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := term.ReadPassword(int(f.Fd()))
+	// The newline typed by the user was not echoed either
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	password := strings.TrimRight(string(line), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("no password entered")
+	}
+	return password, nil
+}
@@ -0,0 +1,100 @@
+// Package secrets reads credentials from external sources.
+//
+// Instead of storing a token or password in plaintext in the config file or
+// passing it on the command line, it can be read from a file, such as a
+// Docker or Kubernetes secret mounted at /run/secrets/pincho_token, or from
+// the output of a helper command, such as a password manager:
+//
+//	token, err := secrets.ReadFile("/run/secrets/pincho_token")
+//	token, err := secrets.RunCommand(ctx, "pass show pincho")
+//
+// Commands run through the system shell (sh -c, or cmd /C on Windows) with
+// the terminal's stdin and stderr, so helpers can prompt for a passphrase.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultCommandTimeout is how long a helper command may run
+// Generous, as helpers may wait for the user to unlock a key.
+const DefaultCommandTimeout = 2 * time.Minute
+
+// ReadFile returns the contents of the file at path, without one trailing newline
+// Other whitespace is kept, as it may be part of a password or webhook secret.
+// A leading ~/ is expanded to the home directory. An empty file is an error.
+func ReadFile(path string) (string, error) {
+	expanded, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(expanded)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	secret := strings.TrimSuffix(string(data), "\n")
+	secret = strings.TrimSuffix(secret, "\r")
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// RunCommand runs command through the shell and returns the first line of its output
+// The command fails if it exits with an error, prints nothing, or runs
+// longer than DefaultCommandTimeout (or the deadline of ctx, if earlier).
+func RunCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCommandTimeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("secret command %q did not complete: %w", command, ctx.Err())
+		}
+		return "", fmt.Errorf("secret command %q failed: %w", command, err)
+	}
+
+	// Password managers such as pass print the secret on the first line
+	line, _, _ := strings.Cut(stdout.String(), "\n")
+	secret := strings.TrimSpace(line)
+	if secret == "" {
+		return "", fmt.Errorf("secret command %q printed nothing", command)
+	}
+	return secret, nil
+}
+
+// shellCommand returns a command running command through the system shell
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// expandHome replaces a leading ~/ in path with the home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,129 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pincho_token")
+	if err := os.WriteFile(path, []byte("wpt_secret123\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	secret, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if secret != "wpt_secret123" {
+		t.Errorf("ReadFile() = %q, want %q", secret, "wpt_secret123")
+	}
+
+	// Only the final line ending is stripped, spaces are part of the secret
+	for content, want := range map[string]string{
+		"  pass phrase \r\n": "  pass phrase ",
+		"secret\n\n":         "secret\n",
+		"no newline":         "no newline",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write secret: %v", err)
+		}
+		if secret, err := ReadFile(path); err != nil || secret != want {
+			t.Errorf("ReadFile(%q) = %q, %v; want %q", content, secret, err, want)
+		}
+	}
+}
+
+func TestReadFile_HomeDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	if err := os.WriteFile(filepath.Join(home, "token"), []byte("from-home"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	secret, err := ReadFile("~/token")
+	if err != nil || secret != "from-home" {
+		t.Errorf("ReadFile(~/token) = %q, %v; want from-home", secret, err)
+	}
+}
+
+func TestReadFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	for _, path := range []string{empty, filepath.Join(dir, "missing")} {
+		if _, err := ReadFile(path); err == nil {
+			t.Errorf("ReadFile(%q) expected error", path)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	secret, err := RunCommand(context.Background(), "printf 'wpt_secret123\\nurl: example.com\\n'")
+	if err != nil {
+		t.Fatalf("RunCommand failed: %v", err)
+	}
+	if secret != "wpt_secret123" {
+		t.Errorf("RunCommand() = %q, want first line %q", secret, "wpt_secret123")
+	}
+}
+
+func TestRunCommand_Errors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"exit 3", "failed"},
+		{"true", "printed nothing"},
+	}
+	for _, tt := range tests {
+		_, err := RunCommand(context.Background(), tt.command)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("RunCommand(%q) error = %v, want %q", tt.command, err, tt.want)
+		}
+	}
+}
+
+func TestRunCommand_Timeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunCommand(ctx, "sleep 5"); err == nil {
+		t.Error("expected error for cancelled context")
+	}
+}
+
+func TestPromptPassword_NotTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Error("IsTerminal() = true for a regular file")
+	}
+	if _, err := PromptPassword(f, "Password: "); err == nil || !strings.Contains(err.Error(), "not a terminal") {
+		t.Errorf("PromptPassword() error = %v, want not a terminal", err)
+	}
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewEntryID returns an ID for a file in a directory of entries that sorts in creation order
+// The random suffix keeps IDs unique across concurrent processes.
+func NewEntryID(at time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate entry ID: %w", err)
+	}
+	return fmt.Sprintf("%020d-%s", at.UnixNano(), hex.EncodeToString(b)), nil
+}
+
+// EntryFiles returns the names of the files with extension ext in dir, oldest first
+// Directories and hidden files, such as temporary files of in-progress writes
+// and lock files, are skipped. A missing directory has no entries.
+func EntryFiles(dir, ext string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, d := range dirEntries {
+		name := d.Name()
+		if d.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
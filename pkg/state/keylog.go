@@ -0,0 +1,81 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyLog records when keys were last seen, persisted to a JSON file.
+// It backs time-windowed duplicate checks such as idempotency keys.
+type KeyLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// keyEntry is a recorded key with the time it may be forgotten
+type keyEntry struct {
+	At      time.Time `json:"at"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewKeyLog creates a key log persisting to the file at path
+func NewKeyLog(path string) *KeyLog {
+	return &KeyLog{path: path}
+}
+
+// Lookup reports when key was last recorded, if that was within window of now
+func (l *KeyLog) Lookup(key string, window time.Duration) (time.Time, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys, err := l.read()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	entry, ok := keys[key]
+	if !ok || time.Since(entry.At) > window {
+		return time.Time{}, false, nil
+	}
+	return entry.At, true, nil
+}
+
+// Record stores key as seen at the given time, keeping it for at least retention
+// Each entry expires on its own, so a caller with a short retention never
+// prunes keys recorded by a caller with a longer one. The file is locked
+// while it is updated, so concurrent processes never lose each other's keys.
+func (l *KeyLog) Record(key string, at time.Time, retention time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return WithLock(l.path+".lock", func() error {
+		keys, err := l.read()
+		if err != nil {
+			// Start over rather than failing forever on a corrupt file
+			keys = make(map[string]keyEntry)
+		}
+
+		now := time.Now()
+		for k, entry := range keys {
+			if entry.Expires.Before(now) {
+				delete(keys, k)
+			}
+		}
+
+		entry := keyEntry{At: at, Expires: at.Add(retention)}
+		if previous, ok := keys[key]; ok && previous.Expires.After(entry.Expires) {
+			entry.Expires = previous.Expires
+		}
+		keys[key] = entry
+		return WriteJSON(l.path, keys)
+	})
+}
+
+// read loads all recorded keys
+func (l *KeyLog) read() (map[string]keyEntry, error) {
+	keys := make(map[string]keyEntry)
+	if _, err := ReadJSON(l.path, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
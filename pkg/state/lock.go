@@ -0,0 +1,72 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock
+var ErrLocked = errors.New("locked by another process")
+
+// FileLock is an exclusive lock on a lock file, held until Unlock
+// Locks are released by the operating system when the process exits, so a
+// crashed process never leaves a stale lock behind. Separate FileLocks on
+// the same path also exclude each other within one process.
+type FileLock struct {
+	file *os.File
+}
+
+// Lock locks the file at path exclusively, waiting for other holders to release it
+// The file and its directory are created if needed.
+func Lock(path string) (*FileLock, error) {
+	return acquire(path, true)
+}
+
+// TryLock locks the file at path exclusively without waiting
+// Returns ErrLocked at once if another holder has the lock.
+func TryLock(path string) (*FileLock, error) {
+	return acquire(path, false)
+}
+
+// WithLock runs fn while holding the lock on the file at path
+// Use it around read-modify-write cycles of files shared between processes.
+func WithLock(path string, fn func() error) error {
+	lock, err := Lock(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return fn()
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	unlockErr := unlockFile(l.file)
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	return unlockErr
+}
+
+// acquire opens the lock file at path and locks it exclusively
+func acquire(path string, wait bool) (*FileLock, error) {
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(f, wait); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &FileLock{file: f}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package state
+
+import "os"
+
+// lockFile does nothing: file locks are not supported on this platform, so
+// concurrent processes are not serialized
+func lockFile(f *os.File, wait bool) error {
+	return nil
+}
+
+// unlockFile does nothing: file locks are not supported on this platform
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package state provides persistence helpers for local CLI state.
+//
+// State files live next to the configuration in the config directory
+// (~/.pincho) and hold data that must survive between CLI invocations,
+// such as the last known rate limit quota for each token.
+//
+// Security:
+//   - Directories are created with 0700 permissions (owner-only access)
+//   - Files are written with 0600 permissions (owner read/write only)
+//
+// Writes are atomic: data is written to a temporary file in the same
+// directory and renamed into place, so concurrent CLI processes never
+// observe a partially written file. Read-modify-write cycles on a shared
+// file are serialized with an exclusive file lock (Lock, WithLock), so
+// concurrent processes do not lose each other's updates.
+//
+// Example usage:
+//
+//	path, err := state.Path("quota.json")
+//
+//	var quotas map[string]Quota
+//	found, err := state.ReadJSON(path, &quotas)
+//
+//	err = state.WriteJSON(path, quotas)
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+)
+
+// Path returns the path of a state file or directory inside the config directory
+func Path(elem ...string) (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{configDir}, elem...)...), nil
+}
+
+// EnsureDir creates dir (and its parents) with owner-only permissions
+func EnsureDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	return nil
+}
+
+// ReadJSON decodes the JSON file at path into v
+// Returns false without error if the file does not exist
+func ReadJSON(path string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+
+	return true, nil
+}
+
+// WriteJSON atomically replaces the file at path with the JSON encoding of v
+// The parent directory is created with 0700 and the file with 0600 permissions
+func WriteJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	return WriteFile(path, data)
+}
+
+// WriteFile atomically replaces the file at path with data
+// The parent directory is created with 0700 and the file with 0600 permissions
+func WriteFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := EnsureDir(dir); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	// Clean up the temporary file if anything below fails
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if err := tmp.Chmod(0600); err != nil {
+		return fmt.Errorf("failed to set state file permissions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	success = true
+	return nil
+}
@@ -0,0 +1,268 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/config"
+)
+
+func TestPath(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	path, err := Path("jobs", "backup.json")
+	if err != nil {
+		t.Fatalf("Path() failed: %v", err)
+	}
+
+	expected := filepath.Join(tmpHome, config.ConfigDirName, "jobs", "backup.json")
+	if path != expected {
+		t.Errorf("Path() = %q, want %q", path, expected)
+	}
+}
+
+func TestReadJSON_Missing(t *testing.T) {
+	var v map[string]int
+	found, err := ReadJSON(filepath.Join(t.TempDir(), "missing.json"), &v)
+	if err != nil {
+		t.Fatalf("ReadJSON() failed: %v", err)
+	}
+	if found {
+		t.Error("expected found to be false for a missing file")
+	}
+}
+
+func TestWriteAndReadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	if err := WriteJSON(path, map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Fatalf("WriteJSON() failed: %v", err)
+	}
+
+	var v map[string]int
+	found, err := ReadJSON(path, &v)
+	if err != nil {
+		t.Fatalf("ReadJSON() failed: %v", err)
+	}
+	if !found {
+		t.Fatal("expected found to be true")
+	}
+	if v["a"] != 1 || v["b"] != 2 {
+		t.Errorf("ReadJSON() = %v, want map[a:1 b:2]", v)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat() failed: %v", err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("state file permissions = %o, want 600", mode)
+		}
+
+		dirInfo, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			t.Fatalf("Stat() failed: %v", err)
+		}
+		if mode := dirInfo.Mode().Perm(); mode != 0700 {
+			t.Errorf("state directory permissions = %o, want 700", mode)
+		}
+	}
+
+	// Overwriting leaves no temporary files behind
+	if err := WriteJSON(path, map[string]int{"a": 3}); err != nil {
+		t.Fatalf("WriteJSON() overwrite failed: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir() failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the state file in directory, got %d entries", len(entries))
+	}
+}
+
+func TestReadJSON_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var v map[string]int
+	if _, err := ReadJSON(path, &v); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestKeyLog(t *testing.T) {
+	log := NewKeyLog(filepath.Join(t.TempDir(), "keys.json"))
+
+	if _, found, err := log.Lookup("deploy-1", time.Hour); err != nil || found {
+		t.Fatalf("Lookup() on empty log = %v, %v; want false, nil", found, err)
+	}
+
+	sentAt := time.Now().Add(-10 * time.Minute)
+	if err := log.Record("deploy-1", sentAt, 24*time.Hour); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	at, found, err := log.Lookup("deploy-1", time.Hour)
+	if err != nil || !found {
+		t.Fatalf("Lookup() = %v, %v; want true, nil", found, err)
+	}
+	if !at.Equal(sentAt) {
+		t.Errorf("Lookup() time = %v, want %v", at, sentAt)
+	}
+
+	// Outside the window the key is no longer reported
+	if _, found, _ := log.Lookup("deploy-1", 5*time.Minute); found {
+		t.Error("expected key older than the window not to be found")
+	}
+
+	// A shorter retention does not prune entries recorded with a longer one
+	if err := log.Record("deploy-2", time.Now(), time.Minute); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if _, found, _ := log.Lookup("deploy-1", 24*time.Hour); !found {
+		t.Error("expected key within its own retention to be kept")
+	}
+
+	// Expired entries are pruned on the next write
+	if err := log.Record("deploy-3", time.Now().Add(-2*time.Hour), time.Hour); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if err := log.Record("deploy-4", time.Now(), time.Minute); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if _, found, _ := log.Lookup("deploy-3", 24*time.Hour); found {
+		t.Error("expected key past its retention to be pruned")
+	}
+}
+
+func TestKeyLog_ConcurrentRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	// Separate key logs stand in for separate processes or fan-out targets
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := NewKeyLog(path).Record(fmt.Sprintf("key-%d", i), time.Now(), time.Hour); err != nil {
+				t.Errorf("Record() failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	log := NewKeyLog(path)
+	for i := 0; i < 20; i++ {
+		if _, found, _ := log.Lookup(fmt.Sprintf("key-%d", i), time.Hour); !found {
+			t.Errorf("key-%d was lost", i)
+		}
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "flush.lock")
+
+	lock, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() failed: %v", err)
+	}
+	if _, err := TryLock(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked while locked, got %v", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock() failed: %v", err)
+	}
+
+	lock, err = TryLock(path)
+	if err != nil {
+		t.Fatalf("TryLock() after Unlock failed: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestWithLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "counter.json")
+
+	// Concurrent read-modify-write cycles lose no update
+	const workers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := WithLock(path+".lock", func() error {
+				var n int
+				if _, err := ReadJSON(path, &n); err != nil {
+					return err
+				}
+				return WriteJSON(path, n+1)
+			})
+			if err != nil {
+				t.Errorf("WithLock() failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var n int
+	if _, err := ReadJSON(path, &n); err != nil {
+		t.Fatal(err)
+	}
+	if n != workers {
+		t.Errorf("counter = %d, want %d", n, workers)
+	}
+}
+
+func TestNewEntryID(t *testing.T) {
+	now := time.Now()
+	first, err := NewEntryID(now)
+	if err != nil {
+		t.Fatalf("NewEntryID() failed: %v", err)
+	}
+	second, _ := NewEntryID(now)
+	later, _ := NewEntryID(now.Add(time.Nanosecond))
+
+	if first == second {
+		t.Errorf("expected IDs created at the same time to differ, got %q twice", first)
+	}
+	if first >= later || second >= later {
+		t.Errorf("expected %q and %q to sort before %q", first, second, later)
+	}
+}
+
+func TestEntryFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2.json", "1.json", ".1.json.tmp-123", ".flush.lock", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "3.json"), 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	names, err := EntryFiles(dir, ".json")
+	if err != nil {
+		t.Fatalf("EntryFiles() failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "1.json" || names[1] != "2.json" {
+		t.Errorf("EntryFiles() = %v, want [1.json 2.json]", names)
+	}
+
+	names, err = EntryFiles(filepath.Join(dir, "missing"), ".json")
+	if err != nil || len(names) != 0 {
+		t.Errorf("EntryFiles(missing) = %v, %v; want empty, nil", names, err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package templates renders named notification templates.
+//
+// Templates are defined in the templates section of the config file and fill
+// in the fields of a notification using Go text/template syntax:
+//
+//	templates:
+//	  deploy:
+//	    title: "Deployed {{.Vars.version}} to {{.Vars.env | upper}}"
+//	    message: "Deployed by {{env \"USER\"}} on {{hostname}} at {{now.Format \"15:04\"}}"
+//	    type: deploy
+//	    tags: ["{{.Vars.env}}", "release"]
+//	    action_url: "https://ci.example.com/builds/{{.Env.CI_BUILD_ID}}"
+//
+// Variables passed as name=value pairs are available as .Vars and environment
+// variables as .Env. Referencing a variable or environment variable that is
+// not set is an error; use the env function for optional environment
+// variables.
+//
+// Helper functions:
+//   - upper, lower: Change case
+//   - truncate N: Shorten to at most N characters, ending in "..."
+//   - default VALUE: Use VALUE if the piped value is empty
+//   - now: Current time (time.Time), e.g. {{now.Format "2006-01-02"}}
+//   - hostname: Name of this machine
+//   - env NAME: Environment variable, empty if not set
+//
+// Example usage:
+//
+//	rendered, err := templates.Render(tmpl, map[string]string{"version": "1.2.3"})
+package templates
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Template defines the fields of a notification as text templates
+type Template struct {
+	Title     string   `mapstructure:"title"`
+	Message   string   `mapstructure:"message"`
+	Type      string   `mapstructure:"type"`
+	Tags      []string `mapstructure:"tags"`
+	ImageURL  string   `mapstructure:"image_url"`
+	ActionURL string   `mapstructure:"action_url"`
+}
+
+// Rendered is a template with all fields rendered
+// Tags that render to an empty string are dropped.
+type Rendered struct {
+	Title     string
+	Message   string
+	Type      string
+	Tags      []string
+	ImageURL  string
+	ActionURL string
+}
+
+// Data is the value templates are executed with
+type Data struct {
+	Vars map[string]string // Variables passed as name=value
+	Env  map[string]string // Environment variables
+}
+
+// ParseVars parses name=value pairs into a map
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected name=value, got %q", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
+// Render renders every field of t with vars and the current environment
+func Render(t Template, vars map[string]string) (*Rendered, error) {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	data := Data{Vars: vars, Env: environ()}
+
+	var r Rendered
+	fields := []struct {
+		name string
+		src  string
+		dst  *string
+	}{
+		{"title", t.Title, &r.Title},
+		{"message", t.Message, &r.Message},
+		{"type", t.Type, &r.Type},
+		{"image_url", t.ImageURL, &r.ImageURL},
+		{"action_url", t.ActionURL, &r.ActionURL},
+	}
+	for _, f := range fields {
+		out, err := renderField(f.name, f.src, data)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = out
+	}
+
+	for i, tag := range t.Tags {
+		out, err := renderField(fmt.Sprintf("tags[%d]", i), tag, data)
+		if err != nil {
+			return nil, err
+		}
+		if out = strings.TrimSpace(out); out != "" {
+			r.Tags = append(r.Tags, out)
+		}
+	}
+
+	return &r, nil
+}
+
+// Names returns the sorted names of the given templates
+func Names(templates map[string]Template) []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Plural returns singular if n is 1, plural otherwise
+func Plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// renderField executes one template field
+func renderField(name, src string, data Data) (string, error) {
+	if src == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs()).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// funcs returns the helper functions available to templates
+func funcs() template.FuncMap {
+	return template.FuncMap{
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"truncate": truncate,
+		"default":  defaultValue,
+		"now":      time.Now,
+		"hostname": hostname,
+		"env":      os.Getenv,
+	}
+}
+
+// truncate shortens s to at most n characters, ending in "..." if shortened
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(runes[:n])
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// defaultValue returns def if value is empty
+func defaultValue(def, value string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// hostname returns the name of this machine, or an empty string if unknown
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// environ returns the environment variables as a map
+func environ() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
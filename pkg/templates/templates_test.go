@@ -0,0 +1,120 @@
+package templates
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	t.Setenv("PINCHO_TEST_USER", "alice")
+
+	tmpl := Template{
+		Title:     "Deployed {{.Vars.version}} to {{.Vars.env | upper}}",
+		Message:   "by {{.Env.PINCHO_TEST_USER}} on {{hostname}}",
+		Type:      "deploy",
+		Tags:      []string{"{{.Vars.env}}", "release", "{{env \"PINCHO_TEST_UNSET\"}}"},
+		ActionURL: "https://ci.example.com/{{.Vars.version}}",
+	}
+
+	r, err := Render(tmpl, map[string]string{"version": "1.2.3", "env": "prod"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	host, _ := os.Hostname()
+	if r.Title != "Deployed 1.2.3 to PROD" {
+		t.Errorf("Title = %q", r.Title)
+	}
+	if r.Message != "by alice on "+host {
+		t.Errorf("Message = %q", r.Message)
+	}
+	if r.Type != "deploy" || r.ActionURL != "https://ci.example.com/1.2.3" || r.ImageURL != "" {
+		t.Errorf("unexpected fields: %+v", r)
+	}
+	if strings.Join(r.Tags, ",") != "prod,release" {
+		t.Errorf("Tags = %v, want [prod release] (empty tags dropped)", r.Tags)
+	}
+}
+
+func TestRender_MissingVar(t *testing.T) {
+	_, err := Render(Template{Title: "Deployed {{.Vars.version}}"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "title") {
+		t.Errorf("expected error naming the title field, got %v", err)
+	}
+}
+
+func TestRender_InvalidTemplate(t *testing.T) {
+	if _, err := Render(Template{Message: "{{.Vars.x"}, nil); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestRender_Helpers(t *testing.T) {
+	tmpl := Template{
+		Title:   `{{.Vars.long | truncate 8}}`,
+		Message: `{{.Vars.empty | default "none"}} {{now.Year}} {{"MiXeD" | lower}}`,
+	}
+
+	r, err := Render(tmpl, map[string]string{"long": "a very long title", "empty": ""})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if r.Title != "a ver..." {
+		t.Errorf("Title = %q, want %q", r.Title, "a ver...")
+	}
+	want := "none " + time.Now().Format("2006") + " mixed"
+	if r.Message != want {
+		t.Errorf("Message = %q, want %q", r.Message, want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n    int
+		in   string
+		want string
+	}{
+		{10, "short", "short"},
+		{5, "exactly5", "ex..."},
+		{2, "abc", "ab"},
+		{4, "héllo wörld", "h..."},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.in); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	for n, want := range map[int]string{0: "events", 1: "event", 2: "events"} {
+		if got := Plural(n, "event", "events"); got != want {
+			t.Errorf("Plural(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"version=1.2.3", "query=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("ParseVars failed: %v", err)
+	}
+	if vars["version"] != "1.2.3" || vars["query"] != "a=b" || vars["empty"] != "" {
+		t.Errorf("unexpected vars: %v", vars)
+	}
+
+	for _, bad := range []string{"novalue", "=x"} {
+		if _, err := ParseVars([]string{bad}); err == nil {
+			t.Errorf("ParseVars(%q) expected error", bad)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := Names(map[string]Template{"b": {}, "a": {}})
+	if strings.Join(names, ",") != "a,b" {
+		t.Errorf("Names = %v", names)
+	}
+}
@@ -0,0 +1,207 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is how often a followed file is checked for new lines
+	DefaultPollInterval = time.Second
+
+	// MaxLineLength is the length at which longer lines are split
+	MaxLineLength = 64 * 1024
+)
+
+// FollowOptions configures Follow
+type FollowOptions struct {
+	FromStart    bool          // Read the lines already in the file instead of starting at its end
+	PollInterval time.Duration // How often to check for new lines (default DefaultPollInterval)
+}
+
+// Follow sends the lines appended to the file at path to lines until ctx is done
+//
+// Like tail -F, it keeps following path when the file is rotated (renamed or
+// removed and created again): the rest of the old file is read first, then
+// the new file from its beginning. A file truncated in place is read again
+// from its beginning. The file must exist when Follow starts.
+func Follow(ctx context.Context, path string, opts FollowOptions, lines chan<- string) error {
+	poll := opts.PollInterval
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+
+	f := &follower{path: path, lines: lines}
+	if err := f.open(!opts.FromStart); err != nil {
+		return err
+	}
+	defer f.close()
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if err := f.read(ctx); err != nil {
+			return err
+		}
+		if err := f.checkFile(ctx); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// follower is the state of Follow
+type follower struct {
+	path    string
+	lines   chan<- string
+	file    *os.File
+	info    os.FileInfo // Of the open file, to detect rotation
+	offset  int64
+	partial []byte // Start of a line whose end was not written yet
+}
+
+// open opens the file at path, at its end if atEnd is set
+func (f *follower) open(atEnd bool) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	var offset int64
+	if atEnd {
+		if offset, err = file.Seek(0, io.SeekEnd); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	f.file, f.info, f.offset, f.partial = file, info, offset, nil
+	return nil
+}
+
+// close closes the open file, if any
+func (f *follower) close() {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+}
+
+// read sends the complete lines written to the open file since the last read
+func (f *follower) read(ctx context.Context) error {
+	if f.file == nil {
+		return nil
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.file.Read(buf)
+		if n > 0 {
+			f.offset += int64(n)
+			if err := f.split(ctx, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if stderrors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", f.path, err)
+		}
+	}
+}
+
+// split sends the complete lines in data, keeping the incomplete last one
+func (f *follower) split(ctx context.Context, data []byte) error {
+	f.partial = append(f.partial, data...)
+	for {
+		i := bytes.IndexByte(f.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if err := f.send(ctx, f.partial[:i]); err != nil {
+			return err
+		}
+		f.partial = f.partial[i+1:]
+	}
+
+	// Split lines too long to keep
+	for len(f.partial) >= MaxLineLength {
+		if err := f.send(ctx, f.partial[:MaxLineLength]); err != nil {
+			return err
+		}
+		f.partial = f.partial[MaxLineLength:]
+	}
+	f.partial = append([]byte(nil), f.partial...)
+	return nil
+}
+
+// send sends one line, without a trailing carriage return
+func (f *follower) send(ctx context.Context, line []byte) error {
+	select {
+	case f.lines <- string(bytes.TrimSuffix(line, []byte("\r"))):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkFile handles a rotated, truncated or recreated file
+func (f *follower) checkFile(ctx context.Context) error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		if stderrors.Is(err, os.ErrNotExist) {
+			// Rotated away and not created again yet; the old file was read to its end
+			return nil
+		}
+		return fmt.Errorf("failed to check %s: %w", f.path, err)
+	}
+
+	if f.file != nil && os.SameFile(info, f.info) {
+		if info.Size() < f.offset {
+			// Truncated in place: start over
+			if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read %s: %w", f.path, err)
+			}
+			f.offset, f.partial = 0, nil
+		}
+		return nil
+	}
+
+	// Rotated: finish the old file, including a last line without newline,
+	// then read the new one from its beginning
+	if err := f.read(ctx); err != nil {
+		return err
+	}
+	if len(f.partial) > 0 {
+		if err := f.send(ctx, f.partial); err != nil {
+			return err
+		}
+	}
+	f.close()
+
+	if err := f.open(false); err != nil {
+		if stderrors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", f.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startFollow follows path in the background, returning the channel of lines
+func startFollow(t *testing.T, path string, opts FollowOptions) <-chan string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 100)
+	done := make(chan error, 1)
+	opts.PollInterval = 10 * time.Millisecond
+	go func() { done <- Follow(ctx, path, opts, lines) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil && err != context.Canceled {
+			t.Errorf("Follow failed: %v", err)
+		}
+	})
+
+	// Let Follow open the file before the test writes to it
+	time.Sleep(30 * time.Millisecond)
+	return lines
+}
+
+// expectLines waits for the given lines, in order
+func expectLines(t *testing.T, lines <-chan string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Fatalf("expected line %q, got %q", w, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for line %q", w)
+		}
+	}
+}
+
+// appendFile appends data to the file at path
+func appendFile(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old line\n")
+
+	lines := startFollow(t, path, FollowOptions{})
+
+	// A line is sent once it is complete
+	appendFile(t, path, "first\r\nsec")
+	expectLines(t, lines, "first")
+	appendFile(t, path, "ond\n")
+	expectLines(t, lines, "second")
+}
+
+func TestFollow_FromStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "old line\n")
+
+	lines := startFollow(t, path, FollowOptions{FromStart: true})
+	expectLines(t, lines, "old line")
+}
+
+func TestFollow_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	appendFile(t, path, "")
+
+	lines := startFollow(t, path, FollowOptions{})
+	appendFile(t, path, "before rotation\n")
+	expectLines(t, lines, "before rotation")
+
+	// Lines written to the old file after the rename are still read
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	appendFile(t, path+".1", "late\nno newline")
+	appendFile(t, path, "after rotation\n")
+	expectLines(t, lines, "late", "no newline", "after rotation")
+}
+
+func TestFollow_Truncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "")
+
+	lines := startFollow(t, path, FollowOptions{})
+	appendFile(t, path, "a fairly long line before truncation\n")
+	expectLines(t, lines, "a fairly long line before truncation")
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("truncate failed: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	appendFile(t, path, "after\n")
+	expectLines(t, lines, "after")
+}
+
+func TestFollow_Missing(t *testing.T) {
+	lines := make(chan string)
+	err := Follow(context.Background(), filepath.Join(t.TempDir(), "missing.log"), FollowOptions{}, lines)
+	if !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+}
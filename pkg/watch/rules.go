@@ -0,0 +1,299 @@
+// Package watch turns lines appended to a log file into notifications.
+//
+// Follow reads a file like tail -F, following it across rotation and
+// truncation. A Matcher applies a list of rules to the lines: the first rule
+// whose pattern matches a line produces a Match. A rule with a continuation
+// pattern groups the lines following the match that match the continuation,
+// such as the frames of a stack trace, into the same Match. Each rule can be
+// throttled so that a burst of errors sends one notification.
+//
+// Rules are defined in the watch section of the config file as named lists,
+// with notification fields rendered as templates (see pkg/templates). The
+// named groups of the pattern are available as .Vars, together with file,
+// rule, line (the matching line) and lines (all grouped lines), which groups
+// cannot be named after:
+//
+//	watch:
+//	  app:
+//	    - name: panic
+//	      pattern: 'panic: (?P<reason>.*)'
+//	      continuation: '^(\s|goroutine |$)'
+//	      title: "App panicked: {{.Vars.reason | truncate 60}}"
+//	      type: alert
+//	      tags: [app]
+//	    - name: error
+//	      pattern: 'level=error .*msg="(?P<msg>[^"]*)"'
+//	      title: "{{.Vars.msg}}"
+//	      throttle: 10m
+//
+// Example usage:
+//
+//	rules, err := watch.Compile(configs)
+//	m := watch.NewMatcher(rules)
+//
+//	lines := make(chan string)
+//	go watch.Follow(ctx, "/var/log/app.log", watch.FollowOptions{}, lines)
+//	for line := range lines {
+//		for _, match := range m.Feed(line, time.Now()) {
+//			rendered, err := match.Render("/var/log/app.log")
+//			// Send rendered.Title, rendered.Message, ...
+//			m.Sent(match)
+//		}
+//	}
+package watch
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+)
+
+// DefaultMaxLines is the default maximum number of lines grouped into one match
+const DefaultMaxLines = 50
+
+// builtinVars are the template variables set for every match, reserved as group names
+var builtinVars = []string{"file", "rule", "line", "lines"}
+
+// RuleConfig is a rule as defined in the config file or with flags
+type RuleConfig struct {
+	Name         string        `mapstructure:"name"`         // Name used in the default title (default: rule N)
+	Pattern      string        `mapstructure:"pattern"`      // Regular expression a line must match
+	Continuation string        `mapstructure:"continuation"` // Regular expression of lines grouped with a match (empty: no grouping)
+	MaxLines     int           `mapstructure:"max_lines"`    // Maximum number of lines in a group (default DefaultMaxLines)
+	Throttle     time.Duration `mapstructure:"throttle"`     // Minimum time between notifications of the rule (0: none)
+
+	// Notification fields; title and message default to the rule name and the matched lines
+	templates.Template `mapstructure:",squash"`
+}
+
+// Rule is a compiled rule
+type Rule struct {
+	Name         string
+	Pattern      *regexp.Regexp
+	Continuation *regexp.Regexp // nil if the rule does not group lines
+	MaxLines     int
+	Throttle     time.Duration
+	Template     templates.Template
+}
+
+// Compile compiles rule configs, naming unnamed rules by their position
+func Compile(configs []RuleConfig) ([]*Rule, error) {
+	rules := make([]*Rule, 0, len(configs))
+	for i, rc := range configs {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if rc.Pattern == "" {
+			return nil, fmt.Errorf("%s: pattern is required", name)
+		}
+		pattern, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", name, err)
+		}
+		for _, group := range pattern.SubexpNames() {
+			if slices.Contains(builtinVars, group) {
+				return nil, fmt.Errorf("%s: group name %q is reserved for the built-in variable", name, group)
+			}
+		}
+
+		rule := &Rule{Name: name, Pattern: pattern, MaxLines: rc.MaxLines, Throttle: rc.Throttle, Template: rc.Template}
+		if rc.Continuation != "" {
+			if rule.Continuation, err = regexp.Compile(rc.Continuation); err != nil {
+				return nil, fmt.Errorf("%s: invalid continuation: %w", name, err)
+			}
+		}
+		if rule.MaxLines < 0 || rule.Throttle < 0 {
+			return nil, fmt.Errorf("%s: max_lines and throttle must not be negative", name)
+		}
+		if rule.MaxLines == 0 {
+			rule.MaxLines = DefaultMaxLines
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Match is a line matched by a rule, with the lines grouped with it
+type Match struct {
+	Rule   *Rule
+	At     time.Time
+	Lines  []string
+	Groups map[string]string // Named and numbered groups of the pattern
+
+	// Suppressed is the number of matches of the rule throttled since its last notification
+	Suppressed int
+}
+
+// Render renders the notification of the match, from the file at path
+func (m *Match) Render(path string) (*templates.Rendered, error) {
+	vars := map[string]string{
+		"file":  path,
+		"rule":  m.Rule.Name,
+		"line":  m.Lines[0],
+		"lines": strings.Join(m.Lines, "\n"),
+	}
+	for name, value := range m.Groups {
+		vars[name] = value
+	}
+
+	rendered, err := templates.Render(m.Rule.Template, vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Rule.Name, err)
+	}
+
+	if rendered.Title == "" {
+		rendered.Title = fmt.Sprintf("%s in %s", m.Rule.Name, filepath.Base(path))
+	}
+	if rendered.Message == "" {
+		rendered.Message = vars["lines"]
+	}
+	if m.Suppressed > 0 {
+		rendered.Message += fmt.Sprintf("\n\n(%d more %s throttled since the last notification)", m.Suppressed, templates.Plural(m.Suppressed, "match", "matches"))
+	}
+	return rendered, nil
+}
+
+// Matcher applies rules to lines one at a time
+type Matcher struct {
+	rules   []*Rule
+	pending *Match // Match still collecting continuation lines
+
+	lastSent   map[*Rule]time.Time
+	suppressed map[*Rule]int
+}
+
+// NewMatcher creates a matcher applying rules in order
+func NewMatcher(rules []*Rule) *Matcher {
+	return &Matcher{
+		rules:      rules,
+		lastSent:   make(map[*Rule]time.Time),
+		suppressed: make(map[*Rule]int),
+	}
+}
+
+// Feed processes the next line, seen at now, and returns the matches it completes
+// A line continuing a pending group is added to it; any other line completes
+// the group, as does reaching the rule's MaxLines. Throttled matches are
+// counted but not returned. Call Sent once the notification of a returned
+// match is delivered; a match that was not delivered does not start the
+// rule's throttle window.
+func (m *Matcher) Feed(line string, now time.Time) []*Match {
+	var done []*Match
+	if m.pending != nil {
+		if m.continues(line) {
+			m.pending.Lines = append(m.pending.Lines, line)
+			if len(m.pending.Lines) >= m.pending.Rule.MaxLines {
+				return m.appendPending(nil)
+			}
+			return nil
+		}
+		done = m.appendPending(done)
+	}
+
+	for _, rule := range m.rules {
+		groups := rule.Pattern.FindStringSubmatch(line)
+		if groups == nil {
+			continue
+		}
+
+		match := &Match{Rule: rule, At: now, Lines: []string{line}, Groups: groupMap(rule.Pattern, groups)}
+		if rule.Continuation != nil {
+			m.pending = match
+			return done
+		}
+		if m.allow(match) {
+			done = append(done, match)
+		}
+		return done
+	}
+	return done
+}
+
+// Flush returns the pending group, if any, completing it
+// Call it when no line followed for a while, so a group at the end of the
+// file is not held back. Returns nil if there is no pending group or if the
+// rule's throttle suppresses it.
+func (m *Matcher) Flush() *Match {
+	done := m.appendPending(nil)
+	if len(done) == 0 {
+		return nil
+	}
+	return done[0]
+}
+
+// Pending reports whether a group is collecting continuation lines
+// A pending group can still be throttled, so Flush may return nil even then.
+func (m *Matcher) Pending() bool {
+	return m.pending != nil
+}
+
+// continues reports whether line belongs to the pending group
+// A line matching the pattern of a rule starts a new match instead.
+func (m *Matcher) continues(line string) bool {
+	if !m.pending.Rule.Continuation.MatchString(line) {
+		return false
+	}
+	for _, rule := range m.rules {
+		if rule.Pattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendPending completes the pending group and appends it to done unless throttled
+func (m *Matcher) appendPending(done []*Match) []*Match {
+	match := m.pending
+	if match == nil {
+		return done
+	}
+	m.pending = nil
+	if m.allow(match) {
+		done = append(done, match)
+	}
+	return done
+}
+
+// allow applies the rule's throttle to a completed match
+func (m *Matcher) allow(match *Match) bool {
+	rule := match.Rule
+	if last, ok := m.lastSent[rule]; ok && rule.Throttle > 0 && match.At.Sub(last) < rule.Throttle {
+		m.suppressed[rule]++
+		return false
+	}
+
+	match.Suppressed = m.suppressed[rule]
+	return true
+}
+
+// Sent records that the notification of match was delivered
+// It starts the rule's throttle window and clears the throttled matches the
+// notification reported.
+func (m *Matcher) Sent(match *Match) {
+	rule := match.Rule
+	m.suppressed[rule] -= match.Suppressed
+	m.lastSent[rule] = match.At
+}
+
+// groupMap returns the named and numbered submatches of a pattern
+func groupMap(pattern *regexp.Regexp, groups []string) map[string]string {
+	vars := make(map[string]string, len(groups))
+	for i, name := range pattern.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		vars[strconv.Itoa(i)] = groups[i]
+		if name != "" {
+			vars[name] = groups[i]
+		}
+	}
+	return vars
+}
@@ -0,0 +1,241 @@
+package watch
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pincho-App/pincho-cli/pkg/templates"
+)
+
+func mustCompile(t *testing.T, configs ...RuleConfig) []*Rule {
+	t.Helper()
+	rules, err := Compile(configs)
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	return rules
+}
+
+func TestCompile(t *testing.T) {
+	rules := mustCompile(t, RuleConfig{Pattern: "ERROR"}, RuleConfig{Name: "panic", Pattern: "panic:", Continuation: `^\s`})
+	if rules[0].Name != "rule 1" || rules[0].MaxLines != DefaultMaxLines || rules[0].Continuation != nil {
+		t.Errorf("unexpected defaults: %+v", rules[0])
+	}
+	if rules[1].Name != "panic" || rules[1].Continuation == nil {
+		t.Errorf("unexpected rule: %+v", rules[1])
+	}
+
+	invalid := []RuleConfig{
+		{Name: "no pattern"},
+		{Pattern: "("},
+		{Pattern: "x", Continuation: "["},
+		{Pattern: "x", Throttle: -time.Second},
+		{Pattern: `(?P<line>\d+)`},
+		{Pattern: `(?P<file>\S+)`},
+	}
+	for _, rc := range invalid {
+		if _, err := Compile([]RuleConfig{rc}); err == nil {
+			t.Errorf("Compile(%+v) expected error", rc)
+		}
+	}
+}
+
+func TestMatcher_FirstRuleWins(t *testing.T) {
+	m := NewMatcher(mustCompile(t,
+		RuleConfig{Name: "critical", Pattern: "CRITICAL"},
+		RuleConfig{Name: "error", Pattern: "ERROR|CRITICAL"},
+	))
+
+	now := time.Now()
+	if got := m.Feed("INFO all good", now); len(got) != 0 {
+		t.Errorf("expected no match, got %+v", got)
+	}
+	got := m.Feed("CRITICAL disk full", now)
+	if len(got) != 1 || got[0].Rule.Name != "critical" {
+		t.Fatalf("expected a critical match, got %+v", got)
+	}
+	if got := m.Feed("ERROR timeout", now); len(got) != 1 || got[0].Rule.Name != "error" {
+		t.Errorf("expected an error match, got %+v", got)
+	}
+}
+
+func TestMatcher_Grouping(t *testing.T) {
+	m := NewMatcher(mustCompile(t,
+		RuleConfig{Name: "exception", Pattern: `Exception: (?P<reason>.*)`, Continuation: `^\s+at |^Caused by:`},
+		RuleConfig{Name: "error", Pattern: `ERROR`},
+	))
+	now := time.Now()
+
+	for _, line := range []string{
+		"java.lang.IllegalStateException: boom",
+		"    at com.example.Foo.bar(Foo.java:10)",
+		"Caused by: java.io.IOException",
+		"    at com.example.Baz.qux(Baz.java:20)",
+	} {
+		if got := m.Feed(line, now); len(got) != 0 {
+			t.Fatalf("expected the group to stay pending at %q, got %+v", line, got)
+		}
+	}
+	if !m.Pending() {
+		t.Fatal("expected a pending group")
+	}
+
+	// A line matching a rule completes the group and starts its own match
+	got := m.Feed("ERROR next", now)
+	if len(got) != 2 {
+		t.Fatalf("expected the group and the next match, got %+v", got)
+	}
+	if got[0].Rule.Name != "exception" || len(got[0].Lines) != 4 || got[0].Groups["reason"] != "boom" {
+		t.Errorf("unexpected group: %+v", got[0])
+	}
+	if got[1].Rule.Name != "error" {
+		t.Errorf("expected an error match, got %+v", got[1])
+	}
+}
+
+func TestMatcher_GroupEnds(t *testing.T) {
+	m := NewMatcher(mustCompile(t, RuleConfig{Pattern: `^panic:`, Continuation: `^\t`, MaxLines: 3}))
+	now := time.Now()
+
+	// An unrelated line completes the group
+	m.Feed("panic: first", now)
+	m.Feed("\tframe", now)
+	if got := m.Feed("unrelated", now); len(got) != 1 || len(got[0].Lines) != 2 {
+		t.Fatalf("expected a group of 2 lines, got %+v", got)
+	}
+
+	// Reaching MaxLines completes the group; further continuation lines are dropped
+	m.Feed("panic: second", now)
+	m.Feed("\tframe 1", now)
+	if got := m.Feed("\tframe 2", now); len(got) != 1 || len(got[0].Lines) != 3 {
+		t.Fatalf("expected a group of 3 lines, got %+v", got)
+	}
+	if got := m.Feed("\tframe 3", now); len(got) != 0 || m.Pending() {
+		t.Errorf("expected the extra line to be dropped, got %+v", got)
+	}
+
+	// Flush completes a group at the end of the file
+	m.Feed("panic: third", now)
+	if match := m.Flush(); match == nil || match.Lines[0] != "panic: third" {
+		t.Errorf("expected Flush to return the group, got %+v", match)
+	}
+	if m.Flush() != nil {
+		t.Error("expected nothing left to flush")
+	}
+}
+
+func TestMatcher_Throttle(t *testing.T) {
+	m := NewMatcher(mustCompile(t, RuleConfig{Pattern: "ERROR", Throttle: time.Minute}))
+	start := time.Now()
+
+	first := m.Feed("ERROR 1", start)
+	if len(first) != 1 {
+		t.Fatalf("expected the first match, got %+v", first)
+	}
+	m.Sent(first[0])
+	for i := 1; i <= 3; i++ {
+		if got := m.Feed("ERROR again", start.Add(time.Duration(i)*time.Second)); len(got) != 0 {
+			t.Fatalf("expected match %d to be throttled", i)
+		}
+	}
+
+	got := m.Feed("ERROR later", start.Add(2*time.Minute))
+	if len(got) != 1 || got[0].Suppressed != 3 {
+		t.Fatalf("expected a match reporting 3 throttled, got %+v", got)
+	}
+	m.Sent(got[0])
+	if got := m.Feed("ERROR much later", start.Add(10*time.Minute)); len(got) != 1 || got[0].Suppressed != 0 {
+		t.Errorf("expected the throttled count to reset, got %+v", got)
+	}
+}
+
+func TestMatcher_ThrottleAfterFailedSend(t *testing.T) {
+	m := NewMatcher(mustCompile(t, RuleConfig{Pattern: "ERROR", Throttle: time.Minute}))
+	start := time.Now()
+
+	m.Sent(m.Feed("ERROR 1", start)[0])
+	m.Feed("ERROR throttled", start.Add(time.Second))
+
+	// Not delivered: no Sent, so the throttled count is kept and no window starts
+	got := m.Feed("ERROR undelivered", start.Add(2*time.Minute))
+	if len(got) != 1 || got[0].Suppressed != 1 {
+		t.Fatalf("expected a match reporting 1 throttled, got %+v", got)
+	}
+	got = m.Feed("ERROR retried", start.Add(2*time.Minute+time.Second))
+	if len(got) != 1 || got[0].Suppressed != 1 {
+		t.Errorf("expected the next match to be allowed and still report 1 throttled, got %+v", got)
+	}
+}
+
+func TestMatcher_ThrottledGroupFlushesNil(t *testing.T) {
+	m := NewMatcher(mustCompile(t, RuleConfig{Pattern: `^panic`, Continuation: `^\t`, Throttle: time.Minute}))
+	start := time.Now()
+
+	m.Feed("panic a", start)
+	first := m.Flush()
+	if first == nil {
+		t.Fatal("expected the first group to flush")
+	}
+	m.Sent(first)
+
+	m.Feed("panic b", start.Add(time.Second))
+	if !m.Pending() {
+		t.Fatal("expected the throttled group to be pending until flushed")
+	}
+	if got := m.Flush(); got != nil {
+		t.Errorf("expected a throttled group to flush to nil, got %+v", got)
+	}
+	if m.Pending() {
+		t.Error("expected no pending group after Flush")
+	}
+
+	got := m.Feed("panic c", start.Add(2*time.Minute))
+	if len(got) != 0 {
+		t.Fatalf("expected the group to stay pending, got %+v", got)
+	}
+	if match := m.Flush(); match == nil || match.Suppressed != 1 {
+		t.Errorf("expected a group reporting 1 throttled, got %+v", match)
+	}
+}
+
+func TestMatchRender(t *testing.T) {
+	rules := mustCompile(t,
+		RuleConfig{
+			Name:    "error",
+			Pattern: `ERROR \[(?P<component>\w+)\] (.*)`,
+			Template: templates.Template{
+				Title: "{{.Vars.component | upper}} error",
+				Type:  "alert",
+				Tags:  []string{"{{.Vars.component}}"},
+			},
+		},
+		RuleConfig{Name: "plain", Pattern: "WARN"},
+	)
+	m := NewMatcher(rules)
+
+	match := m.Feed("ERROR [db] connection lost", time.Now())[0]
+	match.Suppressed = 2
+	rendered, err := match.Render("/var/log/app.log")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if rendered.Title != "DB error" || rendered.Type != "alert" || len(rendered.Tags) != 1 || rendered.Tags[0] != "db" {
+		t.Errorf("unexpected rendered fields: %+v", rendered)
+	}
+	if !strings.HasPrefix(rendered.Message, "ERROR [db] connection lost") || !strings.Contains(rendered.Message, "2 more matches throttled") {
+		t.Errorf("unexpected message: %q", rendered.Message)
+	}
+	if match.Groups["2"] != "connection lost" {
+		t.Errorf("expected numbered groups, got %v", match.Groups)
+	}
+
+	// Defaults: the rule name and file in the title, the lines as message
+	rendered, err = m.Feed("WARN slow query", time.Now())[0].Render("/var/log/app.log")
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if rendered.Title != "plain in app.log" || rendered.Message != "WARN slow query" {
+		t.Errorf("unexpected defaults: %+v", rendered)
+	}
+}